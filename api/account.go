@@ -1,16 +1,19 @@
 package api
 
 import (
-	"database/sql"
+	"errors"
 	"net/http"
+
 	db "simple_bank/db/sqlc"
+	"simple_bank/token"
 
 	"github.com/gin-gonic/gin"
 )
 
+// createAccountRequest has no owner or balance: accounts are always opened
+// empty for the authenticated user. Money only arrives through transfers, so
+// the ledger stays balanced.
 type createAccountRequest struct {
-	Owner    string `json:"owner" binding:"required"`
-	Balance  int64  `json:"balance" binding:"min=0"`
 	Currency string `json:"currency" binding:"required,currency"`
 }
 
@@ -26,19 +29,28 @@ type listAccountsRequest struct {
 func (server *Server) createAccountHandler(ctx *gin.Context) {
 	var req createAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
 		return
 	}
 
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	arg := db.CreateAccountParams{
-		Owner:    req.Owner,
-		Balance:  req.Balance,
+		Owner:    authPayload.Username,
+		Balance:  0,
 		Currency: req.Currency,
 	}
 
-	account, err := server.store.CreateAccount(ctx.Request.Context(), arg)
+	account, err := server.store.CreateAccountTx(ctx.Request.Context(), arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		if errors.Is(err, db.ErrCurrencyDisabled) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+			return
+		}
+		if db.ErrorCode(err) == db.UniqueViolation {
+			ctx.JSON(http.StatusForbidden, errorResponse(ctx, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
 		return
 	}
 
@@ -46,20 +58,14 @@ func (server *Server) createAccountHandler(ctx *gin.Context) {
 }
 
 func (server *Server) getAccountHandler(ctx *gin.Context) {
-
 	var req getAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
 		return
 	}
 
-	account, err := server.store.GetAccount(ctx.Request.Context(), req.ID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	account, _, ok := server.authorizedAccount(ctx, req.ID)
+	if !ok {
 		return
 	}
 
@@ -69,18 +75,20 @@ func (server *Server) getAccountHandler(ctx *gin.Context) {
 func (server *Server) listAccountsHandler(ctx *gin.Context) {
 	var req listAccountsRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
 		return
 	}
 
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	arg := db.ListAccountsParams{
-		Limit:  req.PageSize,
-		Offset: (req.PageID - 1) * req.PageSize,
+		Username: authPayload.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
 	}
 
 	accounts, err := server.store.ListAccounts(ctx.Request.Context(), arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
 		return
 	}
 
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+	"simple_bank/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// addAccountHolderRequest has no primary role: every account has exactly
+// one primary holder, its owner.
+type addAccountHolderRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+	Role     string `json:"role" binding:"required,oneof=joint viewer"`
+}
+
+type removeAccountHolderRequest struct {
+	ID       int64  `uri:"id" binding:"required,min=1"`
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// listAccountHoldersHandler lists everyone who holds an account, to any of
+// its holders.
+func (server *Server) listAccountHoldersHandler(ctx *gin.Context) {
+	var req getAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	if _, _, ok := server.authorizedAccount(ctx, req.ID); !ok {
+		return
+	}
+
+	holders, err := server.store.ListAccountHolders(ctx.Request.Context(), req.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, holders)
+}
+
+// addAccountHolderHandler lets another user hold an account. Only the
+// primary holder can add holders.
+func (server *Server) addAccountHolderHandler(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+	var req addAccountHolderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	account, role, ok := server.authorizedAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+	if role != util.PrimaryHolder {
+		err := fmt.Errorf("only the primary holder can add holders to account [%d]", account.ID)
+		ctx.JSON(http.StatusForbidden, errorResponse(ctx, err))
+		return
+	}
+
+	holder, err := server.store.AddAccountHolderTx(ctx.Request.Context(), db.CreateAccountHolderParams{
+		AccountID: account.ID,
+		Username:  req.Username,
+		Role:      req.Role,
+	})
+	if err != nil {
+		switch db.ErrorCode(err) {
+		case db.ForeignKeyViolation:
+			err := fmt.Errorf("user %s not found", req.Username)
+			ctx.JSON(http.StatusNotFound, errorResponse(ctx, err))
+			return
+		case db.UniqueViolation:
+			err := fmt.Errorf("%s already holds account [%d]", req.Username, account.ID)
+			ctx.JSON(http.StatusConflict, errorResponse(ctx, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, holder)
+}
+
+// removeAccountHolderHandler stops a user holding an account. The primary
+// holder can remove anyone else, and other holders can remove themselves;
+// the primary holder cannot be removed.
+func (server *Server) removeAccountHolderHandler(ctx *gin.Context) {
+	var req removeAccountHolderRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	account, role, ok := server.authorizedAccount(ctx, req.ID)
+	if !ok {
+		return
+	}
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if role != util.PrimaryHolder && req.Username != authPayload.Username {
+		err := fmt.Errorf("only the primary holder can remove other holders from account [%d]", account.ID)
+		ctx.JSON(http.StatusForbidden, errorResponse(ctx, err))
+		return
+	}
+	if req.Username == account.Owner {
+		err := fmt.Errorf("the primary holder cannot be removed from account [%d]", account.ID)
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	_, err := server.store.RemoveAccountHolderTx(ctx.Request.Context(), db.DeleteAccountHolderParams{
+		AccountID: account.ID,
+		Username:  req.Username,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ctx, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+// expectHolder stubs the lookup of username's role in account.
+func expectHolder(store *mockdb.MockStore, account db.Account, username, role string) {
+	store.EXPECT().
+		GetAccountHolder(gomock.Any(), gomock.Eq(db.GetAccountHolderParams{AccountID: account.ID, Username: username})).
+		Times(1).
+		Return(db.AccountHolder{AccountID: account.ID, Username: username, Role: role}, nil)
+}
+
+func TestListAccountHoldersAPI(t *testing.T) {
+	account := createRandomAccount()
+	holders := []db.AccountHolder{
+		{AccountID: account.ID, Username: account.Owner, Role: util.PrimaryHolder},
+		{AccountID: account.ID, Username: "viewer", Role: util.ViewerHolder},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+	expectHolder(store, account, "viewer", util.ViewerHolder)
+	store.EXPECT().ListAccountHolders(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(holders, nil)
+	server := newTestServer(t, store)
+
+	url := fmt.Sprintf("/accounts/%d/holders", account.ID)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "viewer", time.Minute)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp []db.AccountHolder
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Len(t, rsp, 2)
+	require.Equal(t, util.PrimaryHolder, rsp[0].Role)
+	require.Equal(t, "viewer", rsp[1].Username)
+}
+
+func TestAddAccountHolderAPI(t *testing.T) {
+	account := createRandomAccount()
+
+	testCases := []struct {
+		name          string
+		username      string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: account.Owner,
+			body:     gin.H{"username": "partner", "role": util.JointHolder},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.CreateAccountHolderParams{AccountID: account.ID, Username: "partner", Role: util.JointHolder}
+				store.EXPECT().
+					AddAccountHolderTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.AccountHolder{AccountID: account.ID, Username: "partner", Role: util.JointHolder}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"role":"joint"`)
+			},
+		},
+		{
+			name:     "JointHolder",
+			username: "joint",
+			body:     gin.H{"username": "partner", "role": util.ViewerHolder},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				expectHolder(store, account, "joint", util.JointHolder)
+				store.EXPECT().AddAccountHolderTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "PrimaryRole",
+			username: account.Owner,
+			body:     gin.H{"username": "partner", "role": util.PrimaryHolder},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().AddAccountHolderTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "UserNotFound",
+			username: account.Owner,
+			body:     gin.H{"username": "nobody", "role": util.ViewerHolder},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					AddAccountHolderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AccountHolder{}, &pgconn.PgError{Code: db.ForeignKeyViolation})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "AlreadyHolder",
+			username: account.Owner,
+			body:     gin.H{"username": "partner", "role": util.ViewerHolder},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					AddAccountHolderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AccountHolder{}, &pgconn.PgError{Code: db.UniqueViolation})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/accounts/%d/holders", account.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestRemoveAccountHolderAPI(t *testing.T) {
+	account := createRandomAccount()
+
+	testCases := []struct {
+		name          string
+		username      string
+		holder        string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: account.Owner,
+			holder:   "partner",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.DeleteAccountHolderParams{AccountID: account.ID, Username: "partner"}
+				store.EXPECT().RemoveAccountHolderTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name:     "RemoveSelf",
+			username: "viewer",
+			holder:   "viewer",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				expectHolder(store, account, "viewer", util.ViewerHolder)
+				arg := db.DeleteAccountHolderParams{AccountID: account.ID, Username: "viewer"}
+				store.EXPECT().RemoveAccountHolderTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name:     "RemoveOtherHolder",
+			username: "joint",
+			holder:   "viewer",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				expectHolder(store, account, "joint", util.JointHolder)
+				store.EXPECT().RemoveAccountHolderTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "RemovePrimary",
+			username: account.Owner,
+			holder:   account.Owner,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().RemoveAccountHolderTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "NotHolder",
+			username: account.Owner,
+			holder:   "stranger",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					RemoveAccountHolderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AccountHolder{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/accounts/%d/holders/%s", account.ID, tc.holder)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
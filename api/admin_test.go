@@ -0,0 +1,437 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAdminRoleMatrix sends every admin route a request as each role, and
+// without a token, checking only the allowed roles reach the store.
+func TestAdminRoleMatrix(t *testing.T) {
+	account := createRandomAccount()
+	transferID := util.RandomInt(1, 1000)
+
+	routes := []struct {
+		method  string
+		path    string
+		body    gin.H
+		allowed []string
+		// status is the response to an allowed request; 0 means 200 OK.
+		status int
+		// buildStubs sets up the store for a request that is let through.
+		buildStubs func(store *mockdb.MockStore)
+	}{
+		{
+			method:  http.MethodGet,
+			path:    fmt.Sprintf("/admin/accounts/%d", account.ID),
+			allowed: []string{util.SupportRole, util.AdminRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+		},
+		{
+			method:  http.MethodPost,
+			path:    fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			allowed: []string{util.SupportRole, util.AdminRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.SetAccountStatusParams{Status: db.AccountFrozen, ID: account.ID}
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
+			},
+		},
+		{
+			method:  http.MethodPost,
+			path:    fmt.Sprintf("/admin/accounts/%d/unfreeze", account.ID),
+			allowed: []string{util.AdminRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.SetAccountStatusParams{Status: db.AccountActive, ID: account.ID}
+				store.EXPECT().SetAccountStatusTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
+			},
+		},
+		{
+			method:  http.MethodPost,
+			path:    fmt.Sprintf("/admin/transfers/%d/reverse", transferID),
+			allowed: []string{util.AdminRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ReverseTransferTxParams{TransferID: transferID, ReversedBy: "operator"}
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/admin/currencies",
+			allowed: []string{util.SupportRole, util.AdminRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListCurrencies(gomock.Any()).Times(1)
+			},
+		},
+		{
+			method:  http.MethodPost,
+			path:    "/admin/currencies",
+			body:    gin.H{"code": "GBP", "enabled": true},
+			allowed: []string{util.AdminRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateCurrencyParams{Code: "GBP", Enabled: true}
+				store.EXPECT().CreateCurrency(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+		},
+		{
+			method:  http.MethodPatch,
+			path:    "/admin/currencies/CAD",
+			body:    gin.H{"enabled": true},
+			allowed: []string{util.AdminRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.UpdateCurrencyParams{Enabled: true, Code: util.CAD}
+				store.EXPECT().UpdateCurrency(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/admin/ledger",
+			allowed: []string{util.AdminRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLedgerTotals(gomock.Any()).Times(1)
+				store.EXPECT().ListLedgerMismatches(gomock.Any()).Times(1)
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/admin/audit_log?page_id=1&page_size=10",
+			allowed: []string{util.AdminRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLog(gomock.Any(), gomock.Any()).Times(1)
+			},
+		},
+		{
+			method:  http.MethodPost,
+			path:    "/admin/users/alice/unlock",
+			allowed: []string{util.AdminRole},
+			status:  http.StatusNoContent,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UnlockLoginTx(gomock.Any(), gomock.Eq(db.UserTarget("alice"))).Times(1)
+			},
+		},
+	}
+
+	// An empty role sends no token at all.
+	roles := []string{"", util.CustomerRole, util.SupportRole, util.AdminRole}
+
+	for _, route := range routes {
+		for _, role := range roles {
+			name := fmt.Sprintf("%s %s as %q", route.method, route.path, role)
+			t.Run(name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				wantStatus := http.StatusOK
+				store := mockdb.NewMockStore(ctrl)
+				switch {
+				case role == "":
+					wantStatus = http.StatusUnauthorized
+				case !slices.Contains(route.allowed, role):
+					wantStatus = http.StatusForbidden
+				default:
+					if route.status != 0 {
+						wantStatus = route.status
+					}
+					route.buildStubs(store)
+				}
+
+				server := newTestServer(t, store)
+
+				var body bytes.Buffer
+				if route.body != nil {
+					require.NoError(t, json.NewEncoder(&body).Encode(route.body))
+				}
+				request, err := http.NewRequest(route.method, route.path, &body)
+				require.NoError(t, err)
+				if role != "" {
+					addAuthorizationWithRole(t, request, server.tokenMaker, authorizationTypeBearer, "operator", role, time.Minute)
+				}
+
+				recorder := httptest.NewRecorder()
+				server.router.ServeHTTP(recorder, request)
+				require.Equal(t, wantStatus, recorder.Code)
+			})
+		}
+	}
+}
+
+func TestReverseTransferAPI(t *testing.T) {
+	transferID := util.RandomInt(1, 1000)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ReverseTransferTxResult{
+						Reversal: db.TransferReversal{TransferID: transferID, ReversalID: transferID + 1},
+					}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp db.ReverseTransferTxResult
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, transferID, rsp.Reversal.TransferID)
+				require.Equal(t, transferID+1, rsp.Reversal.ReversalID)
+			},
+		},
+		{
+			name: "NotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ReverseTransferTxResult{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyReversed",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ReverseTransferTxResult{}, &pgconn.PgError{Code: db.UniqueViolation})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "already been reversed")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/admin/transfers/%d/reverse", transferID)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+			addAuthorizationWithRole(t, request, server.tokenMaker, authorizationTypeBearer, "operator", util.AdminRole, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestUpdateCurrencyAPIRequiresEnabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	request, err := http.NewRequest(http.MethodPatch, "/admin/currencies/CAD", bytes.NewReader([]byte("{}")))
+	require.NoError(t, err)
+	addAuthorizationWithRole(t, request, server.tokenMaker, authorizationTypeBearer, "operator", util.AdminRole, time.Minute)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestListAuditLogAPI(t *testing.T) {
+	row := db.AuditLog{
+		ID:        7,
+		Actor:     "operator",
+		Action:    db.AuditAccountFreeze,
+		Target:    db.AccountTarget(3),
+		RequestID: "req-1",
+		Ip:        "10.0.0.1",
+		Before:    []byte(`{"status":"active"}`),
+		After:     []byte(`{"status":"frozen"}`),
+		PrevHash:  []byte{0xab},
+		Hash:      []byte{0xcd, 0xef},
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAuditLogParams{PageSize: 5, PageOffset: 0}
+				store.EXPECT().
+					ListAuditLog(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.AuditLog{row}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp []auditLogResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 1)
+				require.Equal(t, "ab", rsp[0].PrevHash)
+				require.Equal(t, "cdef", rsp[0].Hash)
+				require.JSONEq(t, `{"status":"frozen"}`, string(rsp[0].After))
+			},
+		},
+		{
+			name:  "Filters",
+			query: "page_id=3&page_size=10&actor=operator&target=account:3",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAuditLogParams{
+					Actor:      pgtype.Text{String: "operator", Valid: true},
+					Target:     pgtype.Text{String: "account:3", Valid: true},
+					PageSize:   10,
+					PageOffset: 20,
+				}
+				store.EXPECT().
+					ListAuditLog(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.AuditLog{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, "[]", recorder.Body.String())
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: "page_id=1&page_size=1000",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLog(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuditLog(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			request, err := http.NewRequest(http.MethodGet, "/admin/audit_log?"+tc.query, nil)
+			require.NoError(t, err)
+			addAuthorizationWithRole(t, request, server.tokenMaker, authorizationTypeBearer, "operator", util.AdminRole, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestUnlockUserAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: "alice",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UnlockLoginTx(gomock.Any(), gomock.Eq(db.UserTarget("alice"))).Times(1).Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name:     "NotLockedOut",
+			username: "alice",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UnlockLoginTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidUsername",
+			username: "not-valid",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UnlockLoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			username: "alice",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UnlockLoginTx(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/admin/users/%s/unlock", tc.username)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+			addAuthorizationWithRole(t, request, server.tokenMaker, authorizationTypeBearer, "operator", util.AdminRole, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"simple_bank/apikey"
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// createAPIKeyRequest takes an optional expiry; keys without one are valid
+// until revoked.
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,unique,dive,api_scope"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type apiKeyResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// createAPIKeyResponse is the only response carrying the key itself.
+type createAPIKeyResponse struct {
+	apiKeyResponse
+	Key string `json:"key"`
+}
+
+func newAPIKeyResponse(key db.ApiKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		ExpiresAt:  optionalTime(key.ExpiresAt),
+		RevokedAt:  optionalTime(key.RevokedAt),
+		LastUsedAt: optionalTime(key.LastUsedAt),
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+// optionalTime turns a NULL timestamp into an omitted field.
+func optionalTime(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func (server *Server) createAPIKeyHandler(ctx *gin.Context) {
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	var expiresAt time.Time
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			err := errors.New("expires_at must be in the future")
+			ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+			return
+		}
+		expiresAt = *req.ExpiresAt
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	key, keyString, err := server.apiKeys.Create(ctx.Request.Context(), apikey.CreateParams{
+		Owner:     authPayload.Username,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createAPIKeyResponse{
+		apiKeyResponse: newAPIKeyResponse(key),
+		Key:            keyString,
+	})
+}
+
+func (server *Server) listAPIKeysHandler(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	keys, err := server.store.ListApiKeys(ctx.Request.Context(), authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	rsp := make([]apiKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		rsp = append(rsp, newAPIKeyResponse(key))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type revokeAPIKeyRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// revokeAPIKeyHandler revokes a key of the authenticated user. Keys of
+// other users are reported as not found.
+func (server *Server) revokeAPIKeyHandler(ctx *gin.Context) {
+	var req revokeAPIKeyRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	_, err := server.store.RevokeAPIKeyTx(ctx.Request.Context(), db.RevokeApiKeyParams{
+		ID:    req.ID,
+		Owner: authPayload.Username,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ctx, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
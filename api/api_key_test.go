@@ -0,0 +1,412 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"simple_bank/apikey"
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+	"simple_bank/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// randomAPIKey issues a key for owner with scopes and returns the stored
+// row and the key string.
+func randomAPIKey(t *testing.T, owner string, scopes ...string) (db.ApiKey, string) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateAPIKeyTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateApiKeyParams) (db.ApiKey, error) {
+			return db.ApiKey{
+				ID:         util.RandomInt(1, 1000),
+				Owner:      arg.Owner,
+				Name:       arg.Name,
+				Prefix:     arg.Prefix,
+				SecretHash: arg.SecretHash,
+				Scopes:     arg.Scopes,
+				CreatedAt:  time.Now(),
+			}, nil
+		})
+
+	key, keyString, err := apikey.NewService(store).Create(context.Background(), apikey.CreateParams{
+		Owner:  owner,
+		Name:   "partner",
+		Scopes: scopes,
+	})
+	require.NoError(t, err)
+	return key, keyString
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	account := createRandomAccount()
+	key, keyString := randomAPIKey(t, account.Owner, util.ScopeAccountsRead)
+	transferKey, transferKeyString := randomAPIKey(t, account.Owner, util.ScopeTransfersCreate)
+
+	revoked := key
+	revoked.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, "ApiKey "+keyString)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(key, nil)
+				store.EXPECT().TouchApiKey(gomock.Any(), gomock.Eq(key.ID)).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name:   "BearerToken",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "MissingScope",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, "ApiKey "+transferKeyString)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(transferKey, nil)
+				store.EXPECT().TouchApiKey(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "TransferMissingScope",
+			method: http.MethodPost,
+			path:   "/transfers",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, "ApiKey "+keyString)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(key, nil)
+				store.EXPECT().TouchApiKey(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "RevokedKey",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, "ApiKey "+keyString)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(revoked, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "StoreError",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/accounts/%d", account.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, "ApiKey "+keyString)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			// Only routes that name a scope take keys.
+			name:   "RouteWithoutScope",
+			method: http.MethodGet,
+			path:   "/users/me/api_keys",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, "ApiKey "+keyString)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListApiKeys(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			body := bytes.NewReader([]byte("{}"))
+			request, err := http.NewRequest(tc.method, tc.path, body)
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestCreateAPIKeyAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"name": "payroll", "scopes": []string{util.ScopeAccountsRead, util.ScopeTransfersCreate}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKeyTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateApiKeyParams) (db.ApiKey, error) {
+						require.Equal(t, username, arg.Owner)
+						require.Equal(t, "payroll", arg.Name)
+						require.False(t, arg.ExpiresAt.Valid)
+						return db.ApiKey{ID: 1, Owner: arg.Owner, Name: arg.Name, Prefix: arg.Prefix, SecretHash: arg.SecretHash, Scopes: arg.Scopes}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp createAPIKeyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.True(t, apikey.IsKey(rsp.Key))
+				require.Equal(t, rsp.Prefix, apikey.Prefix(rsp.Key))
+				require.Nil(t, rsp.ExpiresAt)
+				require.NotContains(t, recorder.Body.String(), "secret_hash")
+			},
+		},
+		{
+			name: "ExpiresAt",
+			body: gin.H{"name": "payroll", "scopes": []string{util.ScopeAccountsRead}, "expires_at": time.Now().Add(time.Hour)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKeyTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateApiKeyParams) (db.ApiKey, error) {
+						require.True(t, arg.ExpiresAt.Valid)
+						return db.ApiKey{ID: 1, Scopes: arg.Scopes, ExpiresAt: arg.ExpiresAt}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "expires_at")
+			},
+		},
+		{
+			name: "ExpiresInThePast",
+			body: gin.H{"name": "payroll", "scopes": []string{util.ScopeAccountsRead}, "expires_at": time.Now().Add(-time.Hour)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKeyTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownScope",
+			body: gin.H{"name": "payroll", "scopes": []string{"accounts:write"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKeyTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoScopes",
+			body: gin.H{"name": "payroll", "scopes": []string{}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKeyTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"name": "payroll", "scopes": []string{util.ScopeAccountsRead}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKeyTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+			request, err := http.NewRequest(http.MethodPost, "/users/me/api_keys", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestListAPIKeysAPI(t *testing.T) {
+	key, _ := randomAPIKey(t, util.RandomOwner(), util.ScopeAccountsRead)
+	key.LastUsedAt = pgtype.Timestamptz{Time: time.Now().UTC().Truncate(time.Second), Valid: true}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListApiKeys(gomock.Any(), gomock.Eq(key.Owner)).Times(1).Return([]db.ApiKey{key}, nil)
+	server := newTestServer(t, store)
+
+	request, err := http.NewRequest(http.MethodGet, "/users/me/api_keys", nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, key.Owner, time.Minute)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp []apiKeyResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Len(t, rsp, 1)
+	require.Equal(t, key.Prefix, rsp[0].Prefix)
+	require.Equal(t, key.LastUsedAt.Time, *rsp[0].LastUsedAt)
+	require.Nil(t, rsp[0].RevokedAt)
+}
+
+func TestRevokeAPIKeyAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name          string
+		id            int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			id:   7,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.RevokeApiKeyParams{ID: 7, Owner: username}
+				store.EXPECT().RevokeAPIKeyTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			id:   7,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RevokeAPIKeyTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidID",
+			id:   0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RevokeAPIKeyTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			id:   7,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RevokeAPIKeyTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/users/me/api_keys/%d", tc.id)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
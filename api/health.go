@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "simple_bank/db/sqlc"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Server lifecycle states reported by the health endpoints.
+const (
+	stateStarting int32 = iota
+	stateServing
+	stateShuttingDown
+)
+
+const readinessTimeout = 2 * time.Second
+
+var stateNames = map[int32]string{
+	stateStarting:     "starting",
+	stateServing:      "ok",
+	stateShuttingDown: "shutting_down",
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+type readinessResponse struct {
+	Status        string       `json:"status"`
+	Error         string       `json:"error,omitempty"`
+	SchemaVersion int64        `json:"schema_version"`
+	Pool          db.PoolStats `json:"pool"`
+}
+
+// healthzHandler reports whether the process is alive and serving.
+func (server *Server) healthzHandler(ctx *gin.Context) {
+	state := server.state.Load()
+	if state != stateServing {
+		ctx.JSON(http.StatusServiceUnavailable, healthResponse{Status: stateNames[state]})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, healthResponse{Status: stateNames[state]})
+}
+
+// readyzHandler reports whether the server can take traffic: it is serving,
+// the database answers and its schema is at db.SchemaVersion.
+func (server *Server) readyzHandler(ctx *gin.Context) {
+	rsp := readinessResponse{
+		Status: stateNames[server.state.Load()],
+		Pool:   server.store.PoolStats(),
+	}
+	if rsp.Status != stateNames[stateServing] {
+		ctx.JSON(http.StatusServiceUnavailable, rsp)
+		return
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	version, err := server.checkDatabase(checkCtx)
+	rsp.SchemaVersion = version
+	if err != nil {
+		rsp.Status = "unavailable"
+		rsp.Error = err.Error()
+		ctx.JSON(http.StatusServiceUnavailable, rsp)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+func (server *Server) checkDatabase(ctx context.Context) (int64, error) {
+	if err := server.store.Ping(ctx); err != nil {
+		return 0, fmt.Errorf("cannot ping database: %w", err)
+	}
+
+	version, dirty, err := server.store.MigrationVersion(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("cannot read migration version: %w", err)
+	}
+	if dirty {
+		return version, fmt.Errorf("migration %d is dirty", version)
+	}
+	if version != db.SchemaVersion {
+		return version, fmt.Errorf("schema version %d, expected %d", version, db.SchemaVersion)
+	}
+
+	return version, nil
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthzAPI(t *testing.T) {
+	testCases := []struct {
+		name       string
+		state      int32
+		wantCode   int
+		wantStatus string
+	}{
+		{
+			name:       "OK",
+			state:      stateServing,
+			wantCode:   http.StatusOK,
+			wantStatus: "ok",
+		},
+		{
+			name:       "Starting",
+			state:      stateStarting,
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: "starting",
+		},
+		{
+			name:       "ShuttingDown",
+			state:      stateShuttingDown,
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: "shutting_down",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := newTestServer(t, mockdb.NewMockStore(ctrl))
+			server.state.Store(tc.state)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+			server.router.ServeHTTP(recorder, request)
+
+			require.Equal(t, tc.wantCode, recorder.Code)
+
+			var rsp healthResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+			require.Equal(t, tc.wantStatus, rsp.Status)
+		})
+	}
+}
+
+func TestReadyzAPI(t *testing.T) {
+	stats := db.PoolStats{TotalConns: 4, IdleConns: 3, AcquiredConns: 1, MaxConns: 10}
+
+	testCases := []struct {
+		name          string
+		state         int32
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			state: stateServing,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().PoolStats().Times(1).Return(stats)
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(db.SchemaVersion), false, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				rsp := requireBodyMatchReadiness(t, recorder)
+				require.Equal(t, "ok", rsp.Status)
+				require.Equal(t, int64(db.SchemaVersion), rsp.SchemaVersion)
+				require.Equal(t, stats, rsp.Pool)
+			},
+		},
+		{
+			name:  "Starting",
+			state: stateStarting,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().PoolStats().Times(1).Return(stats)
+				store.EXPECT().Ping(gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				require.Equal(t, "starting", requireBodyMatchReadiness(t, recorder).Status)
+			},
+		},
+		{
+			name:  "ShuttingDown",
+			state: stateShuttingDown,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().PoolStats().Times(1).Return(stats)
+				store.EXPECT().Ping(gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				require.Equal(t, "shutting_down", requireBodyMatchReadiness(t, recorder).Status)
+			},
+		},
+		{
+			name:  "PingError",
+			state: stateServing,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().PoolStats().Times(1).Return(stats)
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(errors.New("connection refused"))
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				rsp := requireBodyMatchReadiness(t, recorder)
+				require.Equal(t, "unavailable", rsp.Status)
+				require.Contains(t, rsp.Error, "connection refused")
+			},
+		},
+		{
+			name:  "SchemaVersionMismatch",
+			state: stateServing,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().PoolStats().Times(1).Return(stats)
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(db.SchemaVersion-1), false, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				rsp := requireBodyMatchReadiness(t, recorder)
+				require.Equal(t, "unavailable", rsp.Status)
+				require.Equal(t, int64(db.SchemaVersion-1), rsp.SchemaVersion)
+			},
+		},
+		{
+			name:  "DirtyMigration",
+			state: stateServing,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().PoolStats().Times(1).Return(stats)
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(db.SchemaVersion), true, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				require.Contains(t, requireBodyMatchReadiness(t, recorder).Error, "dirty")
+			},
+		},
+		{
+			name:  "MigrationVersionError",
+			state: stateServing,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().PoolStats().Times(1).Return(stats)
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(0), false, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				require.Equal(t, "unavailable", requireBodyMatchReadiness(t, recorder).Status)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.state.Store(tc.state)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			server.router.ServeHTTP(recorder, request)
+
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func requireBodyMatchReadiness(t *testing.T, recorder *httptest.ResponseRecorder) readinessResponse {
+	var rsp readinessResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	return rsp
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/logging"
+	"simple_bank/util"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	testCases := []struct {
+		name          string
+		requestID     string
+		checkResponse func(t *testing.T, requestID string, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "Accepted",
+			requestID: "client-req-42",
+			checkResponse: func(t *testing.T, requestID string, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, requestID, recorder.Header().Get(requestIDHeader))
+			},
+		},
+		{
+			name:      "Generated",
+			requestID: "",
+			checkResponse: func(t *testing.T, _ string, recorder *httptest.ResponseRecorder) {
+				_, err := uuid.Parse(recorder.Header().Get(requestIDHeader))
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:      "InvalidReplaced",
+			requestID: "bad id\nwith newline",
+			checkResponse: func(t *testing.T, requestID string, recorder *httptest.ResponseRecorder) {
+				got := recorder.Header().Get(requestIDHeader)
+				require.NotEqual(t, requestID, got)
+				_, err := uuid.Parse(got)
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:      "TooLongReplaced",
+			requestID: strings.Repeat("a", 129),
+			checkResponse: func(t *testing.T, requestID string, recorder *httptest.ResponseRecorder) {
+				require.NotEqual(t, requestID, recorder.Header().Get(requestIDHeader))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			account := createRandomAccount()
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(1).
+				DoAndReturn(func(ctx context.Context, _ int64) (db.Account, error) {
+					// The ID reaches the store through the request context.
+					require.NotEmpty(t, logging.RequestID(ctx))
+					return db.Account{}, db.ErrRecordNotFound
+				})
+
+			server := newTestServer(t, store)
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+			if tc.requestID != "" {
+				request.Header.Set(requestIDHeader, tc.requestID)
+			}
+			server.router.ServeHTTP(recorder, request)
+
+			tc.checkResponse(t, tc.requestID, recorder)
+
+			var body struct {
+				Error     string `json:"error"`
+				RequestID string `json:"request_id"`
+			}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+			require.NotEmpty(t, body.Error)
+			require.Equal(t, recorder.Header().Get(requestIDHeader), body.RequestID)
+		})
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(context.Context, int64) (db.Account, error) {
+			panic("boom")
+		})
+
+	server := newTestServer(t, store)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/accounts/1", nil)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, util.RandomOwner(), time.Minute)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	require.Contains(t, recorder.Body.String(), errInternal.Error())
+	require.Contains(t, recorder.Body.String(), recorder.Header().Get(requestIDHeader))
+}
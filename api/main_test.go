@@ -1,13 +1,85 @@
 package api
 
 import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
 	"os"
+	"sync"
 	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/mail"
+	"simple_bank/stream"
+	"simple_bank/util"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
 )
 
+// testTOTPKey seals the TOTP secrets of test servers and of totpSecret.
+const testTOTPKey = "abcdefghijklmnopqrstuvwxyz012345"
+
+func newTestServer(t *testing.T, store db.Store) *Server {
+	if store == nil {
+		store = mockdb.NewMockStore(gomock.NewController(t))
+	}
+	allowAccessTokens(store)
+
+	config := util.Config{
+		TokenSymmetricKey:   util.RandomString(32),
+		AccessTokenDuration: time.Minute,
+		TOTPEncryptionKey:   testTOTPKey,
+		OAuthCodeDuration:   time.Minute,
+		OAuthTokenDuration:  time.Minute,
+	}
+
+	server, err := NewServer(config, store, stream.NewBroker(16), mail.LogMailer{})
+	require.NoError(t, err)
+	server.resolver = testResolver{}
+
+	return server
+}
+
+// testResolver resolves internal.example.com to a private address and any
+// other host to a public one, without going to DNS.
+type testResolver struct{}
+
+func (testResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if host == "internal.example.com" {
+		return []net.IPAddr{{IP: net.ParseIP("10.0.0.5")}}, nil
+	}
+	return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+}
+
+// tokenRoles holds the role of the last access token addAuthorizationWithRole
+// created for each username.
+var tokenRoles sync.Map
+
+// allowAccessTokens lets every access token through the password change
+// check of authMiddleware, with the role it was created with as the user's
+// current role, unless a test expects its own lookups first.
+func allowAccessTokens(store db.Store) {
+	if mock, ok := store.(*mockdb.MockStore); ok {
+		mock.EXPECT().
+			GetUserAuthState(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			DoAndReturn(func(_ context.Context, username string) (db.GetUserAuthStateRow, error) {
+				role, ok := tokenRoles.Load(username)
+				if !ok {
+					role = util.CustomerRole
+				}
+				return db.GetUserAuthStateRow{Role: role.(string)}, nil
+			})
+	}
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
 	os.Exit(m.Run())
 }
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"simple_bank/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that match no route, so arbitrary paths
+// cannot blow up the metric cardinality.
+const unmatchedRoute = "unmatched"
+
+// metricsMiddleware records request counts and latency per route template and status.
+func metricsMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(ctx.Writer.Status())
+
+		metrics.HTTPRequests.WithLabelValues(ctx.Request.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(ctx.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	"simple_bank/metrics"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	account := createRandomAccount()
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+
+	server := newTestServer(t, store)
+
+	okCounter := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/accounts/:id", "200")
+	unmatchedCounter := metrics.HTTPRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")
+	okBefore := testutil.ToFloat64(okCounter)
+	unmatchedBefore := testutil.ToFloat64(unmatchedCounter)
+
+	for _, url := range []string{fmt.Sprintf("/accounts/%d", account.ID), "/no/such/route"} {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+	}
+
+	require.Equal(t, okBefore+1, testutil.ToFloat64(okCounter))
+	require.Equal(t, unmatchedBefore+1, testutil.ToFloat64(unmatchedCounter))
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	body := recorder.Body.String()
+	require.Contains(t, body, `simple_bank_http_requests_total{method="GET",route="/accounts/:id",status="200"}`)
+	require.Contains(t, body, `simple_bank_http_request_duration_seconds_bucket{method="GET",route="/accounts/:id",status="200"`)
+	require.NotContains(t, body, "/no/such/route")
+}
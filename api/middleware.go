@@ -0,0 +1,218 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"simple_bank/apikey"
+	db "simple_bank/db/sqlc"
+	"simple_bank/logging"
+	"simple_bank/oauth"
+	"simple_bank/token"
+	"simple_bank/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+	authorizationTypeAPIKey = "apikey"
+	authorizationPayloadKey = "authorization_payload"
+	apiKeyKey               = "api_key"
+	oauthGrantKey           = "oauth_grant"
+)
+
+// authMiddleware creates a gin middleware for authorization. It rejects
+// access tokens issued before the user last changed or reset their
+// password, so a password change signs out every session, including the
+// one that made it: the client has to log in again. The role
+// requireRole checks is the user's current one, not the one in the token,
+// so a demoted admin loses access at once.
+func (server *Server) authMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
+		if len(authorizationHeader) == 0 {
+			err := errors.New("authorization header is not provided")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(ctx, err))
+			return
+		}
+
+		fields := strings.Fields(authorizationHeader)
+		if len(fields) < 2 {
+			err := errors.New("invalid authorization header format")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(ctx, err))
+			return
+		}
+
+		authorizationType := strings.ToLower(fields[0])
+		if authorizationType != authorizationTypeBearer {
+			err := fmt.Errorf("unsupported authorization type %s", authorizationType)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(ctx, err))
+			return
+		}
+
+		accessToken := fields[1]
+		payload, err := server.tokenMaker.VerifyToken(accessToken)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(ctx, err))
+			return
+		}
+
+		state, err := server.store.GetUserAuthState(ctx.Request.Context(), payload.Username)
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(ctx, token.ErrRevokedToken))
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(ctx, err))
+			return
+		}
+		if payload.IssuedAt.Before(state.PasswordChangedAt) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(ctx, token.ErrRevokedToken))
+			return
+		}
+		payload.Role = state.Role
+
+		ctx.Set(authorizationPayloadKey, payload)
+		setAuditActor(ctx, payload.Username)
+		ctx.Next()
+	}
+}
+
+// scopedAuthMiddleware creates a gin middleware that authenticates like
+// authMiddleware, but also accepts an API key ("ApiKey sbk_...") or an
+// OAuth access token ("Bearer sbo_...") that was granted scope. Requests
+// made with either act for the user they belong to with the customer role,
+// whatever role that user has.
+func (server *Server) scopedAuthMiddleware(scope string) gin.HandlerFunc {
+	bearer := server.authMiddleware()
+	return func(ctx *gin.Context) {
+		fields := strings.Fields(ctx.GetHeader(authorizationHeaderKey))
+		if len(fields) < 2 {
+			bearer(ctx)
+			return
+		}
+
+		switch authorizationType := strings.ToLower(fields[0]); {
+		case authorizationType == authorizationTypeAPIKey:
+			server.authorizeAPIKey(ctx, fields[1], scope)
+		case authorizationType == authorizationTypeBearer && oauth.IsToken(fields[1]):
+			server.authorizeOAuthToken(ctx, fields[1], scope)
+		default:
+			bearer(ctx)
+		}
+	}
+}
+
+// authenticateAPIKey authenticates keyString at most once per request: the
+// rate limiter keys requests by the key's owner before scopedAuthMiddleware
+// runs.
+func (server *Server) authenticateAPIKey(ctx *gin.Context, keyString string) (db.ApiKey, error) {
+	if key, ok := ctx.Get(apiKeyKey); ok {
+		return key.(db.ApiKey), nil
+	}
+	key, err := server.apiKeys.Authenticate(ctx.Request.Context(), keyString)
+	if err != nil {
+		return db.ApiKey{}, err
+	}
+	ctx.Set(apiKeyKey, key)
+	return key, nil
+}
+
+// authenticateOAuthToken is authenticateAPIKey for OAuth access tokens.
+func (server *Server) authenticateOAuthToken(ctx *gin.Context, accessToken string) (db.OauthToken, error) {
+	if grant, ok := ctx.Get(oauthGrantKey); ok {
+		return grant.(db.OauthToken), nil
+	}
+	grant, err := server.oauth.AuthenticateToken(ctx.Request.Context(), accessToken)
+	if err != nil {
+		return db.OauthToken{}, err
+	}
+	ctx.Set(oauthGrantKey, grant)
+	return grant, nil
+}
+
+func (server *Server) authorizeAPIKey(ctx *gin.Context, keyString, scope string) {
+	key, err := server.authenticateAPIKey(ctx, keyString)
+	if err != nil {
+		if errors.Is(err, apikey.ErrInvalidKey) || errors.Is(err, apikey.ErrRevokedKey) || errors.Is(err, apikey.ErrExpiredKey) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(ctx, err))
+			return
+		}
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	if !apikey.HasScope(key, scope) {
+		err := fmt.Errorf("API key lacks the %q scope", scope)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.Set(authorizationPayloadKey, &token.Payload{
+		Username:  key.Owner,
+		Role:      util.CustomerRole,
+		IssuedAt:  key.CreatedAt,
+		ExpiredAt: key.ExpiresAt.Time,
+	})
+	setAuditActor(ctx, key.Owner)
+	ctx.Next()
+}
+
+func (server *Server) authorizeOAuthToken(ctx *gin.Context, accessToken, scope string) {
+	grant, err := server.authenticateOAuthToken(ctx, accessToken)
+	if err != nil {
+		if errors.Is(err, oauth.ErrInvalidToken) || errors.Is(err, oauth.ErrExpiredToken) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(ctx, err))
+			return
+		}
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	if !oauth.HasScope(grant, scope) {
+		err := fmt.Errorf("OAuth access token lacks the %q scope", scope)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.Set(authorizationPayloadKey, &token.Payload{
+		Username:  grant.Username,
+		Role:      util.CustomerRole,
+		IssuedAt:  grant.CreatedAt,
+		ExpiredAt: grant.ExpiresAt,
+	})
+	setAuditActor(ctx, grant.Username)
+	ctx.Next()
+}
+
+// requireRole creates a gin middleware that only lets through users who
+// have one of roles. It must run after authMiddleware, which sets the role.
+func requireRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !slices.Contains(roles, authPayload.Role) {
+			err := fmt.Errorf("role %q is not allowed to %s %s", authPayload.Role, ctx.Request.Method, ctx.FullPath())
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(ctx, err))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// setAuditActor attributes the actions the store audits while serving ctx
+// to username, along with the request ID and client IP. The client IP only
+// comes from X-Forwarded-For when the request came through one of
+// TRUSTED_PROXIES, so clients cannot put another address in the audit log.
+func setAuditActor(ctx *gin.Context, username string) {
+	reqCtx := ctx.Request.Context()
+	ctx.Request = ctx.Request.WithContext(db.WithAuditActor(reqCtx, db.AuditActor{
+		Username:  username,
+		RequestID: logging.RequestID(reqCtx),
+		IP:        ctx.ClientIP(),
+	}))
+}
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/mail"
+	"simple_bank/stream"
+	"simple_bank/token"
+	"simple_bank/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func addAuthorization(
+	t *testing.T,
+	request *http.Request,
+	tokenMaker token.Maker,
+	authorizationType string,
+	username string,
+	duration time.Duration,
+) {
+	addAuthorizationWithRole(t, request, tokenMaker, authorizationType, username, util.CustomerRole, duration)
+}
+
+func addAuthorizationWithRole(
+	t *testing.T,
+	request *http.Request,
+	tokenMaker token.Maker,
+	authorizationType string,
+	username string,
+	role string,
+	duration time.Duration,
+) {
+	token, payload, err := tokenMaker.CreateToken(username, role, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+	tokenRoles.Store(username, role)
+
+	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, token)
+	request.Header.Set(authorizationHeaderKey, authorizationHeader)
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UnsupportedAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, "unsupported", "user", time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidAuthorizationFormat",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, "", "user", time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", -time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				server.authMiddleware(),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAuthMiddlewarePasswordChanged(t *testing.T) {
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "IssuedAfterChange",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserAuthState(gomock.Any(), gomock.Eq("user")).
+					Times(1).
+					Return(db.GetUserAuthStateRow{Role: util.CustomerRole, PasswordChangedAt: time.Now().Add(-time.Hour)}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "IssuedBeforeChange",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserAuthState(gomock.Any(), gomock.Eq("user")).
+					Times(1).
+					Return(db.GetUserAuthStateRow{Role: util.CustomerRole, PasswordChangedAt: time.Now().Add(time.Second)}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), token.ErrRevokedToken.Error())
+			},
+		},
+		{
+			name: "UserDeleted",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserAuthState(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetUserAuthStateRow{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserAuthState(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetUserAuthStateRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				server.authMiddleware(),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "user", time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestSetAuditActorForwardedFor(t *testing.T) {
+	testCases := []struct {
+		name           string
+		trustedProxies []string
+		wantIP         string
+	}{
+		{
+			name:   "SpoofedHeader",
+			wantIP: "10.0.0.9",
+		},
+		{
+			name:           "TrustedProxy",
+			trustedProxies: []string{"10.0.0.0/8"},
+			wantIP:         "6.6.6.6",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := mockdb.NewMockStore(gomock.NewController(t))
+			allowAccessTokens(store)
+			server, err := NewServer(util.Config{
+				TokenSymmetricKey: util.RandomString(32),
+				TrustedProxies:    tc.trustedProxies,
+			}, store, stream.NewBroker(16), mail.LogMailer{})
+			require.NoError(t, err)
+
+			var actor db.AuditActor
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				server.authMiddleware(),
+				func(ctx *gin.Context) {
+					actor = db.AuditActorFrom(ctx.Request.Context())
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+			request.RemoteAddr = "10.0.0.9:1234"
+			request.Header.Set("X-Forwarded-For", "6.6.6.6")
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "user", time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+			require.Equal(t, "user", actor.Username)
+			require.Equal(t, tc.wantIP, actor.IP)
+		})
+	}
+}
+
+// TestRequireRoleCurrentRole checks that role changes apply to access tokens
+// issued before them.
+func TestRequireRoleCurrentRole(t *testing.T) {
+	testCases := []struct {
+		name        string
+		tokenRole   string
+		currentRole string
+		wantCode    int
+	}{
+		{name: "Demoted", tokenRole: util.AdminRole, currentRole: util.CustomerRole, wantCode: http.StatusForbidden},
+		{name: "Promoted", tokenRole: util.CustomerRole, currentRole: util.AdminRole, wantCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUserAuthState(gomock.Any(), gomock.Eq("operator")).
+				Times(1).
+				Return(db.GetUserAuthStateRow{Role: tc.currentRole}, nil)
+			server := newTestServer(t, store)
+
+			authPath := "/admin_only"
+			server.router.GET(
+				authPath,
+				server.authMiddleware(),
+				requireRole(util.AdminRole),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+
+			addAuthorizationWithRole(t, request, server.tokenMaker, authorizationTypeBearer, "operator", tc.tokenRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantCode, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,382 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/oauth"
+	"simple_bank/token"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type registerOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,max=10,unique,dive,url"`
+	Scopes       []string `json:"scopes" binding:"required,min=1,unique,dive,api_scope"`
+	Confidential bool     `json:"confidential"`
+}
+
+// oauthClientResponse carries the client secret only when the client is
+// registered.
+type oauthClientResponse struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (server *Server) registerOAuthClientHandler(ctx *gin.Context) {
+	var req registerOAuthClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	// RFC 6749 section 3.1.2 does not allow fragments in redirect URIs.
+	for _, uri := range req.RedirectURIs {
+		if u, err := url.Parse(uri); err != nil || u.Fragment != "" {
+			err := fmt.Errorf("redirect URI %q must be absolute and have no fragment", uri)
+			ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+			return
+		}
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	client, secret, err := server.oauth.RegisterClient(ctx.Request.Context(), oauth.RegisterClientParams{
+		Owner:        authPayload.Username,
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       req.Scopes,
+		Confidential: req.Confidential,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, oauthClientResponse{
+		ClientID:     client.ID,
+		ClientSecret: secret,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Scopes:       client.Scopes,
+		Confidential: client.SecretHash != nil,
+		CreatedAt:    client.CreatedAt,
+	})
+}
+
+// oauthAuthorizeRequest holds the parameters of RFC 6749 section 4.1.1.
+// The consent screen reads them from the query string of the link the
+// client sent the user to and passes them back when the user decides.
+type oauthAuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri" binding:"required"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+}
+
+func (req oauthAuthorizeRequest) authorizationRequest() oauth.AuthorizationRequest {
+	return oauth.AuthorizationRequest{
+		ResponseType:        req.ResponseType,
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	}
+}
+
+type oauthConsentResponse struct {
+	ClientID    string   `json:"client_id"`
+	ClientName  string   `json:"client_name"`
+	RedirectURI string   `json:"redirect_uri"`
+	Scopes      []string `json:"scopes"`
+}
+
+// getOAuthAuthorizationHandler checks an authorization request and returns
+// what the consent screen should ask the user. All errors are returned to
+// the consent screen, which shows them.
+func (server *Server) getOAuthAuthorizationHandler(ctx *gin.Context) {
+	var req oauthAuthorizeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	auth, err := server.oauth.ValidateAuthorization(ctx.Request.Context(), req.authorizationRequest())
+	if err != nil {
+		ctx.JSON(oauthStatus(err), errorResponse(ctx, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, oauthConsentResponse{
+		ClientID:    auth.Client.ID,
+		ClientName:  auth.Client.Name,
+		RedirectURI: auth.RedirectURI,
+		Scopes:      auth.Scopes,
+	})
+}
+
+type oauthDecisionRequest struct {
+	oauthAuthorizeRequest
+	Approve *bool `json:"approve" binding:"required"`
+}
+
+// oauthRedirectResponse tells the consent screen where to send the user.
+type oauthRedirectResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// oauthAuthorizeHandler records the user's decision on an authorization
+// request. Once the redirect URI is known to be the client's, the outcome,
+// whether a code or an error, goes to the client through redirect_to.
+func (server *Server) oauthAuthorizeHandler(ctx *gin.Context) {
+	var req oauthDecisionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	auth, err := server.oauth.ValidateAuthorization(ctx.Request.Context(), req.authorizationRequest())
+	if err != nil {
+		var redirectable *oauth.RedirectableError
+		if !errors.As(err, &redirectable) {
+			ctx.JSON(oauthStatus(err), errorResponse(ctx, err))
+			return
+		}
+		_ = ctx.Error(err)
+		redirectOAuthClient(ctx, auth.RedirectURI, oauthErrorParams(err), req.State)
+		return
+	}
+
+	if !*req.Approve {
+		err := fmt.Errorf("%w: the user denied the request", oauth.ErrAccessDenied)
+		redirectOAuthClient(ctx, auth.RedirectURI, oauthErrorParams(err), req.State)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	code, err := server.oauth.Authorize(ctx.Request.Context(), authPayload.Username, auth)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	redirectOAuthClient(ctx, auth.RedirectURI, url.Values{"code": {code}}, req.State)
+}
+
+// redirectOAuthClient responds with redirectURI with params and state added
+// to its query.
+func redirectOAuthClient(ctx *gin.Context, redirectURI string, params url.Values, state string) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	u.RawQuery = query.Encode()
+
+	ctx.JSON(http.StatusOK, oauthRedirectResponse{RedirectTo: u.String()})
+}
+
+type oauthTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	Scope        string `form:"scope"`
+}
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// oauthTokenHandler is the token endpoint of RFC 6749 section 3.2. It takes
+// a form, and errors are in the RFC's format rather than errorResponse's.
+func (server *Server) oauthTokenHandler(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	client, err := server.authenticateOAuthClient(ctx)
+	if err != nil {
+		oauthErrorResponse(ctx, err)
+		return
+	}
+
+	var req oauthTokenRequest
+	if err := ctx.ShouldBindWith(&req, binding.FormPost); err != nil {
+		oauthErrorResponse(ctx, fmt.Errorf("%w: %s", oauth.ErrInvalidRequest, err))
+		return
+	}
+
+	var tok oauth.Token
+	switch req.GrantType {
+	case oauth.GrantAuthorizationCode:
+		if req.Code == "" || req.RedirectURI == "" {
+			err = fmt.Errorf("%w: code and redirect_uri are required", oauth.ErrInvalidRequest)
+			break
+		}
+		tok, err = server.oauth.ExchangeCode(ctx.Request.Context(), client, req.Code, req.RedirectURI, req.CodeVerifier)
+	case oauth.GrantClientCredentials:
+		tok, err = server.oauth.ClientCredentials(ctx.Request.Context(), client, req.Scope)
+	default:
+		err = fmt.Errorf("%w: %q", oauth.ErrUnsupportedGrantType, req.GrantType)
+	}
+	if err != nil {
+		oauthErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, oauthTokenResponse{
+		AccessToken: tok.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(tok.ExpiresIn / time.Second),
+		Scope:       oauth.FormatScope(tok.Scopes),
+	})
+}
+
+type oauthIntrospectRequest struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+}
+
+// oauthIntrospectResponse follows RFC 7662 section 2.2. Inactive tokens
+// only have the active field.
+type oauthIntrospectResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+}
+
+// oauthIntrospectHandler tells a client whether a token is active. Clients
+// can only introspect their own tokens; those of other clients are
+// reported as inactive.
+func (server *Server) oauthIntrospectHandler(ctx *gin.Context) {
+	client, err := server.authenticateOAuthClient(ctx)
+	if err != nil {
+		oauthErrorResponse(ctx, err)
+		return
+	}
+
+	var req oauthIntrospectRequest
+	if err := ctx.ShouldBindWith(&req, binding.FormPost); err != nil {
+		oauthErrorResponse(ctx, fmt.Errorf("%w: %s", oauth.ErrInvalidRequest, err))
+		return
+	}
+
+	grant, err := server.oauth.AuthenticateToken(ctx.Request.Context(), req.Token)
+	if err != nil {
+		if errors.Is(err, oauth.ErrInvalidToken) || errors.Is(err, oauth.ErrExpiredToken) {
+			ctx.JSON(http.StatusOK, oauthIntrospectResponse{Active: false})
+			return
+		}
+		oauthErrorResponse(ctx, err)
+		return
+	}
+	if grant.ClientID != client.ID {
+		ctx.JSON(http.StatusOK, oauthIntrospectResponse{Active: false})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, oauthIntrospectResponse{
+		Active:    true,
+		Scope:     oauth.FormatScope(grant.Scopes),
+		ClientID:  grant.ClientID,
+		Username:  grant.Username,
+		TokenType: "Bearer",
+		Exp:       grant.ExpiresAt.Unix(),
+		Iat:       grant.CreatedAt.Unix(),
+	})
+}
+
+// authenticateOAuthClient authenticates the client calling the token or
+// introspection endpoint, with HTTP Basic or client_id and client_secret
+// in the form (RFC 6749 section 2.3.1).
+func (server *Server) authenticateOAuthClient(ctx *gin.Context) (db.OauthClient, error) {
+	clientID, secret, ok := ctx.Request.BasicAuth()
+	if ok {
+		// The RFC form-encodes the credentials before Basic encodes them.
+		var idErr, secretErr error
+		clientID, idErr = url.QueryUnescape(clientID)
+		secret, secretErr = url.QueryUnescape(secret)
+		if idErr != nil || secretErr != nil {
+			return db.OauthClient{}, fmt.Errorf("%w: malformed client credentials", oauth.ErrInvalidClient)
+		}
+	} else {
+		clientID = ctx.PostForm("client_id")
+		secret = ctx.PostForm("client_secret")
+	}
+
+	if clientID == "" {
+		return db.OauthClient{}, fmt.Errorf("%w: client authentication is required", oauth.ErrInvalidClient)
+	}
+	return server.oauth.AuthenticateClient(ctx.Request.Context(), clientID, secret)
+}
+
+// oauthErrorResponse writes an error response as RFC 6749 section 5.2 has
+// it. invalid_client is a 401 and other client errors are a 400; anything
+// else is a 500 whose details only go to the request log.
+func oauthErrorResponse(ctx *gin.Context, err error) {
+	_ = ctx.Error(err)
+
+	code := oauth.ErrorCode(err)
+	switch code {
+	case oauth.ErrInvalidClient.Error():
+		ctx.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": code, "error_description": oauthErrorDescription(err)})
+	case "server_error":
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": code})
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": code, "error_description": oauthErrorDescription(err)})
+	}
+}
+
+// oauthErrorParams returns the query parameters that send err to a client's
+// redirect URI.
+func oauthErrorParams(err error) url.Values {
+	return url.Values{
+		"error":             {oauth.ErrorCode(err)},
+		"error_description": {oauthErrorDescription(err)},
+	}
+}
+
+// oauthErrorDescription returns what err says beyond its error code.
+func oauthErrorDescription(err error) string {
+	return strings.TrimPrefix(err.Error(), oauth.ErrorCode(err)+": ")
+}
+
+// oauthStatus returns the status of an authorization request error shown
+// to the user rather than sent to the client.
+func oauthStatus(err error) int {
+	if oauth.ErrorCode(err) == "server_error" {
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,590 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/oauth"
+	"simple_bank/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+const testOAuthRedirectURI = "https://app.example.com/callback"
+
+// oauthTestStore keeps the OAuth rows of a mock store in maps, so that tests
+// can walk through whole flows the way a client would.
+type oauthTestStore struct {
+	clients map[string]db.OauthClient
+	codes   map[string]db.OauthAuthorizationCode
+	tokens  map[string]db.OauthToken
+}
+
+func newOAuthTestStore(store *mockdb.MockStore) *oauthTestStore {
+	s := &oauthTestStore{
+		clients: map[string]db.OauthClient{},
+		codes:   map[string]db.OauthAuthorizationCode{},
+		tokens:  map[string]db.OauthToken{},
+	}
+
+	store.EXPECT().
+		CreateOAuthClientTx(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.CreateOauthClientParams) (db.OauthClient, error) {
+			client := db.OauthClient{
+				ID:           arg.ID,
+				Owner:        arg.Owner,
+				Name:         arg.Name,
+				SecretHash:   arg.SecretHash,
+				RedirectUris: arg.RedirectUris,
+				Scopes:       arg.Scopes,
+				CreatedAt:    time.Now(),
+			}
+			s.clients[client.ID] = client
+			return client, nil
+		})
+	store.EXPECT().
+		GetOauthClient(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, id string) (db.OauthClient, error) {
+			client, ok := s.clients[id]
+			if !ok {
+				return db.OauthClient{}, db.ErrRecordNotFound
+			}
+			return client, nil
+		})
+	store.EXPECT().
+		GrantOAuthCodeTx(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.CreateOauthAuthorizationCodeParams) (db.OauthAuthorizationCode, error) {
+			code := db.OauthAuthorizationCode{
+				CodeHash:      arg.CodeHash,
+				ClientID:      arg.ClientID,
+				Username:      arg.Username,
+				RedirectUri:   arg.RedirectUri,
+				Scopes:        arg.Scopes,
+				CodeChallenge: arg.CodeChallenge,
+				ExpiresAt:     arg.ExpiresAt,
+				CreatedAt:     time.Now(),
+			}
+			s.codes[string(code.CodeHash)] = code
+			return code, nil
+		})
+	store.EXPECT().
+		UseOauthAuthorizationCode(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, codeHash []byte) (db.OauthAuthorizationCode, error) {
+			code, ok := s.codes[string(codeHash)]
+			if !ok || code.UsedAt.Valid || !time.Now().Before(code.ExpiresAt) {
+				return db.OauthAuthorizationCode{}, db.ErrRecordNotFound
+			}
+			code.UsedAt.Time, code.UsedAt.Valid = time.Now(), true
+			s.codes[string(codeHash)] = code
+			return code, nil
+		})
+	store.EXPECT().
+		CreateOauthToken(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.CreateOauthTokenParams) (db.OauthToken, error) {
+			tok := db.OauthToken{
+				TokenHash: arg.TokenHash,
+				ClientID:  arg.ClientID,
+				Username:  arg.Username,
+				Scopes:    arg.Scopes,
+				ExpiresAt: arg.ExpiresAt,
+				CreatedAt: time.Now(),
+			}
+			s.tokens[string(tok.TokenHash)] = tok
+			return tok, nil
+		})
+	store.EXPECT().
+		GetOauthToken(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, tokenHash []byte) (db.OauthToken, error) {
+			tok, ok := s.tokens[string(tokenHash)]
+			if !ok {
+				return db.OauthToken{}, db.ErrRecordNotFound
+			}
+			return tok, nil
+		})
+
+	return s
+}
+
+// serveOAuth sends a request to server, with a login token for username
+// unless it is "", and returns the response.
+func serveOAuth(t *testing.T, server *Server, request *http.Request, username string) *httptest.ResponseRecorder {
+	if username != "" {
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+	}
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func newJSONRequest(t *testing.T, method, path string, body any) *http.Request {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	request, err := http.NewRequest(method, path, bytes.NewReader(data))
+	require.NoError(t, err)
+	return request
+}
+
+func newFormRequest(t *testing.T, path string, form url.Values) *http.Request {
+	request, err := http.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return request
+}
+
+func decodeJSON[T any](t *testing.T, recorder *httptest.ResponseRecorder) T {
+	var v T
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &v))
+	return v
+}
+
+// registerTestOAuthClient registers a client for developer through the API.
+func registerTestOAuthClient(t *testing.T, server *Server, developer string, confidential bool, scopes ...string) oauthClientResponse {
+	recorder := serveOAuth(t, server, newJSONRequest(t, http.MethodPost, "/oauth/clients", gin.H{
+		"name":          "budget app",
+		"redirect_uris": []string{testOAuthRedirectURI},
+		"scopes":        scopes,
+		"confidential":  confidential,
+	}), developer)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	return decodeJSON[oauthClientResponse](t, recorder)
+}
+
+// authorizeQuery returns the query string a client sends the user to the
+// consent screen with.
+func authorizeQuery(clientID, scope, verifier string) url.Values {
+	return url.Values{
+		"response_type":         {oauth.ResponseTypeCode},
+		"client_id":             {clientID},
+		"redirect_uri":          {testOAuthRedirectURI},
+		"scope":                 {scope},
+		"state":                 {"xyz"},
+		"code_challenge":        {oauth.S256Challenge(verifier)},
+		"code_challenge_method": {oauth.ChallengeMethodS256},
+	}
+}
+
+// decide posts the user's decision on the request in query and returns the
+// query of the URI the user is sent back to the client with.
+func decide(t *testing.T, server *Server, username string, query url.Values, approve bool) url.Values {
+	body := gin.H{"approve": approve}
+	for key := range query {
+		body[key] = query.Get(key)
+	}
+	recorder := serveOAuth(t, server, newJSONRequest(t, http.MethodPost, "/oauth/authorize", body), username)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	redirectTo, err := url.Parse(decodeJSON[oauthRedirectResponse](t, recorder).RedirectTo)
+	require.NoError(t, err)
+	require.Equal(t, testOAuthRedirectURI, fmt.Sprintf("%s://%s%s", redirectTo.Scheme, redirectTo.Host, redirectTo.Path))
+	require.Equal(t, "xyz", redirectTo.Query().Get("state"))
+	return redirectTo.Query()
+}
+
+func TestOAuthAuthorizationCodeFlow(t *testing.T) {
+	account := createRandomAccount()
+	developer := util.RandomOwner()
+	verifier := util.RandomString(64)
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	newOAuthTestStore(store)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+	server := newTestServer(t, store)
+
+	// A public client, such as a mobile app, registers without a secret.
+	client := registerTestOAuthClient(t, server, developer, false, util.ScopeAccountsRead, util.ScopeTransfersCreate)
+	require.Empty(t, client.ClientSecret)
+	require.False(t, client.Confidential)
+
+	// The consent screen shows what the client asks for.
+	query := authorizeQuery(client.ClientID, util.ScopeAccountsRead, verifier)
+	request, err := http.NewRequest(http.MethodGet, "/oauth/authorize?"+query.Encode(), nil)
+	require.NoError(t, err)
+	recorder := serveOAuth(t, server, request, account.Owner)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	consent := decodeJSON[oauthConsentResponse](t, recorder)
+	require.Equal(t, "budget app", consent.ClientName)
+	require.Equal(t, []string{util.ScopeAccountsRead}, consent.Scopes)
+
+	// The user approves and the client gets a code.
+	code := decide(t, server, account.Owner, query, true).Get("code")
+	require.NotEmpty(t, code)
+
+	exchange := url.Values{
+		"grant_type":    {oauth.GrantAuthorizationCode},
+		"client_id":     {client.ClientID},
+		"code":          {code},
+		"redirect_uri":  {testOAuthRedirectURI},
+		"code_verifier": {verifier},
+	}
+	recorder = serveOAuth(t, server, newFormRequest(t, "/oauth/token", exchange), "")
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	require.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+	tok := decodeJSON[oauthTokenResponse](t, recorder)
+	require.Equal(t, "Bearer", tok.TokenType)
+	require.Equal(t, util.ScopeAccountsRead, tok.Scope)
+	require.Equal(t, int64(60), tok.ExpiresIn)
+
+	// Codes only work once.
+	recorder = serveOAuth(t, server, newFormRequest(t, "/oauth/token", exchange), "")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Equal(t, "invalid_grant", decodeJSON[gin.H](t, recorder)["error"])
+
+	// The token acts for the user within its scope.
+	request, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, "Bearer "+tok.AccessToken)
+	recorder = serveOAuth(t, server, request, "")
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	requireBodyMatchAccount(t, recorder.Body, account)
+
+	request = newJSONRequest(t, http.MethodPost, "/transfers", gin.H{
+		"from_account_id": account.ID,
+		"to_account_id":   account.ID + 1,
+		"amount":          1,
+		"currency":        account.Currency,
+	})
+	request.Header.Set(authorizationHeaderKey, "Bearer "+tok.AccessToken)
+	recorder = serveOAuth(t, server, request, "")
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+
+	// Routes without a scope do not take OAuth tokens.
+	request, err = http.NewRequest(http.MethodGet, "/users/me/api_keys", nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, "Bearer "+tok.AccessToken)
+	recorder = serveOAuth(t, server, request, "")
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	// The client can introspect its token.
+	recorder = serveOAuth(t, server, newFormRequest(t, "/oauth/introspect", url.Values{
+		"client_id": {client.ClientID},
+		"token":     {tok.AccessToken},
+	}), "")
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	introspection := decodeJSON[oauthIntrospectResponse](t, recorder)
+	require.True(t, introspection.Active)
+	require.Equal(t, account.Owner, introspection.Username)
+	require.Equal(t, client.ClientID, introspection.ClientID)
+	require.Equal(t, util.ScopeAccountsRead, introspection.Scope)
+	require.Greater(t, introspection.Exp, introspection.Iat)
+}
+
+func TestOAuthClientCredentialsFlow(t *testing.T) {
+	developer := util.RandomOwner()
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	newOAuthTestStore(store)
+	store.EXPECT().
+		ListAccounts(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.ListAccountsParams) ([]db.Account, error) {
+			require.Equal(t, developer, arg.Username)
+			return []db.Account{}, nil
+		})
+	server := newTestServer(t, store)
+
+	client := registerTestOAuthClient(t, server, developer, true, util.ScopeAccountsRead)
+	require.NotEmpty(t, client.ClientSecret)
+	other := registerTestOAuthClient(t, server, developer, true, util.ScopeAccountsRead)
+
+	request := newFormRequest(t, "/oauth/token", url.Values{"grant_type": {oauth.GrantClientCredentials}})
+	request.SetBasicAuth(client.ClientID, client.ClientSecret)
+	recorder := serveOAuth(t, server, request, "")
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	tok := decodeJSON[oauthTokenResponse](t, recorder)
+	require.Equal(t, util.ScopeAccountsRead, tok.Scope)
+
+	// The token acts for the developer who registered the client.
+	request, err := http.NewRequest(http.MethodGet, "/accounts?page_id=1&page_size=5", nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, "Bearer "+tok.AccessToken)
+	recorder = serveOAuth(t, server, request, "")
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	// Other clients cannot see it.
+	request = newFormRequest(t, "/oauth/introspect", url.Values{"token": {tok.AccessToken}})
+	request.SetBasicAuth(other.ClientID, other.ClientSecret)
+	recorder = serveOAuth(t, server, request, "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, gin.H{"active": false}, decodeJSON[gin.H](t, recorder))
+}
+
+func TestOAuthAuthorizeAPI(t *testing.T) {
+	username := util.RandomOwner()
+	verifier := util.RandomString(43)
+
+	testCases := []struct {
+		name          string
+		modify        func(query url.Values)
+		approve       bool
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "Denied",
+			modify:  func(query url.Values) {},
+			approve: false,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				redirectTo := decodeJSON[oauthRedirectResponse](t, recorder).RedirectTo
+				require.Contains(t, redirectTo, "error=access_denied")
+				require.NotContains(t, redirectTo, "code=")
+			},
+		},
+		{
+			name:    "NoPKCE",
+			modify:  func(query url.Values) { query.Del("code_challenge") },
+			approve: true,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				redirectTo := decodeJSON[oauthRedirectResponse](t, recorder).RedirectTo
+				require.Contains(t, redirectTo, "error=invalid_request")
+				require.Contains(t, redirectTo, "state=xyz")
+			},
+		},
+		{
+			name:    "UnknownScope",
+			modify:  func(query url.Values) { query.Set("scope", "accounts:delete") },
+			approve: true,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, decodeJSON[oauthRedirectResponse](t, recorder).RedirectTo, "error=invalid_scope")
+			},
+		},
+		{
+			// Errors are never sent to a redirect URI the client did not
+			// register.
+			name:    "UnregisteredRedirectURI",
+			modify:  func(query url.Values) { query.Set("redirect_uri", "https://evil.example.com/callback") },
+			approve: true,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "redirect_to")
+			},
+		},
+		{
+			name:    "UnknownClient",
+			modify:  func(query url.Values) { query.Set("client_id", "unknown") },
+			approve: true,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			oauthStore := newOAuthTestStore(store)
+			server := newTestServer(t, store)
+
+			client := registerTestOAuthClient(t, server, util.RandomOwner(), false, util.ScopeAccountsRead)
+			query := authorizeQuery(client.ClientID, "", verifier)
+			tc.modify(query)
+
+			body := gin.H{"approve": tc.approve}
+			for key := range query {
+				body[key] = query.Get(key)
+			}
+			recorder := serveOAuth(t, server, newJSONRequest(t, http.MethodPost, "/oauth/authorize", body), username)
+			tc.checkResponse(t, recorder)
+			require.Empty(t, oauthStore.codes)
+		})
+	}
+}
+
+func TestOAuthTokenAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		setupRequest  func(t *testing.T, server *Server, client oauthClientResponse) *http.Request
+		wantStatus    int
+		wantErrorCode string
+	}{
+		{
+			name: "NoClientAuthentication",
+			setupRequest: func(t *testing.T, server *Server, client oauthClientResponse) *http.Request {
+				return newFormRequest(t, "/oauth/token", url.Values{"grant_type": {oauth.GrantClientCredentials}})
+			},
+			wantStatus:    http.StatusUnauthorized,
+			wantErrorCode: "invalid_client",
+		},
+		{
+			name: "WrongSecret",
+			setupRequest: func(t *testing.T, server *Server, client oauthClientResponse) *http.Request {
+				request := newFormRequest(t, "/oauth/token", url.Values{"grant_type": {oauth.GrantClientCredentials}})
+				request.SetBasicAuth(client.ClientID, "wrong")
+				return request
+			},
+			wantStatus:    http.StatusUnauthorized,
+			wantErrorCode: "invalid_client",
+		},
+		{
+			name: "SecretInForm",
+			setupRequest: func(t *testing.T, server *Server, client oauthClientResponse) *http.Request {
+				return newFormRequest(t, "/oauth/token", url.Values{
+					"grant_type":    {oauth.GrantClientCredentials},
+					"client_id":     {client.ClientID},
+					"client_secret": {client.ClientSecret},
+				})
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "UnsupportedGrantType",
+			setupRequest: func(t *testing.T, server *Server, client oauthClientResponse) *http.Request {
+				request := newFormRequest(t, "/oauth/token", url.Values{"grant_type": {"password"}})
+				request.SetBasicAuth(client.ClientID, client.ClientSecret)
+				return request
+			},
+			wantStatus:    http.StatusBadRequest,
+			wantErrorCode: "unsupported_grant_type",
+		},
+		{
+			name: "MissingGrantType",
+			setupRequest: func(t *testing.T, server *Server, client oauthClientResponse) *http.Request {
+				request := newFormRequest(t, "/oauth/token", url.Values{})
+				request.SetBasicAuth(client.ClientID, client.ClientSecret)
+				return request
+			},
+			wantStatus:    http.StatusBadRequest,
+			wantErrorCode: "invalid_request",
+		},
+		{
+			name: "UnknownCode",
+			setupRequest: func(t *testing.T, server *Server, client oauthClientResponse) *http.Request {
+				request := newFormRequest(t, "/oauth/token", url.Values{
+					"grant_type":    {oauth.GrantAuthorizationCode},
+					"code":          {"unknown"},
+					"redirect_uri":  {testOAuthRedirectURI},
+					"code_verifier": {util.RandomString(43)},
+				})
+				request.SetBasicAuth(client.ClientID, client.ClientSecret)
+				return request
+			},
+			wantStatus:    http.StatusBadRequest,
+			wantErrorCode: "invalid_grant",
+		},
+		{
+			name: "ScopeNotRegistered",
+			setupRequest: func(t *testing.T, server *Server, client oauthClientResponse) *http.Request {
+				request := newFormRequest(t, "/oauth/token", url.Values{
+					"grant_type": {oauth.GrantClientCredentials},
+					"scope":      {util.ScopeTransfersCreate},
+				})
+				request.SetBasicAuth(client.ClientID, client.ClientSecret)
+				return request
+			},
+			wantStatus:    http.StatusBadRequest,
+			wantErrorCode: "invalid_scope",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			newOAuthTestStore(store)
+			server := newTestServer(t, store)
+
+			client := registerTestOAuthClient(t, server, util.RandomOwner(), true, util.ScopeAccountsRead)
+			recorder := serveOAuth(t, server, tc.setupRequest(t, server, client), "")
+			require.Equal(t, tc.wantStatus, recorder.Code, recorder.Body.String())
+			if tc.wantErrorCode != "" {
+				require.Equal(t, tc.wantErrorCode, decodeJSON[gin.H](t, recorder)["error"])
+			}
+		})
+	}
+}
+
+func TestRegisterOAuthClientAPI(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       gin.H
+		wantStatus int
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"name":          "budget app",
+				"redirect_uris": []string{testOAuthRedirectURI, "com.example.app://callback"},
+				"scopes":        []string{util.ScopeAccountsRead},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "RedirectURIWithFragment",
+			body: gin.H{
+				"name":          "budget app",
+				"redirect_uris": []string{testOAuthRedirectURI + "#frag"},
+				"scopes":        []string{util.ScopeAccountsRead},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "RelativeRedirectURI",
+			body: gin.H{
+				"name":          "budget app",
+				"redirect_uris": []string{"/callback"},
+				"scopes":        []string{util.ScopeAccountsRead},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "UnknownScope",
+			body: gin.H{
+				"name":          "budget app",
+				"redirect_uris": []string{testOAuthRedirectURI},
+				"scopes":        []string{"accounts:delete"},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "NoRedirectURIs",
+			body: gin.H{
+				"name":   "budget app",
+				"scopes": []string{util.ScopeAccountsRead},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			oauthStore := newOAuthTestStore(store)
+			server := newTestServer(t, store)
+
+			developer := util.RandomOwner()
+			recorder := serveOAuth(t, server, newJSONRequest(t, http.MethodPost, "/oauth/clients", tc.body), developer)
+			require.Equal(t, tc.wantStatus, recorder.Code, recorder.Body.String())
+			if tc.wantStatus != http.StatusOK {
+				require.Empty(t, oauthStore.clients)
+				return
+			}
+
+			client := decodeJSON[oauthClientResponse](t, recorder)
+			require.Equal(t, developer, oauthStore.clients[client.ClientID].Owner)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/oauth"
+	"simple_bank/ratelimit"
+	"simple_bank/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rate limit response headers, following the IETF RateLimit header fields draft.
+const (
+	rateLimitLimitHeader     = "RateLimit-Limit"
+	rateLimitRemainingHeader = "RateLimit-Remaining"
+	rateLimitResetHeader     = "RateLimit-Reset"
+	retryAfterHeader         = "Retry-After"
+)
+
+var errRateLimited = errors.New("rate limit exceeded, retry later")
+
+// rateLimits are the limits applied to all routes and, on top of that, to
+// the sensitive ones.
+type rateLimits struct {
+	global    ratelimit.Limit
+	transfers ratelimit.Limit
+	login     ratelimit.Limit
+}
+
+// newRateLimiter builds the limiter and limits from config. An empty backend
+// disables rate limiting.
+func newRateLimiter(config util.Config, store db.Store) (ratelimit.Limiter, rateLimits, error) {
+	var limits rateLimits
+	var err error
+
+	if limits.global, err = ratelimit.ParseLimit(config.RateLimitDefault); err != nil {
+		return nil, limits, err
+	}
+	if limits.transfers, err = ratelimit.ParseLimit(config.RateLimitTransfers); err != nil {
+		return nil, limits, err
+	}
+	if limits.login, err = ratelimit.ParseLimit(config.RateLimitLogin); err != nil {
+		return nil, limits, err
+	}
+
+	switch config.RateLimitBackend {
+	case "":
+		return nil, limits, nil
+	case ratelimit.BackendMemory:
+		return ratelimit.NewMemoryLimiter(), limits, nil
+	case ratelimit.BackendPostgres:
+		return ratelimit.NewPostgresLimiter(store), limits, nil
+	default:
+		return nil, limits, fmt.Errorf("unsupported rate limit backend %q", config.RateLimitBackend)
+	}
+}
+
+// rateLimitMiddleware takes a token from the client's bucket for scope and
+// rejects the request with 429 once it is empty.
+func (server *Server) rateLimitMiddleware(scope string, limit ratelimit.Limit) gin.HandlerFunc {
+	if server.limiter == nil || limit.IsZero() {
+		return func(ctx *gin.Context) {
+			ctx.Next()
+		}
+	}
+
+	return func(ctx *gin.Context) {
+		key := scope + ":" + server.rateLimitKey(ctx)
+		result, err := server.limiter.Allow(ctx.Request.Context(), key, limit)
+		if err != nil {
+			// Fail open: an unavailable limiter backend must not take the API down.
+			slog.WarnContext(ctx.Request.Context(), "rate limiter unavailable", "err", err)
+			ctx.Next()
+			return
+		}
+
+		ctx.Header(rateLimitLimitHeader, strconv.Itoa(result.Limit))
+		ctx.Header(rateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+		ctx.Header(rateLimitResetHeader, ceilSeconds(result.Reset))
+
+		if !result.Allowed {
+			ctx.Header(retryAfterHeader, ceilSeconds(result.RetryAfter))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, errorResponse(ctx, errRateLimited))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// rateLimitKey identifies the client: the authenticated user when the request
+// carries a valid access token, API key or OAuth access token, the client IP
+// otherwise. Credentials that do not authenticate count against the IP, so
+// a client cannot pick a fresh bucket by making one up.
+func (server *Server) rateLimitKey(ctx *gin.Context) string {
+	fields := strings.Fields(ctx.GetHeader(authorizationHeaderKey))
+	if len(fields) == 2 {
+		switch authorizationType := strings.ToLower(fields[0]); {
+		case authorizationType == authorizationTypeAPIKey:
+			if key, err := server.authenticateAPIKey(ctx, fields[1]); err == nil {
+				return "user:" + key.Owner
+			}
+		case authorizationType == authorizationTypeBearer && oauth.IsToken(fields[1]):
+			if grant, err := server.authenticateOAuthToken(ctx, fields[1]); err == nil {
+				return "user:" + grant.Username
+			}
+		case authorizationType == authorizationTypeBearer:
+			if payload, err := server.tokenMaker.VerifyToken(fields[1]); err == nil {
+				return "user:" + payload.Username
+			}
+		}
+	}
+	return "ip:" + ctx.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,298 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/mail"
+	"simple_bank/ratelimit"
+	"simple_bank/stream"
+	"simple_bank/util"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newRateLimitedTestServer(t *testing.T, store *mockdb.MockStore, global, transfers, login string) *Server {
+	config := util.Config{
+		TokenSymmetricKey:   util.RandomString(32),
+		AccessTokenDuration: time.Minute,
+		RateLimitBackend:    ratelimit.BackendMemory,
+		RateLimitDefault:    global,
+		RateLimitTransfers:  transfers,
+		RateLimitLogin:      login,
+	}
+	allowAccessTokens(store)
+
+	server, err := NewServer(config, store, stream.NewBroker(16), mail.LogMailer{})
+	require.NoError(t, err)
+
+	return server
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	testCases := []struct {
+		name     string
+		limits   [3]string
+		requests func(t *testing.T, server *Server) []*http.Request
+		// wantCodes are the status codes of the requests, in order.
+		wantCodes []int
+	}{
+		{
+			name:   "GlobalLimitByIP",
+			limits: [3]string{"2/1m", "", ""},
+			requests: func(t *testing.T, server *Server) []*http.Request {
+				return []*http.Request{
+					httptest.NewRequest(http.MethodGet, "/accounts/0", nil),
+					httptest.NewRequest(http.MethodGet, "/accounts/0", nil),
+					httptest.NewRequest(http.MethodGet, "/accounts/0", nil),
+				}
+			},
+			wantCodes: []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests},
+		},
+		{
+			name:   "ProbesNotLimited",
+			limits: [3]string{"1/1m", "", ""},
+			requests: func(t *testing.T, server *Server) []*http.Request {
+				return []*http.Request{
+					httptest.NewRequest(http.MethodGet, "/openapi.json", nil),
+					httptest.NewRequest(http.MethodGet, "/openapi.json", nil),
+					httptest.NewRequest(http.MethodGet, "/openapi.json", nil),
+				}
+			},
+			wantCodes: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+		{
+			name:   "StricterTransferLimit",
+			limits: [3]string{"10/1m", "1/1m", ""},
+			requests: func(t *testing.T, server *Server) []*http.Request {
+				requests := []*http.Request{
+					httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader([]byte("{}"))),
+					httptest.NewRequest(http.MethodPost, "/transfers", bytes.NewReader([]byte("{}"))),
+					httptest.NewRequest(http.MethodGet, "/accounts/0", nil),
+				}
+				for _, request := range requests {
+					addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "alice", time.Minute)
+				}
+				return requests
+			},
+			wantCodes: []int{http.StatusBadRequest, http.StatusTooManyRequests, http.StatusBadRequest},
+		},
+		{
+			name:   "StricterLoginLimit",
+			limits: [3]string{"10/1m", "", "1/1m"},
+			requests: func(t *testing.T, server *Server) []*http.Request {
+				return []*http.Request{
+					httptest.NewRequest(http.MethodPost, "/users/login", bytes.NewReader([]byte("{}"))),
+					httptest.NewRequest(http.MethodPost, "/users/login", bytes.NewReader([]byte("{}"))),
+				}
+			},
+			wantCodes: []int{http.StatusBadRequest, http.StatusTooManyRequests},
+		},
+		{
+			name:   "KeyedByUser",
+			limits: [3]string{"1/1m", "", ""},
+			requests: func(t *testing.T, server *Server) []*http.Request {
+				var requests []*http.Request
+				for _, username := range []string{"alice", "bob", "alice"} {
+					request := httptest.NewRequest(http.MethodGet, "/accounts/0", nil)
+					addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+					requests = append(requests, request)
+				}
+				return requests
+			},
+			wantCodes: []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusTooManyRequests},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := newRateLimitedTestServer(t, mockdb.NewMockStore(ctrl), tc.limits[0], tc.limits[1], tc.limits[2])
+
+			requests := tc.requests(t, server)
+			require.Len(t, requests, len(tc.wantCodes))
+			for j, request := range requests {
+				recorder := httptest.NewRecorder()
+				server.router.ServeHTTP(recorder, request)
+				require.Equal(t, tc.wantCodes[j], recorder.Code, "request %d", j)
+			}
+		})
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newRateLimitedTestServer(t, mockdb.NewMockStore(ctrl), "2/1m", "", "")
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/accounts/0", nil))
+	require.Equal(t, "2", recorder.Header().Get(rateLimitLimitHeader))
+	require.Equal(t, "1", recorder.Header().Get(rateLimitRemainingHeader))
+	require.Equal(t, "30", recorder.Header().Get(rateLimitResetHeader))
+	require.Empty(t, recorder.Header().Get(retryAfterHeader))
+
+	recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/accounts/0", nil))
+	require.Equal(t, "0", recorder.Header().Get(rateLimitRemainingHeader))
+
+	recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/accounts/0", nil))
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get(rateLimitRemainingHeader))
+	require.Equal(t, "30", recorder.Header().Get(retryAfterHeader))
+	require.Contains(t, recorder.Body.String(), errRateLimited.Error())
+}
+
+func TestRateLimitKeyTrustedProxies(t *testing.T) {
+	forwardedFrom := func(ip string) *http.Request {
+		request := httptest.NewRequest(http.MethodGet, "/accounts/0", nil)
+		request.RemoteAddr = "10.0.0.9:4321"
+		request.Header.Set("X-Forwarded-For", ip)
+		return request
+	}
+
+	testCases := []struct {
+		name           string
+		trustedProxies []string
+		wantCodes      []int
+	}{
+		{
+			// Without trusted proxies a client cannot pick a new bucket by
+			// rotating the header.
+			name:      "SpoofedHeader",
+			wantCodes: []int{http.StatusUnauthorized, http.StatusTooManyRequests},
+		},
+		{
+			name:           "TrustedProxy",
+			trustedProxies: []string{"10.0.0.0/8"},
+			wantCodes:      []int{http.StatusUnauthorized, http.StatusUnauthorized},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server, err := NewServer(util.Config{
+				TokenSymmetricKey: util.RandomString(32),
+				RateLimitBackend:  ratelimit.BackendMemory,
+				RateLimitDefault:  "1/1m",
+				TrustedProxies:    tc.trustedProxies,
+			}, mockdb.NewMockStore(ctrl), stream.NewBroker(16), mail.LogMailer{})
+			require.NoError(t, err)
+
+			for i, ip := range []string{"6.6.6.6", "7.7.7.7"} {
+				recorder := httptest.NewRecorder()
+				server.router.ServeHTTP(recorder, forwardedFrom(ip))
+				require.Equal(t, tc.wantCodes[i], recorder.Code, "request %d", i)
+			}
+		})
+	}
+}
+
+func TestRateLimitKeyCredentials(t *testing.T) {
+	aliceKey, aliceKeyString := randomAPIKey(t, "alice", util.ScopeAccountsRead)
+	aliceKey2, aliceKeyString2 := randomAPIKey(t, "alice", util.ScopeAccountsRead)
+	bobKey, bobKeyString := randomAPIKey(t, "bob", util.ScopeAccountsRead)
+	oauthToken := "sbo_" + util.RandomString(32)
+
+	testCases := []struct {
+		name           string
+		buildStubs     func(store *mockdb.MockStore)
+		authorizations []string
+		wantCodes      []int
+	}{
+		{
+			// Each request authenticates its key once, although both the
+			// rate limiter and scopedAuthMiddleware need it.
+			name: "APIKeyByOwner",
+			buildStubs: func(store *mockdb.MockStore) {
+				for _, key := range []db.ApiKey{aliceKey, bobKey, aliceKey2} {
+					store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(key, nil)
+				}
+				store.EXPECT().TouchApiKey(gomock.Any(), gomock.Any()).Times(3)
+			},
+			authorizations: []string{"ApiKey " + aliceKeyString, "ApiKey " + bobKeyString, "ApiKey " + aliceKeyString2},
+			wantCodes:      []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusTooManyRequests},
+		},
+		{
+			name: "OAuthTokenByOwner",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOauthToken(gomock.Any(), gomock.Eq(util.HashSecret(oauthToken))).
+					Times(2).
+					Return(db.OauthToken{
+						Username:  "alice",
+						Scopes:    []string{util.ScopeAccountsRead},
+						ExpiresAt: time.Now().Add(time.Minute),
+					}, nil)
+			},
+			authorizations: []string{"Bearer " + oauthToken, "Bearer " + oauthToken},
+			wantCodes:      []int{http.StatusBadRequest, http.StatusTooManyRequests},
+		},
+		{
+			// Made-up keys count against the client IP, so rotating them
+			// does not reset the limit.
+			name: "InvalidKeyByIP",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(0)
+			},
+			authorizations: []string{"ApiKey sbk_" + util.RandomString(8), "ApiKey sbk_" + util.RandomString(8)},
+			wantCodes:      []int{http.StatusUnauthorized, http.StatusTooManyRequests},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newRateLimitedTestServer(t, store, "1/1m", "", "")
+
+			for i, authorization := range tc.authorizations {
+				request := httptest.NewRequest(http.MethodGet, "/accounts/0", nil)
+				request.Header.Set(authorizationHeaderKey, authorization)
+
+				recorder := httptest.NewRecorder()
+				server.router.ServeHTTP(recorder, request)
+				require.Equal(t, tc.wantCodes[i], recorder.Code, "request %d", i)
+			}
+		})
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	_, err := NewServer(util.Config{
+		TokenSymmetricKey: util.RandomString(32),
+		RateLimitBackend:  "redis",
+	}, nil, stream.NewBroker(16), mail.LogMailer{})
+	require.Error(t, err)
+
+	_, err = NewServer(util.Config{
+		TokenSymmetricKey: util.RandomString(32),
+		RateLimitBackend:  ratelimit.BackendMemory,
+		RateLimitDefault:  "lots",
+	}, nil, stream.NewBroker(16), mail.LogMailer{})
+	require.Error(t, err)
+
+	_, err = NewServer(util.Config{
+		TokenSymmetricKey: util.RandomString(32),
+		TrustedProxies:    []string{"proxy.internal"},
+	}, nil, stream.NewBroker(16), mail.LogMailer{})
+	require.Error(t, err)
+}
@@ -1,41 +1,283 @@
 package api
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"simple_bank/apikey"
 	db "simple_bank/db/sqlc"
+	"simple_bank/lockout"
+	"simple_bank/logging"
+	"simple_bank/mail"
+	"simple_bank/oauth"
+	"simple_bank/ratelimit"
+	"simple_bank/stream"
+	"simple_bank/tlsconfig"
+	"simple_bank/token"
+	"simple_bank/tracing"
+	"simple_bank/twofactor"
+	"simple_bank/util"
+	"simple_bank/val"
+	"simple_bank/webhook"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 // Server serves HTTP requests for our banking service.
 type Server struct {
-	store  db.Store
-	router *gin.Engine
+	config     util.Config
+	store      db.Store
+	tokenMaker token.Maker
+	broker     *stream.Broker
+	mailer     mail.Mailer
+	twoFactor  *twofactor.Service
+	loginGuard *lockout.Guard
+	apiKeys    *apikey.Service
+	oauth      *oauth.Server
+	router     *gin.Engine
+	limiter    ratelimit.Limiter
+	rateLimits rateLimits
+	// resolver looks up webhook hosts, to refuse ones on private networks.
+	resolver webhook.Resolver
+
+	// tlsConfig is nil when the server listens in plaintext.
+	tlsConfig    *tls.Config
+	certReloader *tlsconfig.CertReloader
+
+	// state is one of stateStarting, stateServing or stateShuttingDown.
+	state atomic.Int32
+
+	// shutdown is closed when the server starts shutting down, to end
+	// long-lived streams that would otherwise hold up the drain.
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
 }
 
 // NewServer creates a new HTTP server and setup routing.
-func NewServer(store db.Store) *Server {
-	server := &Server{store: store}
-	server.router = gin.Default()
+// Account streams are fed by broker, and email to users goes through mailer.
+func NewServer(config util.Config, store db.Store, broker *stream.Broker, mailer mail.Mailer) (*Server, error) {
+	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token maker: %w", err)
+	}
+
+	limiter, limits, err := newRateLimiter(config, store)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate limiter: %w", err)
+	}
+
+	twoFactor, err := twofactor.NewService(store, config.TOTPEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create two-factor service: %w", err)
+	}
+
+	server := &Server{
+		config:     config,
+		store:      store,
+		tokenMaker: tokenMaker,
+		broker:     broker,
+		mailer:     mailer,
+		twoFactor:  twoFactor,
+		loginGuard: lockout.NewGuard(store, lockout.NewPolicy(config)),
+		apiKeys:    apikey.NewService(store),
+		oauth: oauth.NewServer(store, oauth.Options{
+			CodeDuration:  config.OAuthCodeDuration,
+			TokenDuration: config.OAuthTokenDuration,
+		}),
+		limiter:    limiter,
+		rateLimits: limits,
+		resolver:   net.DefaultResolver,
+		shutdown:   make(chan struct{}),
+	}
+
+	if config.TLSCertFile != "" {
+		server.tlsConfig, server.certReloader, err = tlsconfig.New(tlsconfig.Options{
+			CertFile:     config.TLSCertFile,
+			KeyFile:      config.TLSKeyFile,
+			ClientCAFile: config.TLSClientCAFile,
+			MinVersion:   config.TLSMinVersion,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot load TLS config: %w", err)
+		}
+	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-		v.RegisterValidation("currency", validCurrency)
+		val.RegisterValidations(v)
+	}
+
+	if err := server.setupRouter(); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+func (server *Server) setupRouter() error {
+	router := gin.New()
+	// Only trusted proxies may set the client IP through X-Forwarded-For;
+	// with none, ClientIP is the address of the connection.
+	if err := router.SetTrustedProxies(server.config.TrustedProxies); err != nil {
+		return fmt.Errorf("cannot set trusted proxies: %w", err)
 	}
+	router.Use(
+		requestIDMiddleware(),
+		loggerMiddleware(),
+		recoveryMiddleware(),
+		otelgin.Middleware(tracing.ServiceName),
+		metricsMiddleware(),
+	)
+
+	router.GET("/healthz", server.healthzHandler)
+	router.GET("/readyz", server.readyzHandler)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/openapi.json", server.openAPIHandler)
+	router.GET("/docs/*filepath", server.docsHandler)
+
+	// Gin only applies middleware to routes registered after Use, so the
+	// probes, metrics and docs above are never rate limited.
+	router.Use(server.rateLimitMiddleware("global", server.rateLimits.global))
+
+	router.POST("/users", server.createUserHandler)
+	router.POST("/users/login", server.rateLimitMiddleware("login", server.rateLimits.login), server.loginUserHandler)
+	router.POST("/users/password_reset", server.rateLimitMiddleware("password_reset", server.rateLimits.login), server.requestPasswordResetHandler)
+	router.GET("/verify_email", server.rateLimitMiddleware("verify_email", server.rateLimits.login), server.verifyEmailHandler)
+	router.POST("/users/password_reset/confirm", server.rateLimitMiddleware("password_reset", server.rateLimits.login), server.resetPasswordHandler)
 
-	server.router.POST("/accounts", server.createAccountHandler)
-	server.router.GET("/accounts/:id", server.getAccountHandler)
-	server.router.GET("/accounts", server.listAccountsHandler)
-	server.router.POST("/transfers", server.createTransferHandler)
+	authRoutes := router.Group("/").Use(server.authMiddleware())
+	authRoutes.PUT("/users/me/password", server.changePasswordHandler)
+	authRoutes.POST("/users/me/totp", server.enrollTOTPHandler)
+	authRoutes.POST("/users/me/totp/confirm", server.rateLimitMiddleware("totp", server.rateLimits.login), server.enableTOTPHandler)
+	authRoutes.POST("/users/me/api_keys", server.createAPIKeyHandler)
+	authRoutes.GET("/users/me/api_keys", server.listAPIKeysHandler)
+	authRoutes.DELETE("/users/me/api_keys/:id", server.revokeAPIKeyHandler)
+	authRoutes.POST("/oauth/clients", server.registerOAuthClientHandler)
+	authRoutes.GET("/oauth/authorize", server.getOAuthAuthorizationHandler)
+	authRoutes.POST("/oauth/authorize", server.oauthAuthorizeHandler)
+	authRoutes.POST("/accounts", server.createAccountHandler)
+	authRoutes.GET("/accounts/:id/holders", server.listAccountHoldersHandler)
+	authRoutes.POST("/accounts/:id/holders", server.addAccountHolderHandler)
+	authRoutes.DELETE("/accounts/:id/holders/:username", server.removeAccountHolderHandler)
+	authRoutes.POST("/webhooks", server.createWebhookHandler)
+	authRoutes.GET("/webhooks", server.listWebhooksHandler)
+	authRoutes.DELETE("/webhooks/:id", server.deleteWebhookHandler)
+	authRoutes.GET("/webhooks/:id/deliveries", server.listWebhookDeliveriesHandler)
+	authRoutes.POST("/webhooks/:id/deliveries/:delivery_id/replay", server.replayWebhookDeliveryHandler)
 
-	return server
+	// These routes also take API keys and OAuth access tokens granted the
+	// scope they need.
+	router.GET("/accounts/:id", server.scopedAuthMiddleware(util.ScopeAccountsRead), server.getAccountHandler)
+	router.GET("/accounts", server.scopedAuthMiddleware(util.ScopeAccountsRead), server.listAccountsHandler)
+	router.POST("/transfers", server.scopedAuthMiddleware(util.ScopeTransfersCreate),
+		server.rateLimitMiddleware("transfers", server.rateLimits.transfers), server.createTransferHandler)
+
+	// The OAuth token and introspection endpoints authenticate clients, not
+	// users.
+	router.POST("/oauth/token", server.rateLimitMiddleware("oauth_token", server.rateLimits.login), server.oauthTokenHandler)
+	router.POST("/oauth/introspect", server.oauthIntrospectHandler)
+
+	streamRoutes := router.Group("/").Use(queryTokenMiddleware(), server.authMiddleware())
+	streamRoutes.GET("/accounts/:id/stream", server.streamAccountHandler)
+	streamRoutes.GET("/accounts/:id/ws", server.accountWebSocketHandler)
+
+	// Admin routes are open to support staff and admins; those that change
+	// money or configuration are for admins only.
+	staff := requireRole(util.SupportRole, util.AdminRole)
+	admin := requireRole(util.AdminRole)
+	adminRoutes := router.Group("/admin").Use(server.clientCertMiddleware(), server.authMiddleware())
+	adminRoutes.GET("/accounts/:id", staff, server.getAnyAccountHandler)
+	adminRoutes.POST("/accounts/:id/freeze", staff, server.freezeAccountHandler)
+	adminRoutes.POST("/accounts/:id/unfreeze", admin, server.unfreezeAccountHandler)
+	adminRoutes.POST("/transfers/:id/reverse", admin, server.reverseTransferHandler)
+	adminRoutes.GET("/currencies", staff, server.listCurrenciesHandler)
+	adminRoutes.POST("/currencies", admin, server.createCurrencyHandler)
+	adminRoutes.PATCH("/currencies/:code", admin, server.updateCurrencyHandler)
+	adminRoutes.GET("/ledger", admin, server.getLedgerReportHandler)
+	adminRoutes.GET("/audit_log", admin, server.listAuditLogHandler)
+	adminRoutes.POST("/users/:username/unlock", admin, server.unlockUserHandler)
+
+	server.router = router
+	return nil
+}
+
+// Start runs the HTTP server on a specific address until ctx is cancelled,
+// over TLS if a certificate is configured. It then stops accepting connections and waits up to ShutdownTimeout for
+// in-flight requests, such as transfers, to finish.
+func (server *Server) Start(ctx context.Context, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("cannot listen on %s: %w", address, err)
+	}
+	return server.serve(ctx, listener)
+}
+
+func (server *Server) serve(ctx context.Context, listener net.Listener) error {
+	httpServer := &http.Server{
+		Handler:           server.router,
+		ReadTimeout:       server.config.HTTPReadTimeout,
+		ReadHeaderTimeout: server.config.HTTPReadHeaderTimeout,
+		WriteTimeout:      server.config.HTTPWriteTimeout,
+		IdleTimeout:       server.config.HTTPIdleTimeout,
+		MaxHeaderBytes:    server.config.HTTPMaxHeaderBytes,
+	}
+	httpServer.RegisterOnShutdown(server.closeStreams)
+
+	serveErr := make(chan error, 1)
+	if server.tlsConfig != nil {
+		httpServer.TLSConfig = server.tlsConfig
+		go server.certReloader.Run(ctx, server.config.TLSReloadInterval)
+		go func() {
+			serveErr <- httpServer.ServeTLS(listener, "", "")
+		}()
+	} else {
+		go func() {
+			serveErr <- httpServer.Serve(listener)
+		}()
+	}
+	server.state.Store(stateServing)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	server.state.Store(stateShuttingDown)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), server.config.ShutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("cannot shut down HTTP server: %w", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
-// Start runs the HTTP server on a specific address.
-func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+func (server *Server) closeStreams() {
+	server.shutdownOnce.Do(func() {
+		close(server.shutdown)
+	})
 }
 
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+// errorResponse builds the JSON error body. It also attaches err to ctx so
+// the request log line records it, and includes the request ID so clients
+// can quote it when reporting a problem.
+func errorResponse(ctx *gin.Context, err error) gin.H {
+	_ = ctx.Error(err)
+	return gin.H{
+		"error":      err.Error(),
+		"request_id": logging.RequestID(ctx.Request.Context()),
+	}
 }
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// startBlockedTransfer serves server on a random port and posts a transfer
+// whose TransferTx blocks until release is closed. It returns once the
+// transfer is in flight.
+func startBlockedTransfer(t *testing.T, store *mockdb.MockStore, server *Server, release chan struct{}) (
+	cancel context.CancelFunc, serveErr chan error, response chan *http.Response,
+) {
+	account := createRandomAccount()
+	account.Currency = util.USD
+
+	started := make(chan struct{})
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Any()).
+		Times(2).
+		Return(account, nil)
+	store.EXPECT().
+		TransferTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, _ db.TransferTxParams) (db.TransferTxResult, error) {
+			close(started)
+			<-release
+			return db.TransferTxResult{}, nil
+		})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr = make(chan error, 1)
+	go func() {
+		serveErr <- server.serve(ctx, listener)
+	}()
+
+	body, err := json.Marshal(gin.H{
+		"from_account_id": account.ID,
+		"to_account_id":   account.ID + 1,
+		"amount":          10,
+		"currency":        util.USD,
+	})
+	require.NoError(t, err)
+
+	url := "http://" + listener.Addr().String() + "/transfers"
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account.Owner, time.Minute)
+
+	response = make(chan *http.Response, 1)
+	go func() {
+		rsp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			close(response)
+			return
+		}
+		response <- rsp
+	}()
+
+	<-started
+	return cancel, serveErr, response
+}
+
+func TestServerDrainsInFlightRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	server.config.ShutdownTimeout = 5 * time.Second
+
+	release := make(chan struct{})
+	cancel, serveErr, response := startBlockedTransfer(t, store, server, release)
+	cancel()
+
+	select {
+	case err := <-serveErr:
+		t.Fatalf("server stopped before the transfer finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+
+	rsp, ok := <-response
+	require.True(t, ok)
+	defer rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	require.NoError(t, <-serveErr)
+}
+
+func TestServerShutdownDeadline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	server.config.ShutdownTimeout = 50 * time.Millisecond
+
+	release := make(chan struct{})
+	defer close(release)
+
+	cancel, serveErr, _ := startBlockedTransfer(t, store, server, release)
+	cancel()
+
+	err := <-serveErr
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestServerClosesStreamsOnShutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	server.closeStreams()
+	server.closeStreams()
+
+	select {
+	case <-server.shutdown:
+	default:
+		t.Fatal("shutdown channel not closed")
+	}
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+	"simple_bank/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	accessTokenQueryKey = "access_token"
+	streamHeartbeat     = 15 * time.Second
+	streamWriteTimeout  = 10 * time.Second
+)
+
+// Stream event types, used as the SSE event name and the WebSocket message type.
+const (
+	streamEventAccount = "account"
+	streamEventUpdate  = "update"
+	streamEventError   = "error"
+)
+
+var errSlowConsumer = errors.New("client is too slow to keep up with updates")
+
+var upgrader = websocket.Upgrader{}
+
+type streamAccountRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type streamMessage struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// queryTokenMiddleware lets clients that cannot set headers, such as browser
+// EventSource and WebSocket, pass the access token as a query parameter.
+func queryTokenMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accessToken := ctx.Query(accessTokenQueryKey)
+		if ctx.GetHeader(authorizationHeaderKey) == "" && accessToken != "" {
+			ctx.Request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+		}
+		ctx.Next()
+	}
+}
+
+// streamAccountHandler pushes the account and then every balance update as Server-Sent Events.
+func (server *Server) streamAccountHandler(ctx *gin.Context) {
+	var req streamAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	// Subscribe before loading the account so no update is missed in between.
+	sub := server.broker.Subscribe(req.ID)
+	defer sub.Close()
+
+	account, _, ok := server.authorizedAccount(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	// The server write timeout covers the whole response, which would cut
+	// streams short; give each write its own deadline instead.
+	rc := http.NewResponseController(ctx.Writer)
+	rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.SSEvent(streamEventAccount, account)
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-server.shutdown:
+			return
+		case update, ok := <-sub.Updates():
+			rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if !ok {
+				ctx.SSEvent(streamEventError, errorResponse(ctx, errSlowConsumer))
+				ctx.Writer.Flush()
+				return
+			}
+			ctx.SSEvent(streamEventUpdate, update)
+			ctx.Writer.Flush()
+		case <-heartbeat.C:
+			rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			ctx.Writer.WriteString(": keep-alive\n\n")
+			ctx.Writer.Flush()
+		}
+	}
+}
+
+// accountWebSocketHandler is the WebSocket equivalent of streamAccountHandler.
+func (server *Server) accountWebSocketHandler(ctx *gin.Context) {
+	var req streamAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	sub := server.broker.Subscribe(req.ID)
+	defer sub.Close()
+
+	account, _, ok := server.authorizedAccount(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		// Upgrade already replied with an HTTP error.
+		return
+	}
+	defer conn.Close()
+
+	// Clients only ever send control frames; reading is needed to process them
+	// and to notice when the client goes away.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := writeStreamMessage(conn, streamEventAccount, account); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-server.shutdown:
+			msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down")
+			conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(streamWriteTimeout))
+			return
+		case update, ok := <-sub.Updates():
+			if !ok {
+				msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, errSlowConsumer.Error())
+				conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(streamWriteTimeout))
+				return
+			}
+			if err := writeStreamMessage(conn, streamEventUpdate, update); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeStreamMessage(conn *websocket.Conn, eventType string, data any) error {
+	conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return conn.WriteJSON(streamMessage{Type: eventType, Data: data})
+}
+
+// authorizedAccount loads an account and the role the authenticated user
+// holds it in, writing the error response and returning false if they do not
+// hold it.
+func (server *Server) authorizedAccount(ctx *gin.Context, id int64) (db.Account, string, bool) {
+	account, err := server.store.GetAccount(ctx.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ctx, err))
+			return account, "", false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return account, "", false
+	}
+
+	// The owner is always the primary holder, so only other users need a
+	// lookup.
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner == authPayload.Username {
+		return account, util.PrimaryHolder, true
+	}
+
+	holder, err := server.store.GetAccountHolder(ctx.Request.Context(), db.GetAccountHolderParams{
+		AccountID: id,
+		Username:  authPayload.Username,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			err := errors.New("account doesn't belong to the authenticated user")
+			ctx.JSON(http.StatusForbidden, errorResponse(ctx, err))
+			return account, "", false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return account, "", false
+	}
+
+	return account, holder.Role, true
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamAccountAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := createRandomAccount()
+	account.Owner = user.Username
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+
+	server := newTestServer(t, store)
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, user.Role, time.Minute)
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("%s/accounts/%d/stream?access_token=%s", httpServer.URL, account.ID, accessToken)
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	event, data := readServerSentEvent(t, reader)
+	require.Equal(t, streamEventAccount, event)
+	require.Contains(t, data, fmt.Sprintf(`"balance":%d`, account.Balance))
+
+	server.broker.Publish(db.AccountUpdate{AccountID: account.ID, Balance: account.Balance + 10})
+
+	event, data = readServerSentEvent(t, reader)
+	require.Equal(t, streamEventUpdate, event)
+	require.Contains(t, data, fmt.Sprintf(`"balance":%d`, account.Balance+10))
+}
+
+func TestStreamAccountAPIUnauthorizedUser(t *testing.T) {
+	account := createRandomAccount()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+	store.EXPECT().
+		GetAccountHolder(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.AccountHolder{}, db.ErrRecordNotFound)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/accounts/%d/stream", account.ID)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "unauthorized", time.Minute)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+}
+
+func TestAccountWebSocketAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := createRandomAccount()
+	account.Owner = user.Username
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+
+	server := newTestServer(t, store)
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	header := http.Header{}
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, user.Role, time.Minute)
+	require.NoError(t, err)
+	header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+	url := fmt.Sprintf("ws%s/accounts/%d/ws", strings.TrimPrefix(httpServer.URL, "http"), account.ID)
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var msg struct {
+		Type string         `json:"type"`
+		Data map[string]any `json:"data"`
+	}
+	require.NoError(t, conn.ReadJSON(&msg))
+	require.Equal(t, streamEventAccount, msg.Type)
+	require.EqualValues(t, account.Balance, msg.Data["balance"])
+
+	server.broker.Publish(db.AccountUpdate{AccountID: account.ID, Balance: account.Balance + 10})
+
+	require.NoError(t, conn.ReadJSON(&msg))
+	require.Equal(t, streamEventUpdate, msg.Type)
+	require.EqualValues(t, account.Balance+10, msg.Data["balance"])
+}
+
+// readServerSentEvent reads the next event, skipping comments, and returns its name and data.
+func readServerSentEvent(t *testing.T, reader *bufio.Reader) (event string, data string) {
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			data = strings.TrimPrefix(line, "data:")
+		case line == "" && event != "":
+			return event, data
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/mail"
+	"simple_bank/stream"
+	"simple_bank/tlsconfig/tlstest"
+	"simple_bank/util"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// startTLSServer serves a server configured with a certificate from ca, and
+// ca as client CA, on a random port. It returns the server and its base URL.
+func startTLSServer(t *testing.T, store db.Store, ca *tlstest.CA, minVersion string) (*Server, string) {
+	certFile, keyFile, _ := ca.Issue(t, "server", "localhost")
+
+	config := util.Config{
+		TokenSymmetricKey:   util.RandomString(32),
+		AccessTokenDuration: time.Minute,
+		ShutdownTimeout:     time.Second,
+		TLSCertFile:         certFile,
+		TLSKeyFile:          keyFile,
+		TLSClientCAFile:     ca.CertFile,
+		TLSMinVersion:       minVersion,
+		TLSReloadInterval:   time.Minute,
+	}
+	allowAccessTokens(store)
+	server, err := NewServer(config, store, stream.NewBroker(16), mail.LogMailer{})
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.serve(ctx, listener)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-serveErr)
+	})
+
+	return server, "https://" + listener.Addr().String()
+}
+
+func newTLSClient(ca *tlstest.CA, clientCerts ...tls.Certificate) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:      ca.Pool(),
+				Certificates: clientCerts,
+			},
+		},
+	}
+}
+
+func TestServeTLS(t *testing.T) {
+	ca := tlstest.NewCA(t)
+	_, _, clientCert := ca.Issue(t, "client", "operator")
+	_, _, strangerCert := tlstest.NewCA(t).Issue(t, "stranger", "operator")
+
+	testCases := []struct {
+		name        string
+		path        string
+		clientCerts []tls.Certificate
+		// role is the role of the access token sent, if not empty.
+		role       string
+		buildStubs func(store *mockdb.MockStore)
+		// wantStatus is 0 when the handshake should fail.
+		wantStatus int
+	}{
+		{
+			name:       "PublicRouteWithoutClientCert",
+			path:       "/healthz",
+			buildStubs: func(store *mockdb.MockStore) {},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "AdminRouteWithoutClientCert",
+			path: "/admin/ledger",
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLedgerTotals(gomock.Any()).Times(0)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:        "AdminRouteWithClientCert",
+			path:        "/admin/ledger",
+			clientCerts: []tls.Certificate{clientCert},
+			role:        util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLedgerTotals(gomock.Any()).Times(1).Return(db.GetLedgerTotalsRow{}, nil)
+				store.EXPECT().ListLedgerMismatches(gomock.Any()).Times(1).Return([]db.ListLedgerMismatchesRow{}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "UntrustedClientCert",
+			path:        "/healthz",
+			clientCerts: []tls.Certificate{strangerCert},
+			buildStubs:  func(store *mockdb.MockStore) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, baseURL := startTLSServer(t, store, ca, "1.2")
+			client := newTLSClient(ca, tc.clientCerts...)
+
+			request, err := http.NewRequest(http.MethodGet, baseURL+tc.path, nil)
+			require.NoError(t, err)
+			if tc.role != "" {
+				addAuthorizationWithRole(t, request, server.tokenMaker, authorizationTypeBearer, "operator", tc.role, time.Minute)
+			}
+
+			rsp, err := client.Do(request)
+			if tc.wantStatus == 0 {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer rsp.Body.Close()
+			require.Equal(t, tc.wantStatus, rsp.StatusCode)
+			require.NotNil(t, rsp.TLS)
+		})
+	}
+}
+
+func TestServeTLSMinVersion(t *testing.T) {
+	ca := tlstest.NewCA(t)
+	_, baseURL := startTLSServer(t, nil, ca, "1.3")
+
+	client := newTLSClient(ca)
+	client.Transport.(*http.Transport).TLSClientConfig.MaxVersion = tls.VersionTLS12
+	_, err := client.Get(baseURL + "/healthz")
+	require.Error(t, err)
+
+	rsp, err := newTLSClient(ca).Get(baseURL + "/healthz")
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	require.Equal(t, uint16(tls.VersionTLS13), rsp.TLS.Version)
+}
+
+func TestClientCertMiddlewarePlaintext(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.config.TLSClientCAFile = "ca.pem"
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/admin/ledger", nil)
+	require.NoError(t, err)
+	addAuthorizationWithRole(t, request, server.tokenMaker, authorizationTypeBearer, "operator", util.AdminRole, time.Minute)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+	"simple_bank/twofactor"
+
+	"github.com/gin-gonic/gin"
+)
+
+type enrollTOTPResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// enrollTOTPHandler starts two-factor enrollment: it returns a new secret
+// and the otpauth:// URI to show as a QR code. Login does not ask for a
+// code until enableTOTPHandler confirms the secret.
+func (server *Server) enrollTOTPHandler(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	enrollment, err := server.twoFactor.Enroll(ctx.Request.Context(), authPayload.Username)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrTOTPAlreadyEnabled):
+			ctx.JSON(http.StatusForbidden, errorResponse(ctx, err))
+		case errors.Is(err, twofactor.ErrNotConfigured):
+			ctx.JSON(http.StatusNotImplemented, errorResponse(ctx, err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollTOTPResponse{
+		Secret:          enrollment.Secret,
+		ProvisioningURI: enrollment.ProvisioningURI,
+	})
+}
+
+type enableTOTPRequest struct {
+	Code string `json:"code" binding:"required,numeric,len=6"`
+}
+
+type enableTOTPResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// enableTOTPHandler confirms enrollment with a code from the user's
+// authenticator app and returns their one-time recovery codes. They are
+// only shown this once.
+func (server *Server) enableTOTPHandler(ctx *gin.Context) {
+	var req enableTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	recoveryCodes, err := server.twoFactor.Enable(ctx.Request.Context(), authPayload.Username, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, twofactor.ErrCodeInvalid):
+			ctx.JSON(http.StatusUnauthorized, errorResponse(ctx, err))
+		case errors.Is(err, twofactor.ErrNotEnrolled):
+			ctx.JSON(http.StatusNotFound, errorResponse(ctx, err))
+		case errors.Is(err, db.ErrTOTPAlreadyEnabled):
+			ctx.JSON(http.StatusForbidden, errorResponse(ctx, err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enableTOTPResponse{RecoveryCodes: recoveryCodes})
+}
+
+// checkStepUp requires a TOTP code from the user for transfers above
+// TRANSFER_STEP_UP_THRESHOLD. It writes the error response and returns
+// false if the transfer must not go ahead.
+func (server *Server) checkStepUp(ctx *gin.Context, amount int64, code string) bool {
+	threshold := int64(server.config.TransferStepUpThreshold)
+	if threshold == 0 || amount <= threshold {
+		return true
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	err := server.twoFactor.VerifyStepUp(ctx.Request.Context(), authPayload.Username, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, twofactor.ErrNotEnabled):
+			ctx.JSON(http.StatusForbidden, errorResponse(ctx, errors.New("two-factor authentication must be enabled for transfers of this amount")))
+		case errors.Is(err, twofactor.ErrCodeRequired), errors.Is(err, twofactor.ErrCodeInvalid):
+			ctx.JSON(http.StatusUnauthorized, errorResponse(ctx, err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/twofactor"
+	"simple_bank/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// totpSecret returns a confirmed TOTP row for username, sealed with
+// testTOTPKey, and the secret its codes are generated from.
+func totpSecret(t *testing.T, username string) (db.TotpSecret, string) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	service, err := twofactor.NewService(store, testTOTPKey)
+	require.NoError(t, err)
+
+	var totp db.TotpSecret
+	store.EXPECT().
+		CreateTotpSecret(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, arg db.CreateTotpSecretParams) (db.TotpSecret, error) {
+			totp = db.TotpSecret{Username: arg.Username, SecretEncrypted: arg.SecretEncrypted}
+			return totp, nil
+		})
+	enrollment, err := service.Enroll(context.Background(), username)
+	require.NoError(t, err)
+
+	totp.ConfirmedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return totp, enrollment.Secret
+}
+
+func currentTOTPCode(t *testing.T, secret string) string {
+	code, err := twofactor.Code(secret, twofactor.Step(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		wantStatus int
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateTotpSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateTotpSecretParams) (db.TotpSecret, error) {
+						require.Equal(t, username, arg.Username)
+						return db.TotpSecret{Username: arg.Username, SecretEncrypted: arg.SecretEncrypted}, nil
+					})
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "AlreadyEnabled",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateTotpSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TotpSecret{}, db.ErrRecordNotFound)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/totp", nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantStatus, recorder.Code)
+
+			if tc.wantStatus == http.StatusOK {
+				var rsp enrollTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.Secret)
+				require.Contains(t, rsp.ProvisioningURI, "otpauth://totp/")
+				require.Contains(t, rsp.ProvisioningURI, "secret="+rsp.Secret)
+			}
+		})
+	}
+}
+
+func TestEnrollTOTPAPINotConfigured(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.twoFactor, _ = twofactor.NewService(nil, "")
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodPost, "/users/me/totp", nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, util.RandomOwner(), time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNotImplemented, recorder.Code)
+}
+
+func TestEnableTOTPAPI(t *testing.T) {
+	username := util.RandomOwner()
+	enabled, secret := totpSecret(t, username)
+	pending := enabled
+	pending.ConfirmedAt = pgtype.Timestamptz{}
+
+	// Find a code the secret does not accept right now.
+	var wrongCode string
+	for i := 0; ; i++ {
+		wrongCode = fmt.Sprintf("%06d", i)
+		if _, ok := twofactor.Validate(secret, wrongCode, time.Now()); !ok {
+			break
+		}
+	}
+
+	testCases := []struct {
+		name       string
+		body       gin.H
+		buildStubs func(store *mockdb.MockStore)
+		wantStatus int
+	}{
+		{
+			name: "OK",
+			body: gin.H{"code": currentTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(username)).Times(1).Return(pending, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.EnableTOTPTxParams) (db.TotpSecret, error) {
+						require.Equal(t, username, arg.Username)
+						require.Len(t, arg.RecoveryCodeHashes, twofactor.RecoveryCodeCount)
+						return enabled, nil
+					})
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "WrongCode",
+			body: gin.H{"code": wrongCode},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(username)).Times(1).Return(pending, nil)
+				store.EXPECT().EnableTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "NotEnrolled",
+			body: gin.H{"code": "123456"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(username)).Times(1).Return(db.TotpSecret{}, db.ErrRecordNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "AlreadyEnabled",
+			body: gin.H{"code": "123456"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(username)).Times(1).Return(enabled, nil)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "InvalidCode",
+			body: gin.H{"code": "12ab56"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/me/totp/confirm", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantStatus, recorder.Code)
+
+			if tc.wantStatus == http.StatusOK {
+				var rsp enableTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp.RecoveryCodes, twofactor.RecoveryCodeCount)
+			}
+		})
+	}
+}
+
+func TestCreateTransferStepUpAPI(t *testing.T) {
+	owner := util.RandomOwner()
+	account1 := db.Account{ID: 1, Owner: owner, Currency: util.USD, Balance: 1000}
+	account2 := db.Account{ID: 2, Owner: util.RandomOwner(), Currency: util.USD}
+	totp, secret := totpSecret(t, owner)
+
+	testCases := []struct {
+		name       string
+		amount     int64
+		totpCode   string
+		buildStubs func(store *mockdb.MockStore)
+		wantStatus int
+	}{
+		{
+			name:   "BelowThreshold",
+			amount: 100,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:     "AboveThresholdWithCode",
+			amount:   101,
+			totpCode: currentTOTPCode(t, secret),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(owner)).Times(1).Return(totp, nil)
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(1).Return(totp, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "AboveThresholdWithoutCode",
+			amount: 101,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(owner)).Times(1).Return(totp, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:     "AboveThresholdNotEnrolled",
+			amount:   101,
+			totpCode: "123456",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(owner)).Times(1).Return(db.TotpSecret{}, db.ErrRecordNotFound)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).AnyTimes().Return(account1, nil)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).AnyTimes().Return(account2, nil)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+			server.config.TransferStepUpThreshold = 100
+
+			body := gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          tc.amount,
+				"currency":        util.USD,
+			}
+			if tc.totpCode != "" {
+				body["totp_code"] = tc.totpCode
+			}
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, owner, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.wantStatus, recorder.Code)
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package api
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
+
 	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+	"simple_bank/util"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,52 +17,71 @@ type createTransferRequest struct {
 	ToAccountID   int64  `json:"to_account_id" binding:"required,gt=0"`
 	Amount        int64  `json:"amount" binding:"required,min=1"`
 	Currency      string `json:"currency" binding:"required,currency"`
+	// TOTPCode is required above TRANSFER_STEP_UP_THRESHOLD.
+	TOTPCode string `json:"totp_code" binding:"omitempty,numeric,len=6"`
 }
 
 func (server *Server) createTransferHandler(ctx *gin.Context) {
 	var req createTransferRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
 		return
 	}
 
-	if !server.validateAccount(ctx, req.FromAccountID, req.Currency) {
+	fromAccount, role, ok := server.authorizedAccount(ctx, req.FromAccountID)
+	if !ok {
+		return
+	}
+	if !util.CanTransfer(role) {
+		err := fmt.Errorf("%s holders cannot transfer from account [%d]", role, fromAccount.ID)
+		ctx.JSON(http.StatusForbidden, errorResponse(ctx, err))
+		return
+	}
+	if !checkCurrency(ctx, fromAccount, req.Currency) {
 		return
 	}
 
-	if !server.validateAccount(ctx, req.ToAccountID, req.Currency) {
+	toAccount, err := server.store.GetAccount(ctx.Request.Context(), req.ToAccountID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ctx, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+	if !checkCurrency(ctx, toAccount, req.Currency) {
+		return
+	}
+	if !server.checkStepUp(ctx, req.Amount, req.TOTPCode) {
 		return
 	}
 
-	arg := db.CreateTransferParams{
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountID,
 		Amount:        req.Amount,
+		Sender:        authPayload.Username,
 	}
 
-	transfer, err := server.store.CreateTransfer(ctx.Request.Context(), arg)
+	result, err := server.store.TransferTx(ctx.Request.Context(), arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		if errors.Is(err, db.ErrAccountFrozen) || errors.Is(err, db.ErrEmailNotVerified) {
+			ctx.JSON(http.StatusForbidden, errorResponse(ctx, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, transfer)
+	ctx.JSON(http.StatusOK, result)
 }
 
-func (server *Server) validateAccount(ctx *gin.Context, accountID int64, currency string) bool {
-	account, err := server.store.GetAccount(ctx.Request.Context(), accountID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return false
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return false
-	}
-
+func checkCurrency(ctx *gin.Context, account db.Account, currency string) bool {
 	if account.Currency != currency {
-		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", accountID, account.Currency, currency)
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
 		return false
 	}
 	return true
@@ -0,0 +1,397 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/lockout"
+	"simple_bank/mail"
+	"simple_bank/token"
+	"simple_bank/twofactor"
+	"simple_bank/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createUserRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+	Password string `json:"password" binding:"required,min=6"`
+	FullName string `json:"full_name" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+}
+
+type userResponse struct {
+	Username          string    `json:"username"`
+	FullName          string    `json:"full_name"`
+	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func newUserResponse(user db.User) userResponse {
+	return userResponse{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
+		PasswordChangedAt: user.PasswordChangedAt,
+		CreatedAt:         user.CreatedAt,
+	}
+}
+
+func (server *Server) createUserHandler(ctx *gin.Context) {
+	var req createUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.Password)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	secretCode, err := util.NewSecret(secretCodeBytes)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username: req.Username,
+			Password: hashedPassword,
+			FullName: req.FullName,
+			Email:    req.Email,
+		},
+		SecretCodeHash: util.HashSecret(secretCode),
+		ExpiresAt:      time.Now().Add(server.config.EmailVerifyDuration),
+	}
+
+	result, err := server.store.CreateUserTx(ctx.Request.Context(), arg)
+	if err != nil {
+		if db.ErrorCode(err) == db.UniqueViolation {
+			ctx.JSON(http.StatusForbidden, errorResponse(ctx, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	// The link is only mailed once the user is committed. The user exists
+	// either way, so a failure to send is logged rather than returned.
+	user, verifyEmail := result.User, result.VerifyEmail
+	msg := mail.NewVerifyEmailMessage(server.config.PublicURL, user.Email, user.FullName,
+		verifyEmail.ID, secretCode, verifyEmail.ExpiresAt)
+	if err := server.mailer.Send(ctx.Request.Context(), msg); err != nil {
+		slog.ErrorContext(ctx.Request.Context(), "cannot send verification email", "username", user.Username, "err", err)
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type verifyEmailRequest struct {
+	ID   int64  `form:"id" binding:"required,min=1"`
+	Code string `form:"code" binding:"required"`
+}
+
+// verifyEmailHandler marks a user's email as verified with the link mailed
+// by createUserHandler.
+func (server *Server) verifyEmailHandler(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	// The store attributes the verification to the email's user.
+	setAuditActor(ctx, "")
+	user, err := server.store.VerifyEmailTx(ctx.Request.Context(), db.VerifyEmailTxParams{
+		EmailID:        req.ID,
+		SecretCodeHash: util.HashSecret(req.Code),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrVerifyEmailInvalid) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(ctx, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type loginUserRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+	Password string `json:"password" binding:"required,min=6"`
+	// Users with two-factor authentication send a code from their app, or
+	// one of their recovery codes.
+	TOTPCode     string `json:"totp_code" binding:"omitempty,numeric,len=6"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// loginSnapshot is what the audit log records about a login that passed a
+// second factor.
+type loginSnapshot struct {
+	SecondFactor string `json:"second_factor"`
+}
+
+type loginUserResponse struct {
+	AccessToken          string       `json:"access_token"`
+	AccessTokenExpiresAt time.Time    `json:"access_token_expires_at"`
+	User                 userResponse `json:"user"`
+}
+
+// errIncorrectLogin is the only error a login with an unknown username or a
+// wrong password gets.
+var errIncorrectLogin = errors.New("incorrect username or password")
+
+func (server *Server) loginUserHandler(ctx *gin.Context) {
+	var req loginUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	// Locked out logins are refused before the password is looked at, so
+	// that guesses made during a lockout reveal nothing.
+	retryAfter, err := server.loginGuard.Check(ctx.Request.Context(), req.Username, ctx.ClientIP())
+	if err != nil {
+		if errors.Is(err, lockout.ErrLockedOut) {
+			ctx.Header(retryAfterHeader, ceilSeconds(retryAfter))
+			ctx.JSON(http.StatusTooManyRequests, errorResponse(ctx, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	// An unknown username fails like a wrong password, in as much time,
+	// so logins cannot be used to find out which usernames exist.
+	user, err := server.store.GetUser(ctx.Request.Context(), req.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			util.CheckUnknownUserPassword(req.Password)
+			server.loginFailed(ctx, req.Username, http.StatusUnauthorized, errIncorrectLogin)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	err = util.CheckPassword(req.Password, user.Password)
+	if err != nil {
+		server.loginFailed(ctx, req.Username, http.StatusUnauthorized, errIncorrectLogin)
+		return
+	}
+
+	secondFactor, err := server.twoFactor.VerifyLogin(ctx.Request.Context(), user.Username, req.TOTPCode, req.RecoveryCode)
+	if err != nil {
+		switch {
+		case errors.Is(err, twofactor.ErrCodeRequired):
+			// The password was right; the client has to ask for a code.
+			ctx.JSON(http.StatusUnauthorized, errorResponse(ctx, err))
+		case errors.Is(err, twofactor.ErrCodeInvalid):
+			server.loginFailed(ctx, req.Username, http.StatusUnauthorized, err)
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		}
+		return
+	}
+
+	if err := server.loginGuard.Succeed(ctx.Request.Context(), user.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	setAuditActor(ctx, user.Username)
+	audit := db.AppendAuditLogParams{
+		Action: db.AuditUserLogin,
+		Target: db.UserTarget(user.Username),
+	}
+	if secondFactor != "" {
+		audit.After = loginSnapshot{SecondFactor: secondFactor}
+	}
+	_, err = server.store.AppendAuditLog(ctx.Request.Context(), audit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.AccessTokenDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	rsp := loginUserResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+		User:                 newUserResponse(user),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// loginFailed counts a failed login for username from the client's IP and
+// responds with status and err.
+func (server *Server) loginFailed(ctx *gin.Context, username string, status int, err error) {
+	// Lockouts are audited as the system, from the client's IP.
+	setAuditActor(ctx, "")
+	if failErr := server.loginGuard.Fail(ctx.Request.Context(), username, ctx.ClientIP()); failErr != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, failErr))
+		return
+	}
+	ctx.JSON(status, errorResponse(ctx, err))
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required,min=6"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// changePasswordHandler sets a new password for the authenticated user,
+// who must also send the current one. It returns no new access token: the
+// one the request was made with stops working, like every other.
+func (server *Server) changePasswordHandler(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx.Request.Context(), authPayload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ctx, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	err = util.CheckPassword(req.CurrentPassword, user.Password)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(ctx, errors.New("current password is incorrect")))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	_, err = server.store.ChangePasswordTx(ctx.Request.Context(), db.ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type requestPasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// Entropy of the secrets mailed to users.
+const (
+	secretCodeBytes = 32
+	resetTokenBytes = 32
+)
+
+// requestPasswordResetHandler mails a single-use password reset token to
+// the user with the given email. It answers 202 whether or not the email
+// belongs to a user, so it cannot be used to probe for accounts.
+func (server *Server) requestPasswordResetHandler(ctx *gin.Context) {
+	var req requestPasswordResetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	user, err := server.store.GetUserByEmail(ctx.Request.Context(), req.Email)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.Status(http.StatusAccepted)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	resetToken, err := util.NewSecret(resetTokenBytes)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	reset, err := server.store.CreatePasswordResetToken(ctx.Request.Context(), db.CreatePasswordResetTokenParams{
+		Username:  user.Username,
+		TokenHash: util.HashSecret(resetToken),
+		ExpiresAt: time.Now().Add(server.config.PasswordResetDuration),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	msg := mail.NewPasswordResetMessage(user.Email, user.FullName, resetToken, reset.ExpiresAt)
+	err = server.mailer.Send(ctx.Request.Context(), msg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// resetPasswordHandler sets a new password using a token from
+// requestPasswordResetHandler.
+func (server *Server) resetPasswordHandler(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	// The store attributes the reset to the token's user.
+	setAuditActor(ctx, "")
+	_, err = server.store.ResetPasswordTx(ctx.Request.Context(), db.ResetPasswordTxParams{
+		TokenHash:      util.HashSecret(req.Token),
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrResetTokenInvalid) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(ctx, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
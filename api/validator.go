@@ -1,14 +0,0 @@
-package api
-
-import (
-	"simple_bank/util"
-
-	"github.com/go-playground/validator/v10"
-)
-
-var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
-	if currency, ok := fieldLevel.Field().Interface().(string); ok {
-		return util.IsSupportedCurrency(currency)
-	}
-	return false
-}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/token"
+	"simple_bank/util"
+	"simple_bank/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createWebhookRequest struct {
+	Url        string   `json:"url" binding:"required,url"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,event_type"`
+}
+
+type webhookResponse struct {
+	ID         int64     `json:"id"`
+	Owner      string    `json:"owner"`
+	Url        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// createWebhookResponse is the only response carrying the signing secret.
+type createWebhookResponse struct {
+	webhookResponse
+	Secret string `json:"secret"`
+}
+
+func newWebhookResponse(webhook db.Webhook) webhookResponse {
+	return webhookResponse{
+		ID:         webhook.ID,
+		Owner:      webhook.Owner,
+		Url:        webhook.Url,
+		EventTypes: webhook.EventTypes,
+		CreatedAt:  webhook.CreatedAt,
+	}
+}
+
+func (server *Server) createWebhookHandler(ctx *gin.Context) {
+	var req createWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	// Subscribers must not get the server to call into its own network.
+	// The delivery client checks the address again when it connects, in
+	// case the host resolves elsewhere by then.
+	requireHTTPS := server.config.Environment != util.ProfileDev
+	if err := webhook.CheckURL(ctx.Request.Context(), server.resolver, req.Url, requireHTTPS); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.CreateWebhookParams{
+		Owner:      authPayload.Username,
+		Url:        req.Url,
+		EventTypes: req.EventTypes,
+		Secret:     secret,
+	}
+
+	created, err := server.store.CreateWebhook(ctx.Request.Context(), arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createWebhookResponse{
+		webhookResponse: newWebhookResponse(created),
+		Secret:          created.Secret,
+	})
+}
+
+type listWebhooksRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (server *Server) listWebhooksHandler(ctx *gin.Context) {
+	var req listWebhooksRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.ListWebhooksParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	webhooks, err := server.store.ListWebhooks(ctx.Request.Context(), arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	rsp := make([]webhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		rsp = append(rsp, newWebhookResponse(webhook))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type webhookURIRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) deleteWebhookHandler(ctx *gin.Context) {
+	var req webhookURIRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	if _, ok := server.ownedWebhook(ctx, req.ID); !ok {
+		return
+	}
+
+	err := server.store.DeleteWebhook(ctx.Request.Context(), req.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type listWebhookDeliveriesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (server *Server) listWebhookDeliveriesHandler(ctx *gin.Context) {
+	var uri webhookURIRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	var req listWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	if _, ok := server.ownedWebhook(ctx, uri.ID); !ok {
+		return
+	}
+
+	arg := db.ListWebhookDeliveriesParams{
+		WebhookID: uri.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	}
+
+	deliveries, err := server.store.ListWebhookDeliveries(ctx.Request.Context(), arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+type replayWebhookDeliveryRequest struct {
+	ID         int64 `uri:"id" binding:"required,min=1"`
+	DeliveryID int64 `uri:"delivery_id" binding:"required,min=1"`
+}
+
+func (server *Server) replayWebhookDeliveryHandler(ctx *gin.Context) {
+	var req replayWebhookDeliveryRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(ctx, err))
+		return
+	}
+
+	if _, ok := server.ownedWebhook(ctx, req.ID); !ok {
+		return
+	}
+
+	delivery, err := server.store.GetWebhookDelivery(ctx.Request.Context(), req.DeliveryID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ctx, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	if delivery.WebhookID != req.ID {
+		err := errors.New("delivery doesn't belong to the webhook")
+		ctx.JSON(http.StatusNotFound, errorResponse(ctx, err))
+		return
+	}
+
+	delivery, err = server.store.ReplayWebhookDelivery(ctx.Request.Context(), delivery.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, delivery)
+}
+
+// ownedWebhook loads a webhook and checks it belongs to the authenticated user,
+// writing the error response and returning false otherwise.
+func (server *Server) ownedWebhook(ctx *gin.Context, id int64) (db.Webhook, bool) {
+	webhook, err := server.store.GetWebhook(ctx.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(ctx, err))
+			return webhook, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(ctx, err))
+		return webhook, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if webhook.Owner != authPayload.Username {
+		err := errors.New("webhook doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(ctx, err))
+		return webhook, false
+	}
+
+	return webhook, true
+}
@@ -0,0 +1,145 @@
+// Package apikey issues and checks API keys, which let partners' backend
+// services call the API on behalf of a user without logging in. A key is
+// only good for the scopes it was created with.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Keys look like sbk_<prefix>_<secret>. The prefix is hex, so the first
+// underscore after it ends it; the secret may contain more.
+const (
+	keyTag      = "sbk_"
+	prefixBytes = 6
+	secretBytes = 32
+)
+
+var (
+	ErrInvalidKey = errors.New("API key is invalid")
+	ErrExpiredKey = errors.New("API key has expired")
+	ErrRevokedKey = errors.New("API key has been revoked")
+)
+
+// CreateParams holds the arguments for issuing a key. A zero ExpiresAt
+// means the key never expires.
+type CreateParams struct {
+	Owner     string
+	Name      string
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+// Service issues keys and authenticates requests made with them.
+type Service struct {
+	store db.Store
+	now   func() time.Time
+}
+
+// NewService creates a Service that keeps keys in store.
+func NewService(store db.Store) *Service {
+	return &Service{store: store, now: time.Now}
+}
+
+// Create issues a key and returns it along with the key string to hand to
+// the client. Only a hash of the secret is stored, so the key string cannot
+// be shown again.
+func (s *Service) Create(ctx context.Context, arg CreateParams) (db.ApiKey, string, error) {
+	prefixRaw := make([]byte, prefixBytes)
+	if _, err := rand.Read(prefixRaw); err != nil {
+		return db.ApiKey{}, "", fmt.Errorf("failed to generate key prefix: %w", err)
+	}
+	prefix := hex.EncodeToString(prefixRaw)
+
+	secret, err := util.NewSecret(secretBytes)
+	if err != nil {
+		return db.ApiKey{}, "", err
+	}
+
+	key, err := s.store.CreateAPIKeyTx(ctx, db.CreateApiKeyParams{
+		Owner:      arg.Owner,
+		Name:       arg.Name,
+		Prefix:     prefix,
+		SecretHash: util.HashSecret(secret),
+		Scopes:     arg.Scopes,
+		ExpiresAt:  pgtype.Timestamptz{Time: arg.ExpiresAt, Valid: !arg.ExpiresAt.IsZero()},
+	})
+	if err != nil {
+		return db.ApiKey{}, "", err
+	}
+	return key, keyTag + prefix + "_" + secret, nil
+}
+
+// Authenticate returns the key that keyString was issued for, and records
+// that it was used. It fails with ErrInvalidKey, ErrRevokedKey or
+// ErrExpiredKey if the key may not be used.
+func (s *Service) Authenticate(ctx context.Context, keyString string) (db.ApiKey, error) {
+	prefix, secret, ok := parse(keyString)
+	if !ok {
+		return db.ApiKey{}, ErrInvalidKey
+	}
+
+	key, err := s.store.GetApiKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.ApiKey{}, ErrInvalidKey
+		}
+		return db.ApiKey{}, err
+	}
+	if subtle.ConstantTimeCompare(util.HashSecret(secret), key.SecretHash) != 1 {
+		return db.ApiKey{}, ErrInvalidKey
+	}
+	if key.RevokedAt.Valid {
+		return db.ApiKey{}, ErrRevokedKey
+	}
+	if key.ExpiresAt.Valid && !s.now().Before(key.ExpiresAt.Time) {
+		return db.ApiKey{}, ErrExpiredKey
+	}
+
+	if err := s.store.TouchApiKey(ctx, key.ID); err != nil {
+		return db.ApiKey{}, err
+	}
+	return key, nil
+}
+
+// HasScope reports whether key was granted scope.
+func HasScope(key db.ApiKey, scope string) bool {
+	return slices.Contains(key.Scopes, scope)
+}
+
+// IsKey reports whether s looks like an API key rather than an access token.
+func IsKey(s string) bool {
+	return strings.HasPrefix(s, keyTag)
+}
+
+// Prefix returns the public prefix of keyString, which identifies the key
+// without revealing its secret, or "" if keyString is malformed.
+func Prefix(keyString string) string {
+	prefix, _, _ := parse(keyString)
+	return prefix
+}
+
+func parse(keyString string) (prefix, secret string, ok bool) {
+	rest, ok := strings.CutPrefix(keyString, keyTag)
+	if !ok {
+		return "", "", false
+	}
+	prefix, secret, ok = strings.Cut(rest, "_")
+	if !ok || len(prefix) != 2*prefixBytes || secret == "" {
+		return "", "", false
+	}
+	return prefix, secret, true
+}
@@ -0,0 +1,156 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// createKey issues a key through a mock store and returns the stored row
+// and the key string.
+func createKey(t *testing.T, expiresAt time.Time) (db.ApiKey, string) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateAPIKeyTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateApiKeyParams) (db.ApiKey, error) {
+			return db.ApiKey{
+				ID:         1,
+				Owner:      arg.Owner,
+				Name:       arg.Name,
+				Prefix:     arg.Prefix,
+				SecretHash: arg.SecretHash,
+				Scopes:     arg.Scopes,
+				ExpiresAt:  arg.ExpiresAt,
+			}, nil
+		})
+
+	key, keyString, err := NewService(store).Create(context.Background(), CreateParams{
+		Owner:     util.RandomOwner(),
+		Name:      "payroll",
+		Scopes:    []string{util.ScopeAccountsRead},
+		ExpiresAt: expiresAt,
+	})
+	require.NoError(t, err)
+	return key, keyString
+}
+
+func TestCreate(t *testing.T) {
+	key, keyString := createKey(t, time.Time{})
+	require.True(t, IsKey(keyString))
+	require.Equal(t, key.Prefix, Prefix(keyString))
+	_, secret, ok := parse(keyString)
+	require.True(t, ok)
+	require.Equal(t, util.HashSecret(secret), key.SecretHash)
+	require.False(t, key.ExpiresAt.Valid)
+
+	_, other := createKey(t, time.Time{})
+	require.NotEqual(t, keyString, other)
+}
+
+func TestAuthenticate(t *testing.T) {
+	key, keyString := createKey(t, time.Now().Add(time.Hour))
+
+	testCases := []struct {
+		name       string
+		keyString  string
+		buildStubs func(store *mockdb.MockStore)
+		wantErr    error
+	}{
+		{
+			name:      "OK",
+			keyString: keyString,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(key, nil)
+				store.EXPECT().TouchApiKey(gomock.Any(), gomock.Eq(key.ID)).Times(1)
+			},
+		},
+		{
+			name:      "Malformed",
+			keyString: "sbk_nounderscore",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: ErrInvalidKey,
+		},
+		{
+			name:      "UnknownPrefix",
+			keyString: keyString,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, db.ErrRecordNotFound)
+			},
+			wantErr: ErrInvalidKey,
+		},
+		{
+			name:      "WrongSecret",
+			keyString: keyTag + key.Prefix + "_wrong",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(key, nil)
+				store.EXPECT().TouchApiKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: ErrInvalidKey,
+		},
+		{
+			name:      "Revoked",
+			keyString: keyString,
+			buildStubs: func(store *mockdb.MockStore) {
+				revoked := key
+				revoked.RevokedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(revoked, nil)
+				store.EXPECT().TouchApiKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: ErrRevokedKey,
+		},
+		{
+			name:      "Expired",
+			keyString: keyString,
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := key
+				expired.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Second), Valid: true}
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(expired, nil)
+				store.EXPECT().TouchApiKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: ErrExpiredKey,
+		},
+		{
+			name:      "StoreError",
+			keyString: keyString,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			wantErr: sql.ErrConnDone,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			got, err := NewService(store).Authenticate(context.Background(), tc.keyString)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, key.ID, got.ID)
+		})
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	key := db.ApiKey{Scopes: []string{util.ScopeAccountsRead}}
+	require.True(t, HasScope(key, util.ScopeAccountsRead))
+	require.False(t, HasScope(key, util.ScopeTransfersCreate))
+}
@@ -0,0 +1,78 @@
+// Package audit checks that the audit log's hash chain is intact.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	db "simple_bank/db/sqlc"
+)
+
+// DefaultPageSize is how many rows Verify reads at a time unless told otherwise.
+const DefaultPageSize = 1000
+
+// Report is the outcome of Verify.
+type Report struct {
+	Entries  int64    `json:"entries"`
+	LastID   int64    `json:"last_id"`
+	LastHash string   `json:"last_hash"`
+	Problems []string `json:"problems"`
+}
+
+// OK reports whether the hash chain is intact.
+func (report Report) OK() bool {
+	return len(report.Problems) == 0
+}
+
+// Verify walks the audit log in id order and checks that:
+//
+//   - every row's hash matches the hash recomputed from its columns, so no
+//     row was edited;
+//   - every row's prev_hash equals the hash of the row before it, and the
+//     first row's is empty, so no row was removed or inserted.
+//
+// Comparing LastHash with a copy kept elsewhere also catches rows dropped
+// from the end of the log.
+func Verify(ctx context.Context, store db.Store, pageSize int32) (Report, error) {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	report := Report{Problems: []string{}}
+	prevHash := []byte{}
+
+	for {
+		rows, err := store.ListAuditLogAfter(ctx, db.ListAuditLogAfterParams{
+			AfterID:  report.LastID,
+			PageSize: pageSize,
+		})
+		if err != nil {
+			return Report{}, fmt.Errorf("cannot list audit log: %w", err)
+		}
+
+		for _, row := range rows {
+			if !bytes.Equal(row.PrevHash, prevHash) {
+				report.Problems = append(report.Problems, fmt.Sprintf(
+					"row %d: prev_hash %x does not match the hash of the previous row %x",
+					row.ID, row.PrevHash, prevHash))
+			}
+			if want := db.HashAuditLog(row); !bytes.Equal(row.Hash, want) {
+				report.Problems = append(report.Problems, fmt.Sprintf(
+					"row %d: hash %x does not match its contents, want %x",
+					row.ID, row.Hash, want))
+			}
+
+			prevHash = row.Hash
+			report.Entries++
+			report.LastID = row.ID
+		}
+
+		if int32(len(rows)) < pageSize {
+			break
+		}
+	}
+
+	report.LastHash = fmt.Sprintf("%x", prevHash)
+	return report, nil
+}
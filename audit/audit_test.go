@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// randomChain returns n correctly chained audit log rows.
+func randomChain(n int) []db.AuditLog {
+	rows := make([]db.AuditLog, n)
+	prevHash := []byte{}
+	for i := range rows {
+		rows[i] = db.AuditLog{
+			ID:        int64(i + 1),
+			Actor:     "operator",
+			Action:    db.AuditAccountFreeze,
+			Target:    db.AccountTarget(int64(i + 1)),
+			RequestID: fmt.Sprintf("req-%d", i),
+			Ip:        "127.0.0.1",
+			After:     []byte(`{"status":"frozen"}`),
+			PrevHash:  prevHash,
+			CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		}
+		rows[i].Hash = db.HashAuditLog(rows[i])
+		prevHash = rows[i].Hash
+	}
+	return rows
+}
+
+// expectPages serves rows from ListAuditLogAfter the way the query would.
+func expectPages(store *mockdb.MockStore, rows []db.AuditLog) {
+	store.EXPECT().
+		ListAuditLogAfter(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.ListAuditLogAfterParams) ([]db.AuditLog, error) {
+			page := []db.AuditLog{}
+			for _, row := range rows {
+				if row.ID > arg.AfterID && int32(len(page)) < arg.PageSize {
+					page = append(page, row)
+				}
+			}
+			return page, nil
+		})
+}
+
+func TestVerify(t *testing.T) {
+	testCases := []struct {
+		name         string
+		tamper       func(rows []db.AuditLog) []db.AuditLog
+		wantEntries  int64
+		wantProblems int
+	}{
+		{
+			name:        "Intact",
+			tamper:      func(rows []db.AuditLog) []db.AuditLog { return rows },
+			wantEntries: 5,
+		},
+		{
+			name: "Empty",
+			tamper: func(rows []db.AuditLog) []db.AuditLog {
+				return nil
+			},
+		},
+		{
+			name: "EditedRow",
+			tamper: func(rows []db.AuditLog) []db.AuditLog {
+				rows[2].Actor = "someone-else"
+				return rows
+			},
+			wantEntries:  5,
+			wantProblems: 1,
+		},
+		{
+			name: "RehashedRow",
+			tamper: func(rows []db.AuditLog) []db.AuditLog {
+				rows[2].Actor = "someone-else"
+				rows[2].Hash = db.HashAuditLog(rows[2])
+				return rows
+			},
+			wantEntries:  5,
+			wantProblems: 1,
+		},
+		{
+			name: "DeletedRow",
+			tamper: func(rows []db.AuditLog) []db.AuditLog {
+				return append(rows[:2], rows[3:]...)
+			},
+			wantEntries:  4,
+			wantProblems: 1,
+		},
+		{
+			name: "DeletedFirstRow",
+			tamper: func(rows []db.AuditLog) []db.AuditLog {
+				return rows[1:]
+			},
+			wantEntries:  4,
+			wantProblems: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			rows := tc.tamper(randomChain(5))
+			expectPages(store, rows)
+
+			// A page size of 2 makes Verify carry the chain across pages.
+			report, err := Verify(context.Background(), store, 2)
+			require.NoError(t, err)
+			require.Len(t, report.Problems, tc.wantProblems)
+			require.Equal(t, tc.wantProblems == 0, report.OK())
+			require.Equal(t, tc.wantEntries, report.Entries)
+			if len(rows) > 0 {
+				last := rows[len(rows)-1]
+				require.Equal(t, last.ID, report.LastID)
+				require.Equal(t, fmt.Sprintf("%x", last.Hash), report.LastHash)
+			}
+		})
+	}
+}
+
+func TestVerifyError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListAuditLogAfter(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil, errors.New("connection refused"))
+
+	_, err := Verify(context.Background(), store, 0)
+	require.ErrorContains(t, err, "connection refused")
+}
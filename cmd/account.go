@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	db "simple_bank/db/sqlc"
+
+	"github.com/spf13/cobra"
+)
+
+type createAccountArgs struct {
+	Owner    string `validate:"required"`
+	Currency string `validate:"required,currency"`
+}
+
+func (a *app) newCreateAccountCommand() *cobra.Command {
+	var args createAccountArgs
+
+	cmd := &cobra.Command{
+		Use:   "create-account",
+		Short: "Open an account with a zero balance",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return a.withStore(cmd.Context(), func(store db.Store) error {
+				account, err := createAccount(cmd.Context(), store, args)
+				if err != nil {
+					return err
+				}
+				return printJSON(cmd.OutOrStdout(), account)
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&args.Owner, "owner", "", "username of the account owner")
+	cmd.Flags().StringVar(&args.Currency, "currency", "", "account currency, e.g. USD")
+	cmd.MarkFlagRequired("owner")
+	cmd.MarkFlagRequired("currency")
+	return cmd
+}
+
+func createAccount(ctx context.Context, store db.Store, args createAccountArgs) (db.Account, error) {
+	if err := validate.Struct(args); err != nil {
+		return db.Account{}, err
+	}
+
+	account, err := store.CreateAccountTx(ctx, db.CreateAccountParams{
+		Owner:    args.Owner,
+		Balance:  0,
+		Currency: args.Currency,
+	})
+	if err != nil {
+		switch db.ErrorCode(err) {
+		case db.ForeignKeyViolation:
+			return db.Account{}, fmt.Errorf("user %q does not exist", args.Owner)
+		case db.UniqueViolation:
+			return db.Account{}, fmt.Errorf("user %q already has a %s account", args.Owner, args.Currency)
+		}
+		return db.Account{}, fmt.Errorf("cannot create account: %w", err)
+	}
+	return account, nil
+}
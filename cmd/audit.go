@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+
+	"simple_bank/audit"
+	db "simple_bank/db/sqlc"
+
+	"github.com/spf13/cobra"
+)
+
+func (a *app) newVerifyAuditLogCommand() *cobra.Command {
+	var pageSize int32
+
+	cmd := &cobra.Command{
+		Use:   "verify-audit-log",
+		Short: "Check that the audit log's hash chain is unbroken",
+		Long: `Walk the audit log in order, recompute every row's hash and check that each
+row points at the hash of the row before it. Prints a report ending in the
+hash of the last row and exits non-zero if any row was edited, removed or
+inserted out of order.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return a.withStore(cmd.Context(), func(store db.Store) error {
+				report, err := audit.Verify(cmd.Context(), store, pageSize)
+				if err != nil {
+					return err
+				}
+				if err := printJSON(cmd.OutOrStdout(), report); err != nil {
+					return err
+				}
+				if !report.OK() {
+					return fmt.Errorf("audit log has %d problems", len(report.Problems))
+				}
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().Int32Var(&pageSize, "page-size", audit.DefaultPageSize, "rows to read per query")
+	return cmd
+}
@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	db "simple_bank/db/sqlc"
+
+	"github.com/spf13/cobra"
+)
+
+// Export formats.
+const (
+	formatCSV  = "csv"
+	formatJSON = "json"
+)
+
+const defaultExportPageSize = 1000
+
+type exportArgs struct {
+	Table    string `validate:"oneof=accounts entries transfers users"`
+	Format   string `validate:"oneof=csv json"`
+	PageSize int32  `validate:"min=1"`
+}
+
+func (a *app) newExportCommand() *cobra.Command {
+	var args exportArgs
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "export <accounts|entries|transfers|users>",
+		Short: "Dump a table as CSV or JSON lines",
+		Long: `Dump a table as CSV or as JSON lines, one object per row, in primary key
+order. Rows are read in pages, so large tables do not have to fit in memory.
+Password hashes are never exported.`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"accounts", "entries", "transfers", "users"},
+		RunE: func(cmd *cobra.Command, positional []string) (err error) {
+			args.Table = positional[0]
+			if err := validate.Struct(args); err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if output != "" {
+				file, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer func() {
+					if closeErr := file.Close(); err == nil {
+						err = closeErr
+					}
+				}()
+				out = file
+			}
+
+			return a.withStore(cmd.Context(), func(store db.Store) error {
+				return export(cmd.Context(), store, args, out)
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&args.Format, "format", formatCSV, "output format, csv or json")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write instead of standard output")
+	cmd.Flags().Int32Var(&args.PageSize, "page-size", defaultExportPageSize, "rows fetched per query")
+	return cmd
+}
+
+// exportWriter writes rows in one of the export formats.
+type exportWriter struct {
+	csv  *csv.Writer
+	json *json.Encoder
+}
+
+func newExportWriter(format string, w io.Writer) *exportWriter {
+	if format == formatJSON {
+		return &exportWriter{json: json.NewEncoder(w)}
+	}
+	return &exportWriter{csv: csv.NewWriter(w)}
+}
+
+func (w *exportWriter) header(columns ...string) error {
+	if w.csv != nil {
+		return w.csv.Write(columns)
+	}
+	return nil
+}
+
+// row writes row as JSON, or record as CSV.
+func (w *exportWriter) row(row any, record ...string) error {
+	if w.csv != nil {
+		return w.csv.Write(record)
+	}
+	return w.json.Encode(row)
+}
+
+func (w *exportWriter) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	return nil
+}
+
+func export(ctx context.Context, store db.Store, args exportArgs, out io.Writer) error {
+	w := newExportWriter(args.Format, out)
+
+	var err error
+	switch args.Table {
+	case "accounts":
+		err = exportAccounts(ctx, store, args.PageSize, w)
+	case "entries":
+		err = exportEntries(ctx, store, args.PageSize, w)
+	case "transfers":
+		err = exportTransfers(ctx, store, args.PageSize, w)
+	case "users":
+		err = exportUsers(ctx, store, args.PageSize, w)
+	default:
+		err = fmt.Errorf("unknown table %q", args.Table)
+	}
+	if err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+func exportAccounts(ctx context.Context, store db.Store, pageSize int32, w *exportWriter) error {
+	if err := w.header("id", "owner", "balance", "currency", "created_at"); err != nil {
+		return err
+	}
+
+	var afterID int64
+	for {
+		accounts, err := store.ExportAccounts(ctx, db.ExportAccountsParams{AfterID: afterID, PageSize: pageSize})
+		if err != nil {
+			return fmt.Errorf("cannot export accounts: %w", err)
+		}
+		for _, account := range accounts {
+			err := w.row(account,
+				formatInt(account.ID), account.Owner, formatInt(account.Balance), account.Currency, formatTime(account.CreatedAt))
+			if err != nil {
+				return err
+			}
+		}
+		if len(accounts) < int(pageSize) {
+			return nil
+		}
+		afterID = accounts[len(accounts)-1].ID
+	}
+}
+
+func exportEntries(ctx context.Context, store db.Store, pageSize int32, w *exportWriter) error {
+	if err := w.header("id", "account_id", "amount", "created_at"); err != nil {
+		return err
+	}
+
+	var afterID int64
+	for {
+		entries, err := store.ExportEntries(ctx, db.ExportEntriesParams{AfterID: afterID, PageSize: pageSize})
+		if err != nil {
+			return fmt.Errorf("cannot export entries: %w", err)
+		}
+		for _, entry := range entries {
+			err := w.row(entry,
+				formatInt(entry.ID), formatInt(entry.AccountID), formatInt(entry.Amount), formatTime(entry.CreatedAt))
+			if err != nil {
+				return err
+			}
+		}
+		if len(entries) < int(pageSize) {
+			return nil
+		}
+		afterID = entries[len(entries)-1].ID
+	}
+}
+
+func exportTransfers(ctx context.Context, store db.Store, pageSize int32, w *exportWriter) error {
+	if err := w.header("id", "from_account_id", "to_account_id", "amount", "created_at"); err != nil {
+		return err
+	}
+
+	var afterID int64
+	for {
+		transfers, err := store.ExportTransfers(ctx, db.ExportTransfersParams{AfterID: afterID, PageSize: pageSize})
+		if err != nil {
+			return fmt.Errorf("cannot export transfers: %w", err)
+		}
+		for _, transfer := range transfers {
+			err := w.row(transfer,
+				formatInt(transfer.ID), formatInt(transfer.FromAccountID), formatInt(transfer.ToAccountID),
+				formatInt(transfer.Amount), formatTime(transfer.CreatedAt))
+			if err != nil {
+				return err
+			}
+		}
+		if len(transfers) < int(pageSize) {
+			return nil
+		}
+		afterID = transfers[len(transfers)-1].ID
+	}
+}
+
+func exportUsers(ctx context.Context, store db.Store, pageSize int32, w *exportWriter) error {
+	if err := w.header("username", "full_name", "email", "password_changed_at", "created_at"); err != nil {
+		return err
+	}
+
+	var afterUsername string
+	for {
+		users, err := store.ExportUsers(ctx, db.ExportUsersParams{AfterUsername: afterUsername, PageSize: pageSize})
+		if err != nil {
+			return fmt.Errorf("cannot export users: %w", err)
+		}
+		for _, user := range users {
+			err := w.row(user,
+				user.Username, user.FullName, user.Email, formatTime(user.PasswordChangedAt), formatTime(user.CreatedAt))
+			if err != nil {
+				return err
+			}
+		}
+		if len(users) < int(pageSize) {
+			return nil
+		}
+		afterUsername = users[len(users)-1].Username
+	}
+}
+
+func formatInt(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
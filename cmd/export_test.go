@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportAccountsCSV(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	accounts := []db.Account{
+		{ID: 1, Owner: "alice", Balance: 10, Currency: "USD", CreatedAt: createdAt},
+		{ID: 2, Owner: "bob", Balance: -10, Currency: "USD", CreatedAt: createdAt},
+		{ID: 5, Owner: "carol", Balance: 0, Currency: "EUR", CreatedAt: createdAt},
+	}
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().
+			ExportAccounts(gomock.Any(), gomock.Eq(db.ExportAccountsParams{AfterID: 0, PageSize: 2})).
+			Return(accounts[:2], nil),
+		store.EXPECT().
+			ExportAccounts(gomock.Any(), gomock.Eq(db.ExportAccountsParams{AfterID: 2, PageSize: 2})).
+			Return(accounts[2:], nil),
+	)
+
+	var out bytes.Buffer
+	err := export(context.Background(), store, exportArgs{Table: "accounts", Format: formatCSV, PageSize: 2}, &out)
+	require.NoError(t, err)
+	require.Equal(t, strings.Join([]string{
+		"id,owner,balance,currency,created_at",
+		"1,alice,10,USD,2024-01-02T03:04:05Z",
+		"2,bob,-10,USD,2024-01-02T03:04:05Z",
+		"5,carol,0,EUR,2024-01-02T03:04:05Z",
+		"",
+	}, "\n"), out.String())
+}
+
+func TestExportUsersJSON(t *testing.T) {
+	users := []db.ExportUsersRow{
+		{Username: "alice", FullName: "Alice", Email: "alice@example.com"},
+		{Username: "bob", FullName: "Bob", Email: "bob@example.com"},
+	}
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().
+			ExportUsers(gomock.Any(), gomock.Eq(db.ExportUsersParams{AfterUsername: "", PageSize: 2})).
+			Return(users, nil),
+		// A full page means there may be more rows.
+		store.EXPECT().
+			ExportUsers(gomock.Any(), gomock.Eq(db.ExportUsersParams{AfterUsername: "bob", PageSize: 2})).
+			Return([]db.ExportUsersRow{}, nil),
+	)
+
+	var out bytes.Buffer
+	err := export(context.Background(), store, exportArgs{Table: "users", Format: formatJSON, PageSize: 2}, &out)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	for i, line := range lines {
+		var row map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &row))
+		require.Equal(t, users[i].Username, row["username"])
+		require.NotContains(t, row, "password")
+	}
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/ledger"
+
+	"github.com/spf13/cobra"
+)
+
+func (a *app) newVerifyLedgerCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-ledger",
+		Short: "Check that balances, entries and transfers agree",
+		Long: `Check that every account balance matches its entries and transfers, that
+every transfer has two entries and that entries sum to zero. Prints a report
+and exits non-zero if anything is off.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return a.withStore(cmd.Context(), func(store db.Store) error {
+				report, err := ledger.Verify(cmd.Context(), store)
+				if err != nil {
+					return err
+				}
+				if err := printJSON(cmd.OutOrStdout(), report); err != nil {
+					return err
+				}
+				if !report.OK() {
+					return fmt.Errorf("ledger has %d problems", len(report.Problems))
+				}
+				return nil
+			})
+		},
+	}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+
+	"simple_bank/db/migration"
+
+	"github.com/spf13/cobra"
+)
+
+const migrateUsage = `usage: simple_bank migrate <command>
+
+commands:
+  up [N]       apply all pending migrations, or the next N
+  down [N]     roll back the last N migrations (default 1)
+  goto V       migrate up or down to version V
+  status       show the applied and pending versions`
+
+func (a *app) newMigrateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate <command>",
+		Short: "Apply or roll back database migrations",
+		Long:  migrateUsage,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrate(a.config.DBSource, args, cmd.OutOrStdout())
+		},
+	}
+}
+
+// runMigrate runs the migrate subcommand with args, e.g. ["up"] or ["goto", "3"].
+func runMigrate(dbSource string, args []string, out io.Writer) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errors.New(migrateUsage)
+	}
+
+	n := -1
+	if len(args) == 2 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid argument %q\n%s", args[1], migrateUsage)
+		}
+		n = v
+	}
+
+	var action func(*migration.Migrator) error
+	switch {
+	case args[0] == "up":
+		action = func(m *migration.Migrator) error { return m.Up(max(n, 0)) }
+	case args[0] == "down":
+		steps := n
+		if steps < 0 {
+			steps = 1
+		}
+		action = func(m *migration.Migrator) error { return m.Down(steps) }
+	case args[0] == "goto" && n >= 0:
+		action = func(m *migration.Migrator) error { return m.Goto(uint(n)) }
+	case args[0] == "status" && n < 0:
+		action = func(*migration.Migrator) error { return nil }
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	migrator, err := migration.NewMigrator(dbSource)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if err := action(migrator); err != nil {
+		return err
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "version: %d\ndirty: %t\nlatest: %d\npending: %v\n",
+		status.Version, status.Dirty, status.Latest, status.Pending)
+	return nil
+}
+
+// runDBMigration brings the schema up to date before the server starts.
+func runDBMigration(dbSource string) error {
+	migrator, err := migration.NewMigrator(dbSource)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if err := migrator.Up(0); err != nil {
+		return err
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	slog.Info("database migrated", "version", status.Version)
+	return nil
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMigrateUsage(t *testing.T) {
+	// Invalid arguments are rejected before connecting to the database.
+	for _, args := range [][]string{
+		nil,
+		{"sideways"},
+		{"up", "-1"},
+		{"up", "many"},
+		{"goto"},
+		{"status", "2"},
+		{"down", "1", "2"},
+	} {
+		err := runMigrate("postgres://invalid", args, io.Discard)
+		require.ErrorContains(t, err, "usage: simple_bank migrate", "%v", args)
+	}
+}
@@ -0,0 +1,142 @@
+// Package cmd implements the simple_bank command line: the server itself and
+// the tools operators use to manage the database behind it.
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/logging"
+	"simple_bank/tracing"
+	"simple_bank/util"
+	"simple_bank/val"
+
+	"github.com/exaring/otelpgx"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spf13/cobra"
+)
+
+// cliActor is the audit log actor of changes made by commands.
+const cliActor = "cli"
+
+// app holds the state shared by all commands, set up before any of them runs.
+type app struct {
+	config util.Config
+}
+
+// Execute runs the command named on the command line and exits non-zero if
+// it fails. SIGINT and SIGTERM cancel the command's context.
+func Execute() {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := newRootCommand().ExecuteContext(ctx)
+	stop()
+	if err != nil {
+		fatal("command failed", err)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	a := &app{}
+
+	root := &cobra.Command{
+		Use:           "simple_bank",
+		Short:         "Simple bank server and operator tools",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Changes made from the command line show up in the audit log as cliActor.
+			cmd.SetContext(db.WithAuditActor(cmd.Context(), db.AuditActor{Username: cliActor}))
+			return a.setup()
+		},
+	}
+
+	root.AddCommand(
+		a.newServeCommand(),
+		a.newMigrateCommand(),
+		a.newCreateUserCommand(),
+		a.newSetRoleCommand(),
+		a.newCreateAccountCommand(),
+		a.newTransferCommand(),
+		a.newVerifyLedgerCommand(),
+		a.newVerifyAuditLogCommand(),
+		a.newExportCommand(),
+		a.newSeedCommand(),
+	)
+	return root
+}
+
+// setup loads the config and installs the configured logger.
+func (a *app) setup() error {
+	config, err := util.LoadConfig(".")
+	if err != nil {
+		return fmt.Errorf("cannot load config: %w", err)
+	}
+
+	logger, err := logging.New(os.Stderr, config.LogLevel)
+	if err != nil {
+		return fmt.Errorf("cannot create logger: %w", err)
+	}
+	slog.SetDefault(logger)
+
+	a.config = config
+	return nil
+}
+
+// connect opens a connection pool to the database, tracing every query.
+func connect(ctx context.Context, config util.Config) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(config.DBSource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse db source: %w", err)
+	}
+	poolConfig.ConnConfig.Tracer = otelpgx.NewTracer(otelpgx.WithSpanNameFunc(tracing.QuerySpanName))
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to db: %w", err)
+	}
+	return pool, nil
+}
+
+// withStore connects to the database and runs fn with a store on it.
+func (a *app) withStore(ctx context.Context, fn func(store db.Store) error) error {
+	pool, err := connect(ctx, a.config)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	return fn(db.NewStore(pool))
+}
+
+// validate checks command input against the same rules as the APIs, using
+// `validate` struct tags.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	val.RegisterValidations(v)
+	return v
+}
+
+// printJSON writes v to w as indented JSON.
+func printJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// fatal logs err and exits, like log.Fatal.
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	db "simple_bank/db/sqlc"
+	"simple_bank/seed"
+
+	"github.com/spf13/cobra"
+)
+
+func (a *app) newSeedCommand() *cobra.Command {
+	opts := seed.DefaultOptions()
+
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Fill an empty database with a reproducible dataset",
+		Long: `Fill an empty database with users, accounts in several currencies and a
+history of transfers. A treasury user funds every new account, then customers
+send each other money. The same --seed always produces the same dataset.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return a.withStore(cmd.Context(), func(store db.Store) error {
+				result, err := seed.Run(cmd.Context(), store, opts)
+				if err != nil {
+					return err
+				}
+				return printJSON(cmd.OutOrStdout(), result)
+			})
+		},
+	}
+
+	cmd.Flags().Int64Var(&opts.Seed, "seed", opts.Seed, "random seed")
+	cmd.Flags().IntVar(&opts.Users, "users", opts.Users, "number of customers")
+	cmd.Flags().StringSliceVar(&opts.Currencies, "currencies", opts.Currencies, "currencies customers open accounts in")
+	cmd.Flags().Int64Var(&opts.OpeningBalance, "opening-balance", opts.OpeningBalance, "largest opening deposit per account")
+	cmd.Flags().IntVar(&opts.Transfers, "transfers", opts.Transfers, "number of customer transfers")
+	cmd.Flags().Int64Var(&opts.MaxAmount, "max-amount", opts.MaxAmount, "largest customer transfer")
+	cmd.Flags().StringVar(&opts.Password, "password", opts.Password, "password of every seeded user")
+	return cmd
+}
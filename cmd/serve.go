@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"simple_bank/api"
+	db "simple_bank/db/sqlc"
+	"simple_bank/gapi"
+	"simple_bank/mail"
+	"simple_bank/metrics"
+	"simple_bank/outbox"
+	"simple_bank/pb"
+	"simple_bank/stream"
+	"simple_bank/tracing"
+	"simple_bank/util"
+	"simple_bank/webhook"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+func (a *app) newServeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Run the HTTP and gRPC servers and the background workers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServe(cmd.Context(), a.config)
+		},
+	}
+}
+
+// runServe serves until ctx is cancelled, then shuts down gracefully.
+func runServe(ctx context.Context, config util.Config) error {
+	if config.MigrateOnStart {
+		if err := runDBMigration(config.DBSource); err != nil {
+			return fmt.Errorf("cannot migrate database: %w", err)
+		}
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, config.TracingExporter, config.TracingEndpoint)
+	if err != nil {
+		return fmt.Errorf("cannot set up tracing: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("cannot flush traces", "err", err)
+		}
+	}()
+
+	conn, err := connect(context.Background(), config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	prometheus.MustRegister(metrics.NewPoolCollector(conn))
+
+	// Background workers and the gRPC server register here so the pool is
+	// only closed once they have all stopped.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Stop them too if startup fails part way.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	mailer, err := mail.NewMailer(mail.Options{
+		Kind:         config.Mailer,
+		Target:       config.MailTarget,
+		From:         config.MailFrom,
+		SMTPAddress:  config.SMTPAddress,
+		SMTPUsername: config.SMTPUsername,
+		SMTPPassword: config.SMTPPassword,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot create mailer: %w", err)
+	}
+
+	store := db.NewStore(conn)
+	if err := runOutboxRelay(ctx, &wg, config, store); err != nil {
+		return err
+	}
+	runWebhookWorker(ctx, &wg, config, store)
+
+	if config.GRPCServerAddress != "" {
+		if err := runGrpcServer(ctx, &wg, config, store, mailer); err != nil {
+			return err
+		}
+	}
+
+	broker := stream.NewBroker(config.StreamBufferSize)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		broker.Listen(ctx, conn)
+	}()
+
+	server, err := api.NewServer(config, store, broker, mailer)
+	if err != nil {
+		return fmt.Errorf("cannot create server: %w", err)
+	}
+
+	slog.Info("start HTTP server", "address", config.ServerAddress)
+	if err := server.Start(ctx, config.ServerAddress); err != nil {
+		return fmt.Errorf("cannot start server: %w", err)
+	}
+	slog.Info("HTTP server stopped, waiting for background workers")
+	return nil
+}
+
+func runOutboxRelay(ctx context.Context, wg *sync.WaitGroup, config util.Config, store db.Store) error {
+	sinks := outbox.MultiSink{webhook.NewDispatcher(store)}
+	if config.OutboxSink != "" {
+		sink, err := outbox.NewSink(config.OutboxSink, config.OutboxTarget)
+		if err != nil {
+			return fmt.Errorf("cannot create outbox sink: %w", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	relay := outbox.NewRelay(store, sinks, config.OutboxPollInterval)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		relay.Run(ctx)
+	}()
+	return nil
+}
+
+func runWebhookWorker(ctx context.Context, wg *sync.WaitGroup, config util.Config, store db.Store) {
+	client := webhook.NewClient(config.WebhookTimeout)
+	worker := webhook.NewWorker(store, client, config.OutboxPollInterval)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		worker.Run(ctx)
+	}()
+}
+
+func runGrpcServer(ctx context.Context, wg *sync.WaitGroup, config util.Config, store db.Store, mailer mail.Mailer) error {
+	server, err := gapi.NewServer(config, store, mailer)
+	if err != nil {
+		return fmt.Errorf("cannot create gRPC server: %w", err)
+	}
+
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(server.AuthInterceptor))
+	pb.RegisterSimpleBankServer(grpcServer, server)
+	reflection.Register(grpcServer)
+
+	listener, err := net.Listen("tcp", config.GRPCServerAddress)
+	if err != nil {
+		return fmt.Errorf("cannot create gRPC listener: %w", err)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+
+		// Let in-flight RPCs finish, but no longer than the shutdown deadline.
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(config.ShutdownTimeout):
+			grpcServer.Stop()
+		}
+	}()
+
+	go func() {
+		slog.Info("start gRPC server", "address", listener.Addr().String())
+		if err := grpcServer.Serve(listener); err != nil {
+			fatal("cannot start gRPC server", err)
+		}
+	}()
+	return nil
+}
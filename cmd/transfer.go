@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	db "simple_bank/db/sqlc"
+
+	"github.com/spf13/cobra"
+)
+
+type transferArgs struct {
+	FromAccountID int64  `validate:"required,min=1"`
+	ToAccountID   int64  `validate:"required,min=1,nefield=FromAccountID"`
+	Amount        int64  `validate:"required,min=1"`
+	Currency      string `validate:"required,currency"`
+}
+
+func (a *app) newTransferCommand() *cobra.Command {
+	var args transferArgs
+
+	cmd := &cobra.Command{
+		Use:   "transfer",
+		Short: "Move money between two accounts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return a.withStore(cmd.Context(), func(store db.Store) error {
+				result, err := transfer(cmd.Context(), store, args)
+				if err != nil {
+					return err
+				}
+				return printJSON(cmd.OutOrStdout(), result)
+			})
+		},
+	}
+
+	cmd.Flags().Int64Var(&args.FromAccountID, "from", 0, "ID of the account to debit")
+	cmd.Flags().Int64Var(&args.ToAccountID, "to", 0, "ID of the account to credit")
+	cmd.Flags().Int64Var(&args.Amount, "amount", 0, "amount in minor units")
+	cmd.Flags().StringVar(&args.Currency, "currency", "", "currency of both accounts, e.g. USD")
+	cmd.MarkFlagRequired("from")
+	cmd.MarkFlagRequired("to")
+	cmd.MarkFlagRequired("amount")
+	cmd.MarkFlagRequired("currency")
+	return cmd
+}
+
+// transfer runs a transfer after the same checks as the API: both accounts
+// must exist and be in the given currency.
+func transfer(ctx context.Context, store db.Store, args transferArgs) (db.TransferTxResult, error) {
+	if err := validate.Struct(args); err != nil {
+		return db.TransferTxResult{}, err
+	}
+
+	for _, id := range []int64{args.FromAccountID, args.ToAccountID} {
+		account, err := store.GetAccount(ctx, id)
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				return db.TransferTxResult{}, fmt.Errorf("account %d does not exist", id)
+			}
+			return db.TransferTxResult{}, fmt.Errorf("cannot get account %d: %w", id, err)
+		}
+		if account.Currency != args.Currency {
+			return db.TransferTxResult{}, fmt.Errorf("account [%d] currency mismatch: %s vs %s", id, account.Currency, args.Currency)
+		}
+	}
+
+	result, err := store.TransferTx(ctx, db.TransferTxParams{
+		FromAccountID: args.FromAccountID,
+		ToAccountID:   args.ToAccountID,
+		Amount:        args.Amount,
+	})
+	if err != nil {
+		return db.TransferTxResult{}, fmt.Errorf("cannot transfer: %w", err)
+	}
+	return result, nil
+}
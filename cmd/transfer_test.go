@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransfer(t *testing.T) {
+	account1 := db.Account{ID: 1, Owner: util.RandomOwner(), Currency: util.USD}
+	account2 := db.Account{ID: 2, Owner: util.RandomOwner(), Currency: util.USD}
+	account3 := db.Account{ID: 3, Owner: util.RandomOwner(), Currency: util.EUR}
+
+	testCases := []struct {
+		name       string
+		args       transferArgs
+		buildStubs func(store *mockdb.MockStore)
+		wantErr    string
+	}{
+		{
+			name: "OK",
+			args: transferArgs{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Eq(db.TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10})).
+					Times(1).
+					Return(db.TransferTxResult{}, nil)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			args: transferArgs{FromAccountID: account1.ID, ToAccountID: account3.ID, Amount: 10, Currency: util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).Times(1).Return(account3, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: "currency mismatch",
+		},
+		{
+			name: "AccountNotFound",
+			args: transferArgs{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: "account 1 does not exist",
+		},
+		{
+			name: "SameAccount",
+			args: transferArgs{FromAccountID: account1.ID, ToAccountID: account1.ID, Amount: 10, Currency: util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: "ToAccountID",
+		},
+		{
+			name: "NegativeAmount",
+			args: transferArgs{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: -1, Currency: util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: "Amount",
+		},
+		{
+			name: "InvalidCurrency",
+			args: transferArgs{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, Currency: "usd"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: "Currency",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			_, err := transfer(context.Background(), store, tc.args)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.wantErr)
+			}
+		})
+	}
+}
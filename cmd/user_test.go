@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetRole(t *testing.T) {
+	user := db.User{Username: util.RandomOwner(), Role: util.AdminRole}
+
+	testCases := []struct {
+		name       string
+		args       setRoleArgs
+		buildStubs func(store *mockdb.MockStore)
+		wantErr    string
+	}{
+		{
+			// The change goes through the audited transaction.
+			name: "OK",
+			args: setRoleArgs{Username: user.Username, Role: util.AdminRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserRoleTx(gomock.Any(), gomock.Eq(db.UpdateUserRoleParams{Role: util.AdminRole, Username: user.Username})).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().UpdateUserRole(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name: "UserNotFound",
+			args: setRoleArgs{Username: user.Username, Role: util.AdminRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, db.ErrRecordNotFound)
+			},
+			wantErr: "does not exist",
+		},
+		{
+			name: "InvalidRole",
+			args: setRoleArgs{Username: user.Username, Role: "root"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: "Role",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			output, err := setRole(context.Background(), store, tc.args)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				require.Equal(t, user.Role, output.Role)
+				return
+			}
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
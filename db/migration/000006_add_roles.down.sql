@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS "transfer_reversals";
+
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "accounts_currency_fkey";
+
+DROP TABLE IF EXISTS "currencies";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "status";
+
+ALTER TABLE "users" DROP COLUMN IF EXISTS "role";
@@ -0,0 +1,42 @@
+ALTER TABLE "users" ADD COLUMN "role" varchar NOT NULL DEFAULT 'customer'
+  CHECK ("role" IN ('customer', 'support', 'admin'));
+
+ALTER TABLE "accounts" ADD COLUMN "status" varchar NOT NULL DEFAULT 'active'
+  CHECK ("status" IN ('active', 'frozen'));
+
+CREATE TABLE "currencies" (
+  "code" varchar PRIMARY KEY,
+  "enabled" boolean NOT NULL DEFAULT true,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- CAD and the currencies of existing accounts stay valid, but are closed
+-- to new accounts until an admin enables them.
+INSERT INTO "currencies" ("code", "enabled") VALUES ('USD', true), ('EUR', true), ('CAD', false);
+
+INSERT INTO "currencies" ("code", "enabled")
+SELECT DISTINCT "currency", false FROM "accounts"
+ON CONFLICT ("code") DO NOTHING;
+
+ALTER TABLE "accounts" ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
+
+CREATE TABLE "transfer_reversals" (
+  "transfer_id" bigint PRIMARY KEY,
+  "reversal_id" bigint UNIQUE NOT NULL,
+  "reversed_by" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "transfer_reversals" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "transfer_reversals" ADD FOREIGN KEY ("reversal_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "transfer_reversals" ADD FOREIGN KEY ("reversed_by") REFERENCES "users" ("username");
+
+COMMENT ON COLUMN "users"."role" IS 'customer, support or admin';
+
+COMMENT ON COLUMN "accounts"."status" IS 'active or frozen; frozen accounts cannot send or receive transfers';
+
+COMMENT ON COLUMN "currencies"."enabled" IS 'whether new accounts can be opened in this currency';
+
+COMMENT ON COLUMN "transfer_reversals"."reversal_id" IS 'the transfer that moved the money back';
@@ -0,0 +1,33 @@
+CREATE TABLE "audit_log" (
+  "id" bigserial PRIMARY KEY,
+  "actor" varchar NOT NULL,
+  "action" varchar NOT NULL,
+  "target" varchar NOT NULL,
+  "request_id" varchar NOT NULL,
+  "ip" varchar NOT NULL,
+  "before" json,
+  "after" json,
+  "prev_hash" bytea NOT NULL,
+  "hash" bytea NOT NULL,
+  "created_at" timestamptz NOT NULL
+);
+
+CREATE INDEX ON "audit_log" ("actor");
+
+CREATE INDEX ON "audit_log" ("target");
+
+COMMENT ON COLUMN "audit_log"."before" IS 'kept as json, not jsonb, so the hashed text reads back unchanged';
+
+COMMENT ON COLUMN "audit_log"."prev_hash" IS 'hash of the previous row, empty for the first row';
+
+COMMENT ON COLUMN "audit_log"."hash" IS 'SHA-256 over prev_hash and the other columns except id';
+
+CREATE FUNCTION "audit_log_append_only"() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_log_append_only"
+BEFORE UPDATE OR DELETE ON "audit_log"
+FOR EACH ROW EXECUTE FUNCTION "audit_log_append_only"();
@@ -0,0 +1,16 @@
+CREATE TABLE "password_reset_tokens" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "token_hash" bytea UNIQUE NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "password_reset_tokens" ("username");
+
+ALTER TABLE "password_reset_tokens" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+COMMENT ON COLUMN "password_reset_tokens"."token_hash" IS 'SHA-256 of the token sent to the user; the token itself is never stored';
+
+COMMENT ON COLUMN "password_reset_tokens"."used_at" IS 'set when the token resets the password; a token works once';
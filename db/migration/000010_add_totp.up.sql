@@ -0,0 +1,29 @@
+CREATE TABLE "totp_secrets" (
+  "username" varchar PRIMARY KEY,
+  "secret_encrypted" bytea NOT NULL,
+  "confirmed_at" timestamptz,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "totp_secrets" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "totp_recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "code_hash" bytea NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "totp_recovery_codes" ("username", "code_hash");
+
+ALTER TABLE "totp_recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+COMMENT ON COLUMN "totp_secrets"."secret_encrypted" IS 'TOTP secret sealed with TOTP_ENCRYPTION_KEY';
+
+COMMENT ON COLUMN "totp_secrets"."confirmed_at" IS 'set once the user proves they can generate codes; until then login does not ask for one';
+
+COMMENT ON COLUMN "totp_secrets"."last_used_step" IS 'time step of the last accepted code, so that a code cannot be replayed';
+
+COMMENT ON COLUMN "totp_recovery_codes"."code_hash" IS 'SHA-256 of a one-time recovery code; the code itself is never stored';
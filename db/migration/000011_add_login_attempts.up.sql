@@ -0,0 +1,12 @@
+CREATE TABLE "login_attempts" (
+  "key" varchar PRIMARY KEY,
+  "failures" integer NOT NULL,
+  "locked_until" timestamptz,
+  "last_failed_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "login_attempts"."key" IS 'user:<username> or ip:<address> the failures are counted for';
+
+COMMENT ON COLUMN "login_attempts"."failures" IS 'consecutive failed logins; reset by a successful login or after a quiet period';
+
+COMMENT ON COLUMN "login_attempts"."locked_until" IS 'logins for key are refused before this time, without checking the password';
@@ -0,0 +1,26 @@
+CREATE TABLE "api_keys" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "prefix" varchar UNIQUE NOT NULL,
+  "secret_hash" bytea NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "expires_at" timestamptz,
+  "revoked_at" timestamptz,
+  "last_used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "api_keys" ("owner");
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+COMMENT ON COLUMN "api_keys"."prefix" IS 'public part of the key, used to look it up';
+
+COMMENT ON COLUMN "api_keys"."secret_hash" IS 'SHA-256 of the secret part of the key; the key itself is never stored';
+
+COMMENT ON COLUMN "api_keys"."scopes" IS 'what the key may do on behalf of owner, such as accounts:read';
+
+COMMENT ON COLUMN "api_keys"."expires_at" IS 'the key is refused from this time on; NULL never expires';
+
+COMMENT ON COLUMN "api_keys"."last_used_at" IS 'when the key last authenticated a request, to the minute';
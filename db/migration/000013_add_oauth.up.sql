@@ -0,0 +1,60 @@
+CREATE TABLE "oauth_clients" (
+  "id" varchar PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "secret_hash" bytea,
+  "redirect_uris" varchar[] NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "oauth_clients" ("owner");
+
+ALTER TABLE "oauth_clients" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+CREATE TABLE "oauth_authorization_codes" (
+  "code_hash" bytea PRIMARY KEY,
+  "client_id" varchar NOT NULL,
+  "username" varchar NOT NULL,
+  "redirect_uri" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "code_challenge" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id");
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "oauth_tokens" (
+  "token_hash" bytea PRIMARY KEY,
+  "client_id" varchar NOT NULL,
+  "username" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "oauth_tokens" ("expires_at");
+
+ALTER TABLE "oauth_tokens" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id");
+
+ALTER TABLE "oauth_tokens" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+COMMENT ON COLUMN "oauth_clients"."owner" IS 'user who registered the client; client credentials tokens act for them';
+
+COMMENT ON COLUMN "oauth_clients"."secret_hash" IS 'SHA-256 of the client secret; NULL for public clients, which cannot keep one';
+
+COMMENT ON COLUMN "oauth_clients"."scopes" IS 'the most the client may ask for';
+
+COMMENT ON COLUMN "oauth_authorization_codes"."code_hash" IS 'SHA-256 of the single-use code; the code itself is never stored';
+
+COMMENT ON COLUMN "oauth_authorization_codes"."username" IS 'user who consented';
+
+COMMENT ON COLUMN "oauth_authorization_codes"."code_challenge" IS 'PKCE S256 challenge the token request must answer';
+
+COMMENT ON COLUMN "oauth_tokens"."token_hash" IS 'SHA-256 of the access token; the token itself is never stored';
+
+COMMENT ON COLUMN "oauth_tokens"."username" IS 'user the token acts for';
@@ -0,0 +1,23 @@
+CREATE TABLE "account_holders" (
+  "account_id" bigint NOT NULL,
+  "username" varchar NOT NULL,
+  "role" varchar NOT NULL CHECK ("role" IN ('primary', 'joint', 'viewer')),
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "username")
+);
+
+CREATE INDEX ON "account_holders" ("username");
+
+-- Every account has exactly one primary holder: its owner.
+CREATE UNIQUE INDEX ON "account_holders" ("account_id") WHERE "role" = 'primary';
+
+ALTER TABLE "account_holders" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "account_holders" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+INSERT INTO "account_holders" ("account_id", "username", "role", "created_at")
+SELECT "id", "owner", 'primary', "created_at" FROM "accounts";
+
+COMMENT ON COLUMN "accounts"."owner" IS 'the primary holder; account_holders lists everyone who holds the account';
+
+COMMENT ON COLUMN "account_holders"."role" IS 'primary, joint or viewer; joint holders can also send money, viewers can only see the account';
@@ -0,0 +1,3 @@
+ALTER TABLE "outbox" ADD COLUMN "next_attempt_at" timestamptz;
+
+COMMENT ON COLUMN "outbox"."next_attempt_at" IS 'when the relay may retry a failed event; NULL until it first fails';
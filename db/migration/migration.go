@@ -0,0 +1,143 @@
+// Package migration embeds the SQL migrations in this directory and applies
+// them with golang-migrate, so the binary can manage its own schema.
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Status describes the schema version of a database.
+type Status struct {
+	Version uint
+	Dirty   bool
+	Latest  uint
+	Pending []uint
+}
+
+// Migrator applies the embedded migrations to one database.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// NewMigrator connects to the Postgres database at dbSource.
+func NewMigrator(dbSource string) (*Migrator, error) {
+	src, err := iofs.New(files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read embedded migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, dbSource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create migrator: %w", err)
+	}
+	m.Log = logger{}
+
+	return &Migrator{m: m}, nil
+}
+
+// Up applies the next steps migrations, or all pending ones if steps is 0.
+// It is not an error if there is nothing to apply.
+func (migrator *Migrator) Up(steps int) error {
+	if steps == 0 {
+		return ignoreNoChange(migrator.m.Up())
+	}
+	return ignoreNoChange(migrator.m.Steps(steps))
+}
+
+// Down rolls back the last steps migrations.
+func (migrator *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("invalid number of steps %d", steps)
+	}
+	return ignoreNoChange(migrator.m.Steps(-steps))
+}
+
+// Goto migrates up or down to version.
+func (migrator *Migrator) Goto(version uint) error {
+	return ignoreNoChange(migrator.m.Migrate(version))
+}
+
+// Status reports the applied version and the embedded migrations not yet applied.
+func (migrator *Migrator) Status() (Status, error) {
+	var status Status
+
+	version, dirty, err := migrator.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return status, err
+	}
+	status.Version = version
+	status.Dirty = dirty
+
+	versions, err := Versions()
+	if err != nil {
+		return status, err
+	}
+	for _, v := range versions {
+		if v > status.Version {
+			status.Pending = append(status.Pending, v)
+		}
+	}
+	if len(versions) > 0 {
+		status.Latest = versions[len(versions)-1]
+	}
+
+	return status, nil
+}
+
+// Close releases the database connection.
+func (migrator *Migrator) Close() error {
+	srcErr, dbErr := migrator.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+// Versions lists the embedded migration versions in ascending order.
+func Versions() ([]uint, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []uint
+	for _, entry := range entries {
+		m, err := source.DefaultParse(entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file name %q: %w", entry.Name(), err)
+		}
+		if m.Direction == source.Up {
+			versions = append(versions, m.Version)
+		}
+	}
+
+	return versions, nil
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// logger forwards golang-migrate progress messages to slog.
+type logger struct{}
+
+func (logger) Printf(format string, v ...any) {
+	slog.Info(strings.TrimSpace(fmt.Sprintf(format, v...)), "component", "migrate")
+}
+
+func (logger) Verbose() bool {
+	return false
+}
@@ -0,0 +1,27 @@
+package migration
+
+import (
+	"testing"
+
+	db "simple_bank/db/sqlc"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersions(t *testing.T) {
+	versions, err := Versions()
+	require.NoError(t, err)
+	require.NotEmpty(t, versions)
+
+	for i, version := range versions {
+		require.Equal(t, uint(i+1), version, "migrations must be numbered without gaps")
+	}
+}
+
+// TestSchemaVersion fails when a migration is added without bumping
+// db.SchemaVersion, which /readyz compares against the database.
+func TestSchemaVersion(t *testing.T) {
+	versions, err := Versions()
+	require.NoError(t, err)
+	require.Equal(t, uint(db.SchemaVersion), versions[len(versions)-1])
+}
@@ -17,9 +17,11 @@ WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE;
 
 -- name: ListAccounts :many
-SELECT * FROM accounts
-WHERE owner = $1
-ORDER BY id
+-- Lists the accounts username holds, in any role.
+SELECT accounts.* FROM accounts
+JOIN account_holders ON account_holders.account_id = accounts.id
+WHERE account_holders.username = $1
+ORDER BY accounts.id
 LIMIT $2
 OFFSET $3;
 
@@ -37,4 +39,13 @@ RETURNING *;
 
 -- name: DeleteAccount :exec
 DELETE FROM accounts
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: NotifyAccountUpdate :exec
+SELECT pg_notify('account_updates', sqlc.arg(payload)::text);
+
+-- name: SetAccountStatus :one
+UPDATE accounts
+SET status = sqlc.arg(status)
+WHERE id = sqlc.arg(id)
+RETURNING *;
@@ -0,0 +1,24 @@
+-- name: CreateAccountHolder :one
+INSERT INTO account_holders (
+  account_id,
+  username,
+  role
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetAccountHolder :one
+SELECT * FROM account_holders
+WHERE account_id = $1 AND username = $2 LIMIT 1;
+
+-- name: ListAccountHolders :many
+SELECT * FROM account_holders
+WHERE account_id = $1
+ORDER BY created_at, username;
+
+-- name: DeleteAccountHolder :one
+-- Fails with ErrRecordNotFound if username does not hold the account or is
+-- its primary holder, who cannot be removed.
+DELETE FROM account_holders
+WHERE account_id = $1 AND username = $2 AND role <> 'primary'
+RETURNING *;
@@ -0,0 +1,36 @@
+-- name: CreateApiKey :one
+INSERT INTO api_keys (
+  owner,
+  name,
+  prefix,
+  secret_hash,
+  scopes,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetApiKeyByPrefix :one
+SELECT * FROM api_keys
+WHERE prefix = $1 LIMIT 1;
+
+-- name: ListApiKeys :many
+SELECT * FROM api_keys
+WHERE owner = $1
+ORDER BY id;
+
+-- name: RevokeApiKey :one
+-- Revokes a key of owner. Fails with ErrRecordNotFound if owner has no
+-- such key, or it is already revoked.
+UPDATE api_keys
+SET revoked_at = now()
+WHERE id = sqlc.arg(id) AND owner = sqlc.arg(owner) AND revoked_at IS NULL
+RETURNING *;
+
+-- name: TouchApiKey :exec
+-- Records that a key was used. It is written at most once a minute, so
+-- that busy keys do not cost a write per request.
+UPDATE api_keys
+SET last_used_at = now()
+WHERE id = $1
+  AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
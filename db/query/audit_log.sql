@@ -0,0 +1,33 @@
+-- name: LockAuditLog :exec
+-- Serializes appends so the hash chain stays linear. The lock is held until
+-- the transaction ends, so take it last, after any row locks.
+SELECT pg_advisory_xact_lock(hashtext('audit_log'));
+
+-- name: GetLastAuditLogHash :one
+SELECT hash FROM audit_log
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: CreateAuditLog :one
+INSERT INTO audit_log (
+  actor, action, target, request_id, ip, before, after, prev_hash, hash, created_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING *;
+
+-- name: ListAuditLog :many
+-- Newest first. Each filter is skipped when NULL.
+SELECT * FROM audit_log
+WHERE (sqlc.narg(actor)::varchar IS NULL OR actor = sqlc.narg(actor))
+  AND (sqlc.narg(action)::varchar IS NULL OR action = sqlc.narg(action))
+  AND (sqlc.narg(target)::varchar IS NULL OR target = sqlc.narg(target))
+ORDER BY id DESC
+LIMIT sqlc.arg(page_size)
+OFFSET sqlc.arg(page_offset);
+
+-- name: ListAuditLogAfter :many
+-- Pages through the log in chain order.
+SELECT * FROM audit_log
+WHERE id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(page_size);
@@ -0,0 +1,20 @@
+-- name: CreateCurrency :one
+INSERT INTO currencies (
+  code, enabled
+) VALUES (
+  $1, $2
+) RETURNING *;
+
+-- name: GetCurrency :one
+SELECT * FROM currencies
+WHERE code = $1 LIMIT 1;
+
+-- name: ListCurrencies :many
+SELECT * FROM currencies
+ORDER BY code;
+
+-- name: UpdateCurrency :one
+UPDATE currencies
+SET enabled = sqlc.arg(enabled)
+WHERE code = sqlc.arg(code)
+RETURNING *;
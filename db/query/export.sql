@@ -0,0 +1,24 @@
+-- name: ExportAccounts :many
+SELECT * FROM accounts
+WHERE id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(page_size);
+
+-- name: ExportEntries :many
+SELECT * FROM entries
+WHERE id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(page_size);
+
+-- name: ExportTransfers :many
+SELECT * FROM transfers
+WHERE id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(page_size);
+
+-- name: ExportUsers :many
+-- Leaves out the password hash.
+SELECT username, full_name, email, password_changed_at, created_at FROM users
+WHERE username > sqlc.arg(after_username)
+ORDER BY username
+LIMIT sqlc.arg(page_size);
@@ -0,0 +1,29 @@
+-- name: ListLedgerMismatches :many
+-- Lists accounts whose balance differs from the sum of their entries or
+-- from the net amount of the transfers into and out of them.
+SELECT
+  a.id,
+  a.currency,
+  a.balance,
+  COALESCE(e.total, 0)::bigint AS entries_total,
+  (COALESCE(t_in.total, 0) - COALESCE(t_out.total, 0))::bigint AS transfers_net
+FROM accounts a
+LEFT JOIN (
+  SELECT account_id, SUM(amount) AS total FROM entries GROUP BY account_id
+) e ON e.account_id = a.id
+LEFT JOIN (
+  SELECT to_account_id, SUM(amount) AS total FROM transfers GROUP BY to_account_id
+) t_in ON t_in.to_account_id = a.id
+LEFT JOIN (
+  SELECT from_account_id, SUM(amount) AS total FROM transfers GROUP BY from_account_id
+) t_out ON t_out.from_account_id = a.id
+WHERE a.balance <> COALESCE(e.total, 0)
+   OR a.balance <> COALESCE(t_in.total, 0) - COALESCE(t_out.total, 0)
+ORDER BY a.id;
+
+-- name: GetLedgerTotals :one
+SELECT
+  (SELECT COUNT(*) FROM accounts) AS account_count,
+  (SELECT COUNT(*) FROM entries) AS entry_count,
+  (SELECT COALESCE(SUM(amount), 0) FROM entries)::bigint AS entry_sum,
+  (SELECT COUNT(*) FROM transfers) AS transfer_count;
@@ -0,0 +1,29 @@
+-- name: ListLoginLockouts :many
+-- Returns the keys among keys that are locked out right now.
+SELECT * FROM login_attempts
+WHERE key = ANY(sqlc.arg(keys)::varchar[]) AND locked_until > now()
+ORDER BY locked_until DESC;
+
+-- name: RecordLoginFailure :one
+-- Counts a failed login for key, starting over if the last failure was
+-- before reset_before.
+INSERT INTO login_attempts AS a (
+  key,
+  failures
+) VALUES (
+  sqlc.arg(key), 1
+)
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE WHEN a.last_failed_at < sqlc.arg(reset_before) THEN 1 ELSE a.failures + 1 END,
+    last_failed_at = now()
+RETURNING *;
+
+-- name: LockLogin :one
+UPDATE login_attempts
+SET locked_until = sqlc.arg(locked_until)
+WHERE key = sqlc.arg(key)
+RETURNING *;
+
+-- name: DeleteLoginAttempt :execrows
+DELETE FROM login_attempts
+WHERE key = $1;
@@ -0,0 +1,52 @@
+-- name: CreateOauthClient :one
+INSERT INTO oauth_clients (
+  id,
+  owner,
+  name,
+  secret_hash,
+  redirect_uris,
+  scopes
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetOauthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1 LIMIT 1;
+
+-- name: CreateOauthAuthorizationCode :one
+INSERT INTO oauth_authorization_codes (
+  code_hash,
+  client_id,
+  username,
+  redirect_uri,
+  scopes,
+  code_challenge,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: UseOauthAuthorizationCode :one
+-- Marks an unexpired code used, so that it can only be exchanged once.
+-- Fails with ErrRecordNotFound if there is no such code, or it was used
+-- or has expired.
+UPDATE oauth_authorization_codes
+SET used_at = now()
+WHERE code_hash = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING *;
+
+-- name: CreateOauthToken :one
+INSERT INTO oauth_tokens (
+  token_hash,
+  client_id,
+  username,
+  scopes,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetOauthToken :one
+SELECT * FROM oauth_tokens
+WHERE token_hash = $1 LIMIT 1;
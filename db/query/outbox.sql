@@ -0,0 +1,28 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox (
+  event_type,
+  account_ids,
+  payload
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: ListPendingOutboxEvents :many
+SELECT * FROM outbox
+WHERE published_at IS NULL
+ORDER BY id
+LIMIT $1;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox
+SET published_at = now(),
+    attempts = attempts + 1,
+    last_error = NULL
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox
+SET attempts = attempts + 1,
+    last_error = sqlc.arg(last_error)::varchar,
+    next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id = sqlc.arg(id);
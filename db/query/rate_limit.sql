@@ -0,0 +1,21 @@
+-- name: TakeRateLimitToken :one
+-- Refills the bucket for the time since its last update, capped at burst,
+-- and takes one token if at least one is available.
+INSERT INTO rate_limit_buckets AS b (
+  key,
+  tokens,
+  allowed,
+  updated_at
+) VALUES (
+  sqlc.arg(key), sqlc.arg(burst)::float8 - 1, true, now()
+)
+ON CONFLICT (key) DO UPDATE
+SET tokens = LEAST(sqlc.arg(burst)::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::float8 * sqlc.arg(rate)::float8)
+      - CASE WHEN LEAST(sqlc.arg(burst)::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::float8 * sqlc.arg(rate)::float8) >= 1 THEN 1 ELSE 0 END,
+    allowed = LEAST(sqlc.arg(burst)::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::float8 * sqlc.arg(rate)::float8) >= 1,
+    updated_at = now()
+RETURNING *;
+
+-- name: DeleteRateLimitBucketsBefore :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < sqlc.arg(before);
@@ -0,0 +1,48 @@
+-- name: CreateTotpSecret :one
+-- Starts enrollment, replacing a secret that was never confirmed. Returns no
+-- row if the user already has a confirmed secret.
+INSERT INTO totp_secrets (
+  username,
+  secret_encrypted
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (username) DO UPDATE
+SET secret_encrypted = EXCLUDED.secret_encrypted,
+    last_used_step = 0,
+    created_at = now()
+WHERE totp_secrets.confirmed_at IS NULL
+RETURNING *;
+
+-- name: GetTotpSecret :one
+SELECT * FROM totp_secrets
+WHERE username = $1 LIMIT 1;
+
+-- name: ConfirmTotpSecret :one
+UPDATE totp_secrets
+SET confirmed_at = now(),
+    last_used_step = $2
+WHERE username = $1 AND confirmed_at IS NULL
+RETURNING *;
+
+-- name: UseTotpStep :one
+-- Records the time step of an accepted code. Returns no row if a code of
+-- that step or a later one was already used.
+UPDATE totp_secrets
+SET last_used_step = $2
+WHERE username = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2
+RETURNING *;
+
+-- name: CreateTotpRecoveryCode :one
+INSERT INTO totp_recovery_codes (
+  username,
+  code_hash
+) VALUES (
+  $1, $2
+) RETURNING *;
+
+-- name: UseTotpRecoveryCode :one
+UPDATE totp_recovery_codes
+SET used_at = now()
+WHERE username = $1 AND code_hash = $2 AND used_at IS NULL
+RETURNING *;
@@ -0,0 +1,10 @@
+-- name: CreateTransferReversal :one
+INSERT INTO transfer_reversals (
+  transfer_id, reversal_id, reversed_by
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetTransferReversal :one
+SELECT * FROM transfer_reversals
+WHERE transfer_id = $1 LIMIT 1;
@@ -3,11 +3,40 @@ INSERT INTO users (
   username,
   password,
   full_name,
-  email
+  email,
+  is_email_verified
 ) VALUES (
-  $1, $2, $3, $4
+  $1, $2, $3, $4, $5
 ) RETURNING *;
 
 -- name: GetUser :one
 SELECT * FROM users
-WHERE username = $1 LIMIT 1;
\ No newline at end of file
+WHERE username = $1 LIMIT 1;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = sqlc.arg(role)
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET
+  password = sqlc.arg(password),
+  password_changed_at = sqlc.arg(password_changed_at)
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: UpdateUserEmailVerified :one
+UPDATE users
+SET is_email_verified = true
+WHERE username = $1
+RETURNING *;
+
+-- name: GetUserAuthState :one
+SELECT role, password_changed_at FROM users
+WHERE username = $1 LIMIT 1;
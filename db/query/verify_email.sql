@@ -0,0 +1,19 @@
+-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+  username,
+  email,
+  secret_code_hash,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: UseVerifyEmail :one
+-- Matches only an unused, unexpired row, so a code works once.
+UPDATE verify_emails
+SET is_used = true
+WHERE id = sqlc.arg(id)
+  AND secret_code_hash = sqlc.arg(secret_code_hash)
+  AND NOT is_used
+  AND expires_at > now()
+RETURNING *;
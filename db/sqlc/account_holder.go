@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+)
+
+// AddAccountHolderTx lets another user hold an account and records that in
+// the audit log. It fails with a unique violation if they already hold it.
+func (store *SQLStore) AddAccountHolderTx(ctx context.Context, arg CreateAccountHolderParams) (AccountHolder, error) {
+	var holder AccountHolder
+
+	err := store.execTx(ctx, "AddAccountHolderTx", func(ctx context.Context, q *Queries) error {
+		var err error
+		holder, err = q.CreateAccountHolder(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAudit(ctx, q, AppendAuditLogParams{
+			Action: AuditHolderAdd,
+			Target: AccountTarget(holder.AccountID),
+			After:  holder,
+		})
+		return err
+	})
+
+	return holder, err
+}
+
+// RemoveAccountHolderTx stops a user holding an account and records that in
+// the audit log. It fails with ErrRecordNotFound if they do not hold it or
+// are its primary holder.
+func (store *SQLStore) RemoveAccountHolderTx(ctx context.Context, arg DeleteAccountHolderParams) (AccountHolder, error) {
+	var holder AccountHolder
+
+	err := store.execTx(ctx, "RemoveAccountHolderTx", func(ctx context.Context, q *Queries) error {
+		var err error
+		holder, err = q.DeleteAccountHolder(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAudit(ctx, q, AppendAuditLogParams{
+			Action: AuditHolderRemove,
+			Target: AccountTarget(holder.AccountID),
+			Before: holder,
+		})
+		return err
+	})
+
+	return holder, err
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"simple_bank/util"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountHolderTx(t *testing.T) {
+	store := NewStore(testDB)
+	owner := createRandomUser(t)
+	joint := createRandomUser(t)
+	account := createRandomAccount(t, owner.Username, util.RandomCurrency())
+
+	holder, err := store.AddAccountHolderTx(context.Background(), CreateAccountHolderParams{
+		AccountID: account.ID,
+		Username:  joint.Username,
+		Role:      util.JointHolder,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.JointHolder, holder.Role)
+
+	// A user holds an account at most once.
+	_, err = store.AddAccountHolderTx(context.Background(), CreateAccountHolderParams{
+		AccountID: account.ID,
+		Username:  joint.Username,
+		Role:      util.ViewerHolder,
+	})
+	require.Equal(t, UniqueViolation, ErrorCode(err))
+
+	holders, err := store.ListAccountHolders(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Len(t, holders, 2)
+	require.Equal(t, owner.Username, holders[0].Username)
+	require.Equal(t, util.PrimaryHolder, holders[0].Role)
+
+	// Joint holders see the account alongside their own.
+	accounts, err := store.ListAccounts(context.Background(), ListAccountsParams{
+		Username: joint.Username,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, account.ID, accounts[0].ID)
+
+	// The primary holder cannot be removed.
+	_, err = store.RemoveAccountHolderTx(context.Background(), DeleteAccountHolderParams{
+		AccountID: account.ID,
+		Username:  owner.Username,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	removed, err := store.RemoveAccountHolderTx(context.Background(), DeleteAccountHolderParams{
+		AccountID: account.ID,
+		Username:  joint.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, joint.Username, removed.Username)
+
+	rows, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Target:   pgtype.Text{String: AccountTarget(account.ID), Valid: true},
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, AuditHolderRemove, rows[0].Action)
+	require.Equal(t, AuditHolderAdd, rows[1].Action)
+}
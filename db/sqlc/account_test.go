@@ -83,9 +83,9 @@ func TestListAccounts(t *testing.T) {
 		}
 
 		listed, err := q.ListAccounts(context.Background(), ListAccountsParams{
-			Owner:  owner,
-			Limit:  10,
-			Offset: 0,
+			Username: owner,
+			Limit:    10,
+			Offset:   0,
 		})
 		require.NoError(t, err)
 		require.Len(t, listed, n)
@@ -97,9 +97,9 @@ func TestListAccounts(t *testing.T) {
 
 	runTestWithTransaction(t, func(t *testing.T, q *Queries) {
 		listed, err := q.ListAccounts(context.Background(), ListAccountsParams{
-			Owner:  "nonexistent_owner_xyz",
-			Limit:  10,
-			Offset: 0,
+			Username: "nonexistent_owner_xyz",
+			Limit:    10,
+			Offset:   0,
 		})
 		require.NoError(t, err)
 		require.Empty(t, listed)
@@ -189,5 +189,12 @@ func createAccountInTx(t *testing.T, q *Queries, owner string, currency string)
 	account, err := q.CreateAccount(context.Background(), arg)
 	require.NoError(t, err)
 	require.NotEmpty(t, account)
+
+	_, err = q.CreateAccountHolder(context.Background(), CreateAccountHolderParams{
+		AccountID: account.ID,
+		Username:  owner,
+		Role:      util.PrimaryHolder,
+	})
+	require.NoError(t, err)
 	return account
 }
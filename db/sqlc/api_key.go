@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// apiKeySnapshot is what the audit log records about an API key; the
+// secret stays out of the log.
+type apiKeySnapshot struct {
+	Owner     string     `json:"owner"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func newAPIKeySnapshot(key ApiKey) apiKeySnapshot {
+	snapshot := apiKeySnapshot{
+		Owner:  key.Owner,
+		Name:   key.Name,
+		Prefix: key.Prefix,
+		Scopes: key.Scopes,
+	}
+	if key.ExpiresAt.Valid {
+		snapshot.ExpiresAt = &key.ExpiresAt.Time
+	}
+	if key.RevokedAt.Valid {
+		snapshot.RevokedAt = &key.RevokedAt.Time
+	}
+	return snapshot
+}
+
+// CreateAPIKeyTx creates an API key and records it in the audit log.
+func (store *SQLStore) CreateAPIKeyTx(ctx context.Context, arg CreateApiKeyParams) (ApiKey, error) {
+	var key ApiKey
+
+	err := store.execTx(ctx, "CreateAPIKeyTx", func(ctx context.Context, q *Queries) error {
+		var err error
+		key, err = q.CreateApiKey(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAudit(ctx, q, AppendAuditLogParams{
+			Action: AuditAPIKeyCreate,
+			Target: APIKeyTarget(key.ID),
+			After:  newAPIKeySnapshot(key),
+		})
+		return err
+	})
+
+	return key, err
+}
+
+// RevokeAPIKeyTx revokes an API key of arg.Owner and records that in the
+// audit log. It fails with ErrRecordNotFound if the owner has no such key,
+// or it is already revoked.
+func (store *SQLStore) RevokeAPIKeyTx(ctx context.Context, arg RevokeApiKeyParams) (ApiKey, error) {
+	var key ApiKey
+
+	err := store.execTx(ctx, "RevokeAPIKeyTx", func(ctx context.Context, q *Queries) error {
+		var err error
+		key, err = q.RevokeApiKey(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAudit(ctx, q, AppendAuditLogParams{
+			Action: AuditAPIKeyRevoke,
+			Target: APIKeyTarget(key.ID),
+			After:  newAPIKeySnapshot(key),
+		})
+		return err
+	})
+
+	return key, err
+}
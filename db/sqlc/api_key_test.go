@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple_bank/util"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAPIKey(t *testing.T, store Store, owner string) ApiKey {
+	arg := CreateApiKeyParams{
+		Owner:      owner,
+		Name:       util.RandomOwner(),
+		Prefix:     util.RandomString(12),
+		SecretHash: util.HashSecret(util.RandomString(32)),
+		Scopes:     []string{util.ScopeAccountsRead},
+		ExpiresAt:  pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+
+	key, err := store.CreateAPIKeyTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Owner, key.Owner)
+	require.Equal(t, arg.Prefix, key.Prefix)
+	require.Equal(t, arg.SecretHash, key.SecretHash)
+	require.Equal(t, arg.Scopes, key.Scopes)
+	require.False(t, key.RevokedAt.Valid)
+	require.False(t, key.LastUsedAt.Valid)
+	return key
+}
+
+func TestCreateAPIKeyTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	key := createRandomAPIKey(t, store, user.Username)
+
+	got, err := store.GetApiKeyByPrefix(context.Background(), key.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, got.ID)
+
+	rows, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Target:   pgtype.Text{String: APIKeyTarget(key.ID), Valid: true},
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, AuditAPIKeyCreate, rows[0].Action)
+	require.NotContains(t, string(rows[0].After), "secret_hash")
+}
+
+func TestTouchApiKey(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	key := createRandomAPIKey(t, store, user.Username)
+
+	err := testQueries.TouchApiKey(context.Background(), key.ID)
+	require.NoError(t, err)
+	touched, err := testQueries.GetApiKeyByPrefix(context.Background(), key.Prefix)
+	require.NoError(t, err)
+	require.True(t, touched.LastUsedAt.Valid)
+
+	// A second use within the minute is not written.
+	err = testQueries.TouchApiKey(context.Background(), key.ID)
+	require.NoError(t, err)
+	again, err := testQueries.GetApiKeyByPrefix(context.Background(), key.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, touched.LastUsedAt.Time, again.LastUsedAt.Time)
+}
+
+func TestRevokeAPIKeyTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	other := createRandomUser(t)
+	key := createRandomAPIKey(t, store, user.Username)
+
+	// Only the owner can revoke a key.
+	_, err := store.RevokeAPIKeyTx(context.Background(), RevokeApiKeyParams{ID: key.ID, Owner: other.Username})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	revoked, err := store.RevokeAPIKeyTx(context.Background(), RevokeApiKeyParams{ID: key.ID, Owner: user.Username})
+	require.NoError(t, err)
+	require.True(t, revoked.RevokedAt.Valid)
+
+	_, err = store.RevokeAPIKeyTx(context.Background(), RevokeApiKeyParams{ID: key.ID, Owner: user.Username})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	keys, err := testQueries.ListApiKeys(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.True(t, keys[0].RevokedAt.Valid)
+}
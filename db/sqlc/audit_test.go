@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"simple_bank/util"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAccountStatusTxAudit(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t, createRandomUser(t).Username, util.USD)
+
+	ctx := WithAuditActor(context.Background(), AuditActor{
+		Username:  util.RandomOwner(),
+		RequestID: util.RandomString(12),
+		IP:        "10.0.0.1",
+	})
+	frozen, err := store.SetAccountStatusTx(ctx, SetAccountStatusParams{Status: AccountFrozen, ID: account.ID})
+	require.NoError(t, err)
+	require.Equal(t, AccountFrozen, frozen.Status)
+
+	rows, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Target:   pgtype.Text{String: AccountTarget(account.ID), Valid: true},
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+
+	row := rows[0]
+	actor := AuditActorFrom(ctx)
+	require.Equal(t, AuditAccountFreeze, row.Action)
+	require.Equal(t, actor.Username, row.Actor)
+	require.Equal(t, actor.RequestID, row.RequestID)
+	require.Equal(t, actor.IP, row.Ip)
+	require.Equal(t, HashAuditLog(row), row.Hash)
+
+	var before, after Account
+	require.NoError(t, json.Unmarshal(row.Before, &before))
+	require.NoError(t, json.Unmarshal(row.After, &after))
+	require.Equal(t, AccountActive, before.Status)
+	require.Equal(t, AccountFrozen, after.Status)
+}
+
+func TestAppendAuditLogChain(t *testing.T) {
+	store := NewStore(testDB)
+	target := UserTarget(util.RandomOwner())
+
+	first, err := store.AppendAuditLog(context.Background(), AppendAuditLogParams{Action: AuditUserLogin, Target: target})
+	require.NoError(t, err)
+	second, err := store.AppendAuditLog(context.Background(), AppendAuditLogParams{Action: AuditUserLogin, Target: target})
+	require.NoError(t, err)
+
+	require.Equal(t, SystemActor, first.Actor)
+	require.Nil(t, first.Before)
+	require.Equal(t, first.Hash, second.PrevHash)
+
+	rows, err := store.ListAuditLogAfter(context.Background(), ListAuditLogAfterParams{AfterID: first.ID - 1, PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	for _, row := range rows {
+		require.Equal(t, HashAuditLog(row), row.Hash)
+	}
+}
+
+func TestAuditLogAppendOnly(t *testing.T) {
+	store := NewStore(testDB)
+	row, err := store.AppendAuditLog(context.Background(), AppendAuditLogParams{
+		Action: AuditUserLogin,
+		Target: UserTarget(util.RandomOwner()),
+	})
+	require.NoError(t, err)
+
+	_, err = testDB.Exec(context.Background(), `UPDATE audit_log SET actor = 'mallory' WHERE id = $1`, row.ID)
+	require.ErrorContains(t, err, "append-only")
+
+	_, err = testDB.Exec(context.Background(), `DELETE FROM audit_log WHERE id = $1`, row.ID)
+	require.ErrorContains(t, err, "append-only")
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"simple_bank/util"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListCurrencies(t *testing.T) {
+	currencies, err := testQueries.ListCurrencies(context.Background())
+	require.NoError(t, err)
+
+	enabled := map[string]bool{}
+	for _, currency := range currencies {
+		enabled[currency.Code] = currency.Enabled
+	}
+	require.True(t, enabled[util.USD])
+	require.True(t, enabled[util.EUR])
+	require.Contains(t, enabled, util.CAD)
+}
+
+func TestCurrencyLifecycle(t *testing.T) {
+	runTestWithTransaction(t, func(t *testing.T, q *Queries) {
+		created, err := q.CreateCurrency(context.Background(), CreateCurrencyParams{
+			Code:    "GBP",
+			Enabled: true,
+		})
+		require.NoError(t, err)
+		require.True(t, created.Enabled)
+		require.NotZero(t, created.CreatedAt)
+
+		updated, err := q.UpdateCurrency(context.Background(), UpdateCurrencyParams{
+			Enabled: false,
+			Code:    "GBP",
+		})
+		require.NoError(t, err)
+		require.False(t, updated.Enabled)
+
+		got, err := q.GetCurrency(context.Background(), "GBP")
+		require.NoError(t, err)
+		require.Equal(t, updated, got)
+	})
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres error codes the API maps to client errors.
+const (
+	ForeignKeyViolation = "23503"
+	UniqueViolation     = "23505"
+)
+
+// Postgres error codes after which a transaction can safely be retried.
+const (
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
+)
+
+// ErrRecordNotFound is returned by :one queries that match no row.
+var ErrRecordNotFound = pgx.ErrNoRows
+
+// Errors returned by the store transactions when the state of an account or
+// currency forbids the operation.
+var (
+	ErrAccountFrozen    = errors.New("account is frozen")
+	ErrCurrencyDisabled = errors.New("currency is not enabled")
+	ErrEmailNotVerified = errors.New("email address is not verified")
+)
+
+// ErrResetTokenInvalid is returned by ResetPasswordTx for a password reset
+// token that is unknown, used, expired or older than the last password change.
+var ErrResetTokenInvalid = errors.New("password reset token is invalid or expired")
+
+// ErrVerifyEmailInvalid is returned by VerifyEmailTx for an email
+// verification that is unknown, used or expired, or whose code is wrong.
+var ErrVerifyEmailInvalid = errors.New("email verification code is invalid or expired")
+
+// ErrTOTPAlreadyEnabled is returned by EnableTOTPTx for a user whose TOTP
+// secret is already confirmed.
+var ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+// ErrorCode returns the Postgres error code of err, or "" if err is not a Postgres error.
+func ErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"simple_bank/util"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestExportAccounts tests keyset pagination over accounts.
+func TestExportAccounts(t *testing.T) {
+	runTestWithTransaction(t, func(t *testing.T, q *Queries) {
+		user := createRandomUser(t)
+		first := createAccountInTx(t, q, user.Username, util.USD)
+		second := createAccountInTx(t, q, user.Username, util.EUR)
+
+		accounts, err := q.ExportAccounts(context.Background(), ExportAccountsParams{
+			AfterID:  first.ID - 1,
+			PageSize: 1,
+		})
+		require.NoError(t, err)
+		require.Len(t, accounts, 1)
+		require.Equal(t, first, accounts[0])
+
+		accounts, err = q.ExportAccounts(context.Background(), ExportAccountsParams{
+			AfterID:  first.ID,
+			PageSize: 1,
+		})
+		require.NoError(t, err)
+		require.Len(t, accounts, 1)
+		require.Equal(t, second, accounts[0])
+	})
+}
+
+// TestExportUsers tests that users are paged by username.
+func TestExportUsers(t *testing.T) {
+	user := createRandomUser(t)
+
+	users, err := testQueries.ExportUsers(context.Background(), ExportUsersParams{
+		AfterUsername: user.Username[:len(user.Username)-1],
+		PageSize:      100,
+	})
+	require.NoError(t, err)
+
+	var found bool
+	for i, u := range users {
+		if i > 0 {
+			require.Less(t, users[i-1].Username, u.Username)
+		}
+		if u.Username == user.Username {
+			require.Equal(t, user.Email, u.Email)
+			found = true
+		}
+	}
+	require.True(t, found)
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// SchemaVersion is the migration version this code expects the database to
+// be at. Bump it together with every new file in db/migration;
+// TestSchemaVersion there fails otherwise.
+const SchemaVersion = 15
+
+// PoolStats is a snapshot of the connection pool.
+type PoolStats struct {
+	TotalConns           int32         `json:"total_conns"`
+	IdleConns            int32         `json:"idle_conns"`
+	AcquiredConns        int32         `json:"acquired_conns"`
+	ConstructingConns    int32         `json:"constructing_conns"`
+	MaxConns             int32         `json:"max_conns"`
+	AcquireCount         int64         `json:"acquire_count"`
+	EmptyAcquireCount    int64         `json:"empty_acquire_count"`
+	CanceledAcquireCount int64         `json:"canceled_acquire_count"`
+	AcquireDuration      time.Duration `json:"acquire_duration"`
+}
+
+// Ping checks that a connection can be acquired and the database responds.
+func (store *SQLStore) Ping(ctx context.Context) error {
+	return store.db.Ping(ctx)
+}
+
+// PoolStats returns the current connection pool statistics.
+func (store *SQLStore) PoolStats() PoolStats {
+	stat := store.db.Stat()
+	return PoolStats{
+		TotalConns:           stat.TotalConns(),
+		IdleConns:            stat.IdleConns(),
+		AcquiredConns:        stat.AcquiredConns(),
+		ConstructingConns:    stat.ConstructingConns(),
+		MaxConns:             stat.MaxConns(),
+		AcquireCount:         stat.AcquireCount(),
+		EmptyAcquireCount:    stat.EmptyAcquireCount(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		AcquireDuration:      stat.AcquireDuration(),
+	}
+}
+
+// MigrationVersion reads the version golang-migrate recorded in
+// schema_migrations. dirty is true when a migration failed halfway.
+func (store *SQLStore) MigrationVersion(ctx context.Context) (version int64, dirty bool, err error) {
+	err = store.db.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	return
+}
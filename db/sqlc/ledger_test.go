@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"simple_bank/util"
+
+	"github.com/stretchr/testify/require"
+)
+
+func findLedgerMismatch(t *testing.T, q *Queries, accountID int64) (ListLedgerMismatchesRow, bool) {
+	mismatches, err := q.ListLedgerMismatches(context.Background())
+	require.NoError(t, err)
+	for _, m := range mismatches {
+		if m.ID == accountID {
+			return m, true
+		}
+	}
+	return ListLedgerMismatchesRow{}, false
+}
+
+// TestListLedgerMismatches tests that balance changes without entries and
+// transfers are reported.
+func TestListLedgerMismatches(t *testing.T) {
+	runTestWithTransaction(t, func(t *testing.T, q *Queries) {
+		user := createRandomUser(t)
+		account, err := q.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:    user.Username,
+			Balance:  0,
+			Currency: util.USD,
+		})
+		require.NoError(t, err)
+
+		_, found := findLedgerMismatch(t, q, account.ID)
+		require.False(t, found)
+
+		account, err = q.AddAccountBalance(context.Background(), AddAccountBalanceParams{ID: account.ID, Amount: 50})
+		require.NoError(t, err)
+
+		m, found := findLedgerMismatch(t, q, account.ID)
+		require.True(t, found)
+		require.Equal(t, int64(50), m.Balance)
+		require.Zero(t, m.EntriesTotal)
+		require.Zero(t, m.TransfersNet)
+	})
+}
+
+// TestLedgerFundedAccount tests that an account opened by CreateAccountTx
+// and funded by a transfer has no mismatch.
+func TestLedgerFundedAccount(t *testing.T) {
+	store := NewStore(testDB)
+	from := createRandomAccount(t, createRandomUser(t).Username, util.USD)
+
+	account, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   account.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	_, found := findLedgerMismatch(t, testQueries, account.ID)
+	require.False(t, found)
+}
+
+// TestGetLedgerTotals tests that totals count new entries and transfers.
+func TestGetLedgerTotals(t *testing.T) {
+	runTestWithTransaction(t, func(t *testing.T, q *Queries) {
+		before, err := q.GetLedgerTotals(context.Background())
+		require.NoError(t, err)
+
+		user := createRandomUser(t)
+		account := createAccountInTx(t, q, user.Username, util.USD)
+		_, err = q.CreateEntry(context.Background(), CreateEntryParams{AccountID: account.ID, Amount: 10})
+		require.NoError(t, err)
+
+		after, err := q.GetLedgerTotals(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, before.AccountCount+1, after.AccountCount)
+		require.Equal(t, before.EntryCount+1, after.EntryCount)
+		require.Equal(t, before.EntrySum+10, after.EntrySum)
+		require.Equal(t, before.TransferCount, after.TransferCount)
+	})
+}
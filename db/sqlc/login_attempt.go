@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// lockoutSnapshot is what the audit log records when a key is locked out.
+type lockoutSnapshot struct {
+	Failures    int32     `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// RecordLoginFailureTxParams holds the arguments for counting a failed login.
+type RecordLoginFailureTxParams struct {
+	// Key is the UserTarget or IPTarget the failure counts against.
+	Key string
+	// Failures before ResetBefore are forgotten.
+	ResetBefore time.Time
+	// LockFor returns how long to lock Key out after failures consecutive
+	// failed logins, or 0 not to.
+	LockFor func(failures int32) time.Duration
+}
+
+// RecordLoginFailureTx counts a failed login against a key and locks the
+// key out if arg.LockFor says so. Lockouts are recorded in the audit log.
+func (store *SQLStore) RecordLoginFailureTx(ctx context.Context, arg RecordLoginFailureTxParams) (LoginAttempt, error) {
+	var attempt LoginAttempt
+
+	err := store.execTx(ctx, "RecordLoginFailureTx", func(ctx context.Context, q *Queries) error {
+		var err error
+		attempt, err = q.RecordLoginFailure(ctx, RecordLoginFailureParams{
+			Key:         arg.Key,
+			ResetBefore: arg.ResetBefore,
+		})
+		if err != nil {
+			return err
+		}
+
+		lockFor := arg.LockFor(attempt.Failures)
+		if lockFor <= 0 {
+			return nil
+		}
+		attempt, err = q.LockLogin(ctx, LockLoginParams{
+			LockedUntil: pgtype.Timestamptz{Time: time.Now().Add(lockFor), Valid: true},
+			Key:         arg.Key,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAudit(ctx, q, AppendAuditLogParams{
+			Action: AuditLoginLockout,
+			Target: arg.Key,
+			After: lockoutSnapshot{
+				Failures:    attempt.Failures,
+				LockedUntil: attempt.LockedUntil.Time,
+			},
+		})
+		return err
+	})
+
+	return attempt, err
+}
+
+// UnlockLoginTx forgets the failed logins of a key, lifting its lockout,
+// and records that in the audit log. It fails with ErrRecordNotFound if no
+// failures were recorded for key.
+func (store *SQLStore) UnlockLoginTx(ctx context.Context, key string) error {
+	return store.execTx(ctx, "UnlockLoginTx", func(ctx context.Context, q *Queries) error {
+		deleted, err := q.DeleteLoginAttempt(ctx, key)
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return ErrRecordNotFound
+		}
+
+		_, err = recordAudit(ctx, q, AppendAuditLogParams{
+			Action: AuditLoginUnlock,
+			Target: key,
+		})
+		return err
+	})
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple_bank/util"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordLoginFailureTx(t *testing.T) {
+	store := NewStore(testDB)
+	key := UserTarget(util.RandomOwner())
+
+	// Lock out from the third failure on.
+	arg := RecordLoginFailureTxParams{
+		Key:         key,
+		ResetBefore: time.Now().Add(-time.Hour),
+		LockFor: func(failures int32) time.Duration {
+			if failures < 3 {
+				return 0
+			}
+			return time.Minute
+		},
+	}
+
+	for i := int32(1); i <= 3; i++ {
+		attempt, err := store.RecordLoginFailureTx(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, i, attempt.Failures)
+		require.Equal(t, i == 3, attempt.LockedUntil.Valid)
+	}
+
+	lockouts, err := store.ListLoginLockouts(context.Background(), []string{key, IPTarget("192.0.2.1")})
+	require.NoError(t, err)
+	require.Len(t, lockouts, 1)
+	require.Equal(t, key, lockouts[0].Key)
+	require.WithinDuration(t, time.Now().Add(time.Minute), lockouts[0].LockedUntil.Time, 5*time.Second)
+
+	rows, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Target:   pgtype.Text{String: key, Valid: true},
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, AuditLoginLockout, rows[0].Action)
+
+	// Failures older than ResetBefore are forgotten.
+	arg.ResetBefore = time.Now().Add(time.Second)
+	attempt, err := store.RecordLoginFailureTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), attempt.Failures)
+}
+
+func TestUnlockLoginTx(t *testing.T) {
+	store := NewStore(testDB)
+	key := UserTarget(util.RandomOwner())
+
+	err := store.UnlockLoginTx(context.Background(), key)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = store.RecordLoginFailureTx(context.Background(), RecordLoginFailureTxParams{
+		Key:         key,
+		ResetBefore: time.Now().Add(-time.Hour),
+		LockFor:     func(int32) time.Duration { return time.Hour },
+	})
+	require.NoError(t, err)
+
+	err = store.UnlockLoginTx(context.Background(), key)
+	require.NoError(t, err)
+
+	lockouts, err := store.ListLoginLockouts(context.Background(), []string{key})
+	require.NoError(t, err)
+	require.Empty(t, lockouts)
+
+	rows, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Action:   pgtype.Text{String: AuditLoginUnlock, Valid: true},
+		Target:   pgtype.Text{String: key, Valid: true},
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+}
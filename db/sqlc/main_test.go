@@ -94,6 +94,13 @@ func createRandomAccount(t *testing.T, owner string, currency string) Account {
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
 
+	_, err = testQueries.CreateAccountHolder(context.Background(), CreateAccountHolderParams{
+		AccountID: account.ID,
+		Username:  owner,
+		Role:      util.PrimaryHolder,
+	})
+	require.NoError(t, err)
+
 	return account
 }
 
@@ -6,14 +6,69 @@ package db
 
 import (
 	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Account struct {
-	ID        int64     `json:"id"`
+	ID int64 `json:"id"`
+	// the primary holder; account_holders lists everyone who holds the account
 	Owner     string    `json:"owner"`
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// active or frozen; frozen accounts cannot send or receive transfers
+	Status string `json:"status"`
+}
+
+type AccountHolder struct {
+	AccountID int64  `json:"account_id"`
+	Username  string `json:"username"`
+	// primary, joint or viewer; joint holders can also send money, viewers can only see the account
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ApiKey struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	// public part of the key, used to look it up
+	Prefix string `json:"prefix"`
+	// SHA-256 of the secret part of the key; the key itself is never stored
+	SecretHash []byte `json:"secret_hash"`
+	// what the key may do on behalf of owner, such as accounts:read
+	Scopes []string `json:"scopes"`
+	// the key is refused from this time on; NULL never expires
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	RevokedAt pgtype.Timestamptz `json:"revoked_at"`
+	// when the key last authenticated a request, to the minute
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+type AuditLog struct {
+	ID        int64  `json:"id"`
+	Actor     string `json:"actor"`
+	Action    string `json:"action"`
+	Target    string `json:"target"`
+	RequestID string `json:"request_id"`
+	Ip        string `json:"ip"`
+	// kept as json, not jsonb, so the hashed text reads back unchanged
+	Before []byte `json:"before"`
+	After  []byte `json:"after"`
+	// hash of the previous row, empty for the first row
+	PrevHash []byte `json:"prev_hash"`
+	// SHA-256 over prev_hash and the other columns except id
+	Hash      []byte    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Currency struct {
+	Code string `json:"code"`
+	// whether new accounts can be opened in this currency
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Entry struct {
@@ -24,6 +79,108 @@ type Entry struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type LoginAttempt struct {
+	// user:<username> or ip:<address> the failures are counted for
+	Key string `json:"key"`
+	// consecutive failed logins; reset by a successful login or after a quiet period
+	Failures int32 `json:"failures"`
+	// logins for key are refused before this time, without checking the password
+	LockedUntil  pgtype.Timestamptz `json:"locked_until"`
+	LastFailedAt time.Time          `json:"last_failed_at"`
+}
+
+type OauthAuthorizationCode struct {
+	// SHA-256 of the single-use code; the code itself is never stored
+	CodeHash []byte `json:"code_hash"`
+	ClientID string `json:"client_id"`
+	// user who consented
+	Username    string   `json:"username"`
+	RedirectUri string   `json:"redirect_uri"`
+	Scopes      []string `json:"scopes"`
+	// PKCE S256 challenge the token request must answer
+	CodeChallenge string             `json:"code_challenge"`
+	ExpiresAt     time.Time          `json:"expires_at"`
+	UsedAt        pgtype.Timestamptz `json:"used_at"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
+
+type OauthClient struct {
+	ID string `json:"id"`
+	// user who registered the client; client credentials tokens act for them
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	// SHA-256 of the client secret; NULL for public clients, which cannot keep one
+	SecretHash   []byte   `json:"secret_hash"`
+	RedirectUris []string `json:"redirect_uris"`
+	// the most the client may ask for
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OauthToken struct {
+	// SHA-256 of the access token; the token itself is never stored
+	TokenHash []byte `json:"token_hash"`
+	ClientID  string `json:"client_id"`
+	// user the token acts for
+	Username  string    `json:"username"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Outbox struct {
+	ID        int64  `json:"id"`
+	EventType string `json:"event_type"`
+	// accounts whose event stream must stay ordered
+	AccountIds  []int64            `json:"account_ids"`
+	Payload     []byte             `json:"payload"`
+	Attempts    int32              `json:"attempts"`
+	LastError   pgtype.Text        `json:"last_error"`
+	PublishedAt pgtype.Timestamptz `json:"published_at"`
+	CreatedAt   time.Time          `json:"created_at"`
+	// when the relay may retry a failed event; NULL until it first fails
+	NextAttemptAt pgtype.Timestamptz `json:"next_attempt_at"`
+}
+
+type PasswordResetToken struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// SHA-256 of the token sent to the user; the token itself is never stored
+	TokenHash []byte    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// set when the token resets the password; a token works once
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type RateLimitBucket struct {
+	Key    string  `json:"key"`
+	Tokens float64 `json:"tokens"`
+	// whether the last request took a token
+	Allowed   bool      `json:"allowed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type TotpRecoveryCode struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// SHA-256 of a one-time recovery code; the code itself is never stored
+	CodeHash  []byte             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type TotpSecret struct {
+	Username string `json:"username"`
+	// TOTP secret sealed with TOTP_ENCRYPTION_KEY
+	SecretEncrypted []byte `json:"secret_encrypted"`
+	// set once the user proves they can generate codes; until then login does not ask for one
+	ConfirmedAt pgtype.Timestamptz `json:"confirmed_at"`
+	// time step of the last accepted code, so that a code cannot be replayed
+	LastUsedStep int64     `json:"last_used_step"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
 type Transfer struct {
 	ID            int64     `json:"id"`
 	FromAccountID int64     `json:"from_account_id"`
@@ -32,6 +189,14 @@ type Transfer struct {
 	CreatedAt     time.Time `json:"created_at"`
 }
 
+type TransferReversal struct {
+	TransferID int64 `json:"transfer_id"`
+	// the transfer that moved the money back
+	ReversalID int64     `json:"reversal_id"`
+	ReversedBy string    `json:"reversed_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 type User struct {
 	Username          string    `json:"username"`
 	Password          string    `json:"password"`
@@ -39,4 +204,43 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	// customer, support or admin
+	Role string `json:"role"`
+	// transfers from unverified users are rejected
+	IsEmailVerified bool `json:"is_email_verified"`
+}
+
+type VerifyEmail struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	// SHA-256 of the code mailed to the user; the code itself is never stored
+	SecretCodeHash []byte    `json:"secret_code_hash"`
+	IsUsed         bool      `json:"is_used"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID        int64  `json:"id"`
+	WebhookID int64  `json:"webhook_id"`
+	EventID   int64  `json:"event_id"`
+	EventType string `json:"event_type"`
+	Payload   []byte `json:"payload"`
+	// pending, succeeded or failed
+	Status         string    `json:"status"`
+	Attempts       int32     `json:"attempts"`
+	ResponseStatus int32     `json:"response_status"`
+	LastError      string    `json:"last_error"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type Webhook struct {
+	ID         int64     `json:"id"`
+	Owner      string    `json:"owner"`
+	Url        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Secret     string    `json:"secret"`
+	CreatedAt  time.Time `json:"created_at"`
 }
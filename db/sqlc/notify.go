@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// AccountUpdatesChannel is the Postgres NOTIFY channel account updates are published on.
+const AccountUpdatesChannel = "account_updates"
+
+// AccountUpdate is published for every account touched by a transfer once it commits.
+type AccountUpdate struct {
+	AccountID  int64  `json:"account_id"`
+	Balance    int64  `json:"balance"`
+	Currency   string `json:"currency"`
+	TransferID int64  `json:"transfer_id"`
+	Entry      Entry  `json:"entry"`
+}
+
+// publishAccountUpdate queues an AccountUpdate notification using q. Postgres
+// only delivers it to listeners if the surrounding transaction commits.
+func publishAccountUpdate(ctx context.Context, q *Queries, account Account, entry Entry, transferID int64) error {
+	payload, err := json.Marshal(AccountUpdate{
+		AccountID:  account.ID,
+		Balance:    account.Balance,
+		Currency:   account.Currency,
+		TransferID: transferID,
+		Entry:      entry,
+	})
+	if err != nil {
+		return err
+	}
+	return q.NotifyAccountUpdate(ctx, string(payload))
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+)
+
+// oauthClientSnapshot is what the audit log records about a registered
+// client; the secret stays out of the log.
+type oauthClientSnapshot struct {
+	Owner        string   `json:"owner"`
+	Name         string   `json:"name"`
+	Confidential bool     `json:"confidential"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+}
+
+// oauthGrantSnapshot is what the audit log records when a user consents to
+// a client acting for them.
+type oauthGrantSnapshot struct {
+	ClientID string   `json:"client_id"`
+	Scopes   []string `json:"scopes"`
+}
+
+// CreateOAuthClientTx registers an OAuth client and records it in the
+// audit log.
+func (store *SQLStore) CreateOAuthClientTx(ctx context.Context, arg CreateOauthClientParams) (OauthClient, error) {
+	var client OauthClient
+
+	err := store.execTx(ctx, "CreateOAuthClientTx", func(ctx context.Context, q *Queries) error {
+		var err error
+		client, err = q.CreateOauthClient(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAudit(ctx, q, AppendAuditLogParams{
+			Action: AuditOAuthClientCreate,
+			Target: OAuthClientTarget(client.ID),
+			After: oauthClientSnapshot{
+				Owner:        client.Owner,
+				Name:         client.Name,
+				Confidential: client.SecretHash != nil,
+				RedirectURIs: client.RedirectUris,
+				Scopes:       client.Scopes,
+			},
+		})
+		return err
+	})
+
+	return client, err
+}
+
+// GrantOAuthCodeTx stores the authorization code a user's consent produced
+// and records the consent in the audit log.
+func (store *SQLStore) GrantOAuthCodeTx(ctx context.Context, arg CreateOauthAuthorizationCodeParams) (OauthAuthorizationCode, error) {
+	var code OauthAuthorizationCode
+
+	err := store.execTx(ctx, "GrantOAuthCodeTx", func(ctx context.Context, q *Queries) error {
+		var err error
+		code, err = q.CreateOauthAuthorizationCode(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAudit(ctx, q, AppendAuditLogParams{
+			Action: AuditOAuthGrant,
+			Target: UserTarget(code.Username),
+			After: oauthGrantSnapshot{
+				ClientID: code.ClientID,
+				Scopes:   code.Scopes,
+			},
+		})
+		return err
+	})
+
+	return code, err
+}
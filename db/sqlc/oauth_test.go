@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple_bank/util"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomOAuthClient(t *testing.T, store Store, owner string) OauthClient {
+	arg := CreateOauthClientParams{
+		ID:           util.RandomString(16),
+		Owner:        owner,
+		Name:         util.RandomOwner(),
+		SecretHash:   util.HashSecret(util.RandomString(32)),
+		RedirectUris: []string{"https://app.example.com/callback"},
+		Scopes:       []string{util.ScopeAccountsRead},
+	}
+
+	client, err := store.CreateOAuthClientTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, client.ID)
+	require.Equal(t, arg.Owner, client.Owner)
+	require.Equal(t, arg.SecretHash, client.SecretHash)
+	require.Equal(t, arg.RedirectUris, client.RedirectUris)
+	require.Equal(t, arg.Scopes, client.Scopes)
+	return client
+}
+
+func TestCreateOAuthClientTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	client := createRandomOAuthClient(t, store, user.Username)
+
+	got, err := store.GetOauthClient(context.Background(), client.ID)
+	require.NoError(t, err)
+	require.Equal(t, client.Name, got.Name)
+
+	rows, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Target:   pgtype.Text{String: OAuthClientTarget(client.ID), Valid: true},
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, AuditOAuthClientCreate, rows[0].Action)
+	require.NotContains(t, string(rows[0].After), "secret_hash")
+}
+
+func TestUseOauthAuthorizationCode(t *testing.T) {
+	store := NewStore(testDB)
+	developer := createRandomUser(t)
+	user := createRandomUser(t)
+	client := createRandomOAuthClient(t, store, developer.Username)
+
+	grant := func(expiresAt time.Time) []byte {
+		codeHash := util.HashSecret(util.RandomString(32))
+		code, err := store.GrantOAuthCodeTx(context.Background(), CreateOauthAuthorizationCodeParams{
+			CodeHash:      codeHash,
+			ClientID:      client.ID,
+			Username:      user.Username,
+			RedirectUri:   client.RedirectUris[0],
+			Scopes:        client.Scopes,
+			CodeChallenge: util.RandomString(43),
+			ExpiresAt:     expiresAt,
+		})
+		require.NoError(t, err)
+		require.False(t, code.UsedAt.Valid)
+		return codeHash
+	}
+
+	codeHash := grant(time.Now().Add(time.Minute))
+	used, err := store.UseOauthAuthorizationCode(context.Background(), codeHash)
+	require.NoError(t, err)
+	require.True(t, used.UsedAt.Valid)
+	require.Equal(t, user.Username, used.Username)
+
+	// Codes only work once.
+	_, err = store.UseOauthAuthorizationCode(context.Background(), codeHash)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	expired := grant(time.Now().Add(-time.Second))
+	_, err = store.UseOauthAuthorizationCode(context.Background(), expired)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	rows, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Target:   pgtype.Text{String: UserTarget(user.Username), Valid: true},
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, AuditOAuthGrant, rows[0].Action)
+}
+
+func TestOauthToken(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	client := createRandomOAuthClient(t, store, user.Username)
+
+	arg := CreateOauthTokenParams{
+		TokenHash: util.HashSecret(util.RandomString(32)),
+		ClientID:  client.ID,
+		Username:  user.Username,
+		Scopes:    client.Scopes,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	token, err := store.CreateOauthToken(context.Background(), arg)
+	require.NoError(t, err)
+
+	got, err := store.GetOauthToken(context.Background(), arg.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, token.ClientID, got.ClientID)
+	require.Equal(t, arg.Scopes, got.Scopes)
+	require.WithinDuration(t, arg.ExpiresAt, got.ExpiresAt, time.Second)
+
+	_, err = store.GetOauthToken(context.Background(), util.HashSecret("unknown"))
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
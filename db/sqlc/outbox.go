@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Event types written to the outbox.
+const (
+	EventAccountCreated  = "account.created"
+	EventAccountFrozen   = "account.frozen"
+	EventAccountUnfrozen = "account.unfrozen"
+	EventTransferPosted  = "transfer.posted"
+)
+
+// IsSupportedEventType checks if events of the given type are written to the outbox.
+func IsSupportedEventType(eventType string) bool {
+	switch eventType {
+	case EventAccountCreated, EventAccountFrozen, EventAccountUnfrozen, EventTransferPosted:
+		return true
+	}
+	return false
+}
+
+// TransferPostedEvent is the payload of a transfer.posted event. A transfer
+// posts one event per account, carrying only that account and its entry.
+type TransferPostedEvent struct {
+	Transfer Transfer `json:"transfer"`
+	Account  Account  `json:"account"`
+	Entry    Entry    `json:"entry"`
+}
+
+// recordEvent stores payload as a pending outbox event using q, so it is
+// committed or rolled back together with the surrounding transaction.
+func recordEvent(ctx context.Context, q *Queries, eventType string, payload any, accountIDs ...int64) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		EventType:  eventType,
+		AccountIds: accountIDs,
+		Payload:    data,
+	})
+	return err
+}
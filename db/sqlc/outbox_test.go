@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple_bank/util"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// createOutboxEventInTx creates an account.created event for a new account using the given Queries.
+func createOutboxEventInTx(t *testing.T, q *Queries) Outbox {
+	owner := createRandomUser(t).Username
+	account := createAccountInTx(t, q, owner, util.RandomCurrency())
+
+	arg := CreateOutboxEventParams{
+		EventType:  EventAccountCreated,
+		AccountIds: []int64{account.ID},
+		Payload:    []byte(`{"id":1}`),
+	}
+	event, err := q.CreateOutboxEvent(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, event)
+
+	require.Equal(t, arg.EventType, event.EventType)
+	require.Equal(t, arg.AccountIds, event.AccountIds)
+	require.JSONEq(t, string(arg.Payload), string(event.Payload))
+	require.Zero(t, event.Attempts)
+	require.False(t, event.PublishedAt.Valid)
+	require.NotZero(t, event.CreatedAt)
+
+	return event
+}
+
+// TestCreateOutboxEvent tests the creation of an outbox event.
+func TestCreateOutboxEvent(t *testing.T) {
+	runTestWithTransaction(t, func(t *testing.T, q *Queries) {
+		createOutboxEventInTx(t, q)
+	})
+}
+
+// TestListPendingOutboxEvents tests that published events are no longer listed.
+func TestListPendingOutboxEvents(t *testing.T) {
+	runTestWithTransaction(t, func(t *testing.T, q *Queries) {
+		published := createOutboxEventInTx(t, q)
+		pending := createOutboxEventInTx(t, q)
+
+		err := q.MarkOutboxEventPublished(context.Background(), published.ID)
+		require.NoError(t, err)
+
+		events, err := q.ListPendingOutboxEvents(context.Background(), 1000)
+		require.NoError(t, err)
+
+		ids := make([]int64, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		require.Contains(t, ids, pending.ID)
+		require.NotContains(t, ids, published.ID)
+	})
+}
+
+// TestMarkOutboxEventFailed tests that failed deliveries are counted and stay pending.
+func TestMarkOutboxEventFailed(t *testing.T) {
+	runTestWithTransaction(t, func(t *testing.T, q *Queries) {
+		event := createOutboxEventInTx(t, q)
+		require.False(t, event.NextAttemptAt.Valid)
+
+		nextAttemptAt := time.Now().Add(time.Minute).UTC().Truncate(time.Microsecond)
+		err := q.MarkOutboxEventFailed(context.Background(), MarkOutboxEventFailedParams{
+			ID:            event.ID,
+			LastError:     "sink unavailable",
+			NextAttemptAt: pgtype.Timestamptz{Time: nextAttemptAt, Valid: true},
+		})
+		require.NoError(t, err)
+
+		events, err := q.ListPendingOutboxEvents(context.Background(), 1000)
+		require.NoError(t, err)
+
+		var got Outbox
+		for _, e := range events {
+			if e.ID == event.ID {
+				got = e
+			}
+		}
+		require.Equal(t, event.ID, got.ID)
+		require.Equal(t, int32(1), got.Attempts)
+		require.Equal(t, "sink unavailable", got.LastError.String)
+		require.True(t, got.NextAttemptAt.Valid)
+		require.WithinDuration(t, nextAttemptAt, got.NextAttemptAt.Time, time.Microsecond)
+	})
+}
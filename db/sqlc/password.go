@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// passwordSnapshot is what the audit log records about a password change;
+// the hash itself stays out of the log.
+type passwordSnapshot struct {
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+}
+
+// ChangePasswordTxParams holds the arguments for changing a password.
+type ChangePasswordTxParams struct {
+	Username       string
+	HashedPassword string
+}
+
+// ChangePasswordTx sets a user's password and records the change in the
+// audit log. Checking the current password is up to the caller.
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, "ChangePasswordTx", func(ctx context.Context, q *Queries) error {
+		var err error
+		user, err = updatePassword(ctx, q, AuditPasswordChange, arg)
+		return err
+	})
+
+	return user, err
+}
+
+// ResetPasswordTxParams holds the arguments for resetting a password with a
+// reset token. TokenHash is the util.HashSecret of the token the user sent.
+type ResetPasswordTxParams struct {
+	TokenHash      []byte
+	HashedPassword string
+}
+
+// ResetPasswordTx sets the password of the user a reset token was issued to
+// and uses up the token. It fails with ErrResetTokenInvalid if the token is
+// unknown, used or expired, or was issued before the password last changed.
+// The change is audited as the token's user.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, "ResetPasswordTx", func(ctx context.Context, q *Queries) error {
+		reset, err := q.GetPasswordResetTokenForUpdate(ctx, arg.TokenHash)
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return ErrResetTokenInvalid
+			}
+			return err
+		}
+		if reset.UsedAt.Valid || time.Now().After(reset.ExpiresAt) {
+			return ErrResetTokenInvalid
+		}
+
+		user, err = q.GetUser(ctx, reset.Username)
+		if err != nil {
+			return err
+		}
+		if reset.CreatedAt.Before(user.PasswordChangedAt) {
+			return ErrResetTokenInvalid
+		}
+
+		_, err = q.UsePasswordResetToken(ctx, reset.ID)
+		if err != nil {
+			return err
+		}
+
+		actor := AuditActorFrom(ctx)
+		actor.Username = reset.Username
+		user, err = updatePassword(WithAuditActor(ctx, actor), q, AuditPasswordReset, ChangePasswordTxParams{
+			Username:       reset.Username,
+			HashedPassword: arg.HashedPassword,
+		})
+		return err
+	})
+
+	return user, err
+}
+
+func updatePassword(ctx context.Context, q *Queries, action string, arg ChangePasswordTxParams) (User, error) {
+	before, err := q.GetUser(ctx, arg.Username)
+	if err != nil {
+		return User{}, err
+	}
+
+	// Access tokens carry an IssuedAt from the app clock, so the change
+	// time they are compared against comes from the same clock rather
+	// than the database's now().
+	user, err := q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+		Password:          arg.HashedPassword,
+		PasswordChangedAt: time.Now().UTC().Truncate(time.Microsecond),
+		Username:          arg.Username,
+	})
+	if err != nil {
+		return User{}, err
+	}
+
+	_, err = recordAudit(ctx, q, AppendAuditLogParams{
+		Action: action,
+		Target: UserTarget(user.Username),
+		Before: passwordSnapshot{PasswordChangedAt: before.PasswordChangedAt},
+		After:  passwordSnapshot{PasswordChangedAt: user.PasswordChangedAt},
+	})
+	return user, err
+}
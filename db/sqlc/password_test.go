@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple_bank/util"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomResetToken(t *testing.T, username string, expiresIn time.Duration) string {
+	resetToken, err := util.NewSecret(32)
+	require.NoError(t, err)
+
+	_, err = testQueries.CreatePasswordResetToken(context.Background(), CreatePasswordResetTokenParams{
+		Username:  username,
+		TokenHash: util.HashSecret(resetToken),
+		ExpiresAt: time.Now().Add(expiresIn),
+	})
+	require.NoError(t, err)
+	return resetToken
+}
+
+func TestChangePasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(8))
+	require.NoError(t, err)
+
+	updated, err := store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, updated.Password)
+	require.True(t, updated.PasswordChangedAt.After(user.PasswordChangedAt))
+	require.WithinDuration(t, time.Now(), updated.PasswordChangedAt, time.Second)
+
+	rows, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Target:   pgtype.Text{String: UserTarget(user.Username), Valid: true},
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, AuditPasswordChange, rows[0].Action)
+	require.NotContains(t, string(rows[0].After), hashedPassword)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	resetToken := createRandomResetToken(t, user.Username, time.Minute)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(8))
+	require.NoError(t, err)
+	arg := ResetPasswordTxParams{TokenHash: util.HashSecret(resetToken), HashedPassword: hashedPassword}
+
+	updated, err := store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, updated.Password)
+
+	rows, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Target:   pgtype.Text{String: UserTarget(user.Username), Valid: true},
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, AuditPasswordReset, rows[0].Action)
+	require.Equal(t, user.Username, rows[0].Actor)
+
+	// A token works once.
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrResetTokenInvalid)
+}
+
+func TestResetPasswordTxInvalidToken(t *testing.T) {
+	store := NewStore(testDB)
+
+	testCases := []struct {
+		name       string
+		resetToken func(t *testing.T) string
+	}{
+		{
+			name: "Unknown",
+			resetToken: func(t *testing.T) string {
+				return util.RandomString(32)
+			},
+		},
+		{
+			name: "Expired",
+			resetToken: func(t *testing.T) string {
+				return createRandomResetToken(t, createRandomUser(t).Username, -time.Minute)
+			},
+		},
+		{
+			name: "IssuedBeforePasswordChange",
+			resetToken: func(t *testing.T) string {
+				user := createRandomUser(t)
+				resetToken := createRandomResetToken(t, user.Username, time.Minute)
+				_, err := store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+					Username:       user.Username,
+					HashedPassword: user.Password,
+				})
+				require.NoError(t, err)
+				return resetToken
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+				TokenHash:      util.HashSecret(tc.resetToken(t)),
+				HashedPassword: "irrelevant",
+			})
+			require.ErrorIs(t, err, ErrResetTokenInvalid)
+		})
+	}
+}
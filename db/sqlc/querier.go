@@ -6,26 +6,125 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	ConfirmTotpSecret(ctx context.Context, arg ConfirmTotpSecretParams) (TotpSecret, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAccountHolder(ctx context.Context, arg CreateAccountHolderParams) (AccountHolder, error)
+	CreateApiKey(ctx context.Context, arg CreateApiKeyParams) (ApiKey, error)
+	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error)
+	CreateCurrency(ctx context.Context, arg CreateCurrencyParams) (Currency, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateOauthAuthorizationCode(ctx context.Context, arg CreateOauthAuthorizationCodeParams) (OauthAuthorizationCode, error)
+	CreateOauthClient(ctx context.Context, arg CreateOauthClientParams) (OauthClient, error)
+	CreateOauthToken(ctx context.Context, arg CreateOauthTokenParams) (OauthToken, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
+	CreateTotpRecoveryCode(ctx context.Context, arg CreateTotpRecoveryCodeParams) (TotpRecoveryCode, error)
+	// Starts enrollment, replacing a secret that was never confirmed. Returns no
+	// row if the user already has a confirmed secret.
+	CreateTotpSecret(ctx context.Context, arg CreateTotpSecretParams) (TotpSecret, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
+	CreateTransferReversal(ctx context.Context, arg CreateTransferReversalParams) (TransferReversal, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) error
 	DeleteAccount(ctx context.Context, id int64) error
+	// Fails with ErrRecordNotFound if username does not hold the account or is
+	// its primary holder, who cannot be removed.
+	DeleteAccountHolder(ctx context.Context, arg DeleteAccountHolderParams) (AccountHolder, error)
+	DeleteLoginAttempt(ctx context.Context, key string) (int64, error)
+	DeleteRateLimitBucketsBefore(ctx context.Context, before time.Time) (int64, error)
+	DeleteWebhook(ctx context.Context, id int64) error
 	Deletetransfers(ctx context.Context, id int64) error
+	ExportAccounts(ctx context.Context, arg ExportAccountsParams) ([]Account, error)
+	ExportEntries(ctx context.Context, arg ExportEntriesParams) ([]Entry, error)
+	ExportTransfers(ctx context.Context, arg ExportTransfersParams) ([]Transfer, error)
+	// Leaves out the password hash.
+	ExportUsers(ctx context.Context, arg ExportUsersParams) ([]ExportUsersRow, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetAccountHolder(ctx context.Context, arg GetAccountHolderParams) (AccountHolder, error)
+	GetApiKeyByPrefix(ctx context.Context, prefix string) (ApiKey, error)
+	GetCurrency(ctx context.Context, code string) (Currency, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetLastAuditLogHash(ctx context.Context) ([]byte, error)
+	GetLedgerTotals(ctx context.Context) (GetLedgerTotalsRow, error)
+	GetOauthClient(ctx context.Context, id string) (OauthClient, error)
+	GetOauthToken(ctx context.Context, tokenHash []byte) (OauthToken, error)
+	GetPasswordResetTokenForUpdate(ctx context.Context, tokenHash []byte) (PasswordResetToken, error)
+	GetTotpSecret(ctx context.Context, username string) (TotpSecret, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferReversal(ctx context.Context, transferID int64) (TransferReversal, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserAuthState(ctx context.Context, username string) (GetUserAuthStateRow, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetWebhook(ctx context.Context, id int64) (Webhook, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	ListAccountHolders(ctx context.Context, accountID int64) ([]AccountHolder, error)
+	// Lists the accounts username holds, in any role.
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListApiKeys(ctx context.Context, owner string) ([]ApiKey, error)
+	// Newest first. Each filter is skipped when NULL.
+	ListAuditLog(ctx context.Context, arg ListAuditLogParams) ([]AuditLog, error)
+	// Pages through the log in chain order.
+	ListAuditLogAfter(ctx context.Context, arg ListAuditLogAfterParams) ([]AuditLog, error)
+	ListCurrencies(ctx context.Context) ([]Currency, error)
+	ListDueWebhookDeliveries(ctx context.Context, limit int32) ([]WebhookDelivery, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	// Lists accounts whose balance differs from the sum of their entries or
+	// from the net amount of the transfers into and out of them.
+	ListLedgerMismatches(ctx context.Context) ([]ListLedgerMismatchesRow, error)
+	// Returns the keys among keys that are locked out right now.
+	ListLoginLockouts(ctx context.Context, keys []string) ([]LoginAttempt, error)
+	ListPendingOutboxEvents(ctx context.Context, limit int32) ([]Outbox, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhooks(ctx context.Context, arg ListWebhooksParams) ([]Webhook, error)
+	ListWebhooksForEvent(ctx context.Context, arg ListWebhooksForEventParams) ([]Webhook, error)
 	Listtransfers(ctx context.Context, arg ListtransfersParams) ([]Transfer, error)
+	// Serializes appends so the hash chain stays linear. The lock is held until
+	// the transaction ends, so take it last, after any row locks.
+	LockAuditLog(ctx context.Context) error
+	LockLogin(ctx context.Context, arg LockLoginParams) (LoginAttempt, error)
+	MarkOutboxEventFailed(ctx context.Context, arg MarkOutboxEventFailedParams) error
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	NotifyAccountUpdate(ctx context.Context, payload string) error
+	// Counts a failed login for key, starting over if the last failure was
+	// before reset_before.
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginAttempt, error)
+	ReplayWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	// Revokes a key of owner. Fails with ErrRecordNotFound if owner has no
+	// such key, or it is already revoked.
+	RevokeApiKey(ctx context.Context, arg RevokeApiKeyParams) (ApiKey, error)
+	SetAccountStatus(ctx context.Context, arg SetAccountStatusParams) (Account, error)
+	// Refills the bucket for the time since its last update, capped at burst,
+	// and takes one token if at least one is available.
+	TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (RateLimitBucket, error)
+	// Records that a key was used. It is written at most once a minute, so
+	// that busy keys do not cost a write per request.
+	TouchApiKey(ctx context.Context, id int64) error
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateCurrency(ctx context.Context, arg UpdateCurrencyParams) (Currency, error)
 	UpdateTransfer(ctx context.Context, arg UpdateTransferParams) (Transfer, error)
+	UpdateUserEmailVerified(ctx context.Context, username string) (User, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpdateWebhookDeliveryAttempt(ctx context.Context, arg UpdateWebhookDeliveryAttemptParams) (WebhookDelivery, error)
+	// Marks an unexpired code used, so that it can only be exchanged once.
+	// Fails with ErrRecordNotFound if there is no such code, or it was used
+	// or has expired.
+	UseOauthAuthorizationCode(ctx context.Context, codeHash []byte) (OauthAuthorizationCode, error)
+	UsePasswordResetToken(ctx context.Context, id int64) (PasswordResetToken, error)
+	UseTotpRecoveryCode(ctx context.Context, arg UseTotpRecoveryCodeParams) (TotpRecoveryCode, error)
+	// Records the time step of an accepted code. Returns no row if a code of
+	// that step or a later one was already used.
+	UseTotpStep(ctx context.Context, arg UseTotpStepParams) (TotpSecret, error)
+	// Matches only an unused, unexpired row, so a code works once.
+	UseVerifyEmail(ctx context.Context, arg UseVerifyEmailParams) (VerifyEmail, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple_bank/util"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestTakeRateLimitToken tests that a bucket hands out burst tokens and then refuses.
+// now() is fixed within the test transaction, so the bucket never refills.
+func TestTakeRateLimitToken(t *testing.T) {
+	runTestWithTransaction(t, func(t *testing.T, q *Queries) {
+		arg := TakeRateLimitTokenParams{
+			Key:   "test:" + util.RandomString(12),
+			Burst: 2,
+			Rate:  1,
+		}
+
+		bucket, err := q.TakeRateLimitToken(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, arg.Key, bucket.Key)
+		require.True(t, bucket.Allowed)
+		require.Equal(t, float64(1), bucket.Tokens)
+		require.NotZero(t, bucket.UpdatedAt)
+
+		bucket, err = q.TakeRateLimitToken(context.Background(), arg)
+		require.NoError(t, err)
+		require.True(t, bucket.Allowed)
+		require.Equal(t, float64(0), bucket.Tokens)
+
+		bucket, err = q.TakeRateLimitToken(context.Background(), arg)
+		require.NoError(t, err)
+		require.False(t, bucket.Allowed)
+		require.Equal(t, float64(0), bucket.Tokens)
+	})
+}
+
+// TestDeleteRateLimitBucketsBefore tests pruning buckets by last update.
+func TestDeleteRateLimitBucketsBefore(t *testing.T) {
+	runTestWithTransaction(t, func(t *testing.T, q *Queries) {
+		bucket, err := q.TakeRateLimitToken(context.Background(), TakeRateLimitTokenParams{
+			Key:   "test:" + util.RandomString(12),
+			Burst: 5,
+			Rate:  1,
+		})
+		require.NoError(t, err)
+
+		n, err := q.DeleteRateLimitBucketsBefore(context.Background(), bucket.UpdatedAt)
+		require.NoError(t, err)
+		require.Zero(t, n)
+
+		n, err = q.DeleteRateLimitBucketsBefore(context.Background(), bucket.UpdatedAt.Add(time.Second))
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, n, int64(1))
+	})
+}
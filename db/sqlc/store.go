@@ -2,14 +2,51 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
+
+	"simple_bank/metrics"
+	"simple_bank/util"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("simple_bank/db")
+
+// maxTxAttempts bounds how often execTx runs a transaction that keeps
+// failing with a serialization failure or deadlock.
+const maxTxAttempts = 3
+
 type Store interface {
 	Querier
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (User, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error)
+	AddAccountHolderTx(ctx context.Context, arg CreateAccountHolderParams) (AccountHolder, error)
+	RemoveAccountHolderTx(ctx context.Context, arg DeleteAccountHolderParams) (AccountHolder, error)
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error)
+	SetAccountStatusTx(ctx context.Context, arg SetAccountStatusParams) (Account, error)
+	AppendAuditLog(ctx context.Context, arg AppendAuditLogParams) (AuditLog, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (User, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (TotpSecret, error)
+	RecordLoginFailureTx(ctx context.Context, arg RecordLoginFailureTxParams) (LoginAttempt, error)
+	UnlockLoginTx(ctx context.Context, key string) error
+	CreateAPIKeyTx(ctx context.Context, arg CreateApiKeyParams) (ApiKey, error)
+	RevokeAPIKeyTx(ctx context.Context, arg RevokeApiKeyParams) (ApiKey, error)
+	CreateOAuthClientTx(ctx context.Context, arg CreateOauthClientParams) (OauthClient, error)
+	GrantOAuthCodeTx(ctx context.Context, arg CreateOauthAuthorizationCodeParams) (OauthAuthorizationCode, error)
+	Ping(ctx context.Context) error
+	PoolStats() PoolStats
+	MigrationVersion(ctx context.Context) (version int64, dirty bool, err error)
 }
 
 // SQLStore provides all functions to execute SQL queries and transaction.
@@ -26,22 +63,190 @@ func NewStore(db *pgxpool.Pool) Store {
 	}
 }
 
-func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
+// execTx runs fn in a transaction, retrying it from scratch when Postgres
+// aborts it with a serialization failure or deadlock. fn must therefore
+// overwrite, not accumulate, any state it shares with the caller.
+// The transaction is traced as a span called name; fn receives its context
+// so that query spans nest under it.
+func (store *SQLStore) execTx(ctx context.Context, name string, fn func(context.Context, *Queries) error) error {
+	ctx, span := tracer.Start(ctx, name)
+	defer span.End()
+
+	var err error
+	attempt := 1
+	for ; attempt <= maxTxAttempts; attempt++ {
+		err = store.runTx(ctx, fn)
+		if !isRetryable(err) || attempt == maxTxAttempts {
+			break
+		}
+		metrics.TxTotal.WithLabelValues(metrics.TxRetry).Inc()
+		slog.WarnContext(ctx, "retrying transaction", "tx", name, "attempt", attempt, "code", ErrorCode(err))
+		span.AddEvent("retry", trace.WithAttributes(attribute.String("db.error_code", ErrorCode(err))))
+	}
+
+	span.SetAttributes(attribute.Int("db.tx.attempts", attempt))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func (store *SQLStore) runTx(ctx context.Context, fn func(context.Context, *Queries) error) error {
 	tx, err := store.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
 
 	q := New(tx)
-	err = fn(q)
+	err = fn(ctx, q)
 	if err != nil {
+		metrics.TxTotal.WithLabelValues(metrics.TxRollback).Inc()
 		if rbErr := tx.Rollback(ctx); rbErr != nil {
-			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+			return fmt.Errorf("tx err: %w, rb err: %v", err, rbErr)
 		}
 		return err
 	}
 
-	return tx.Commit(ctx)
+	if err := tx.Commit(ctx); err != nil {
+		metrics.TxTotal.WithLabelValues(metrics.TxRollback).Inc()
+		return err
+	}
+	metrics.TxTotal.WithLabelValues(metrics.TxCommit).Inc()
+	return nil
+}
+
+func isRetryable(err error) bool {
+	code := ErrorCode(err)
+	return code == SerializationFailure || code == DeadlockDetected
+}
+
+// Account statuses.
+const (
+	AccountActive = "active"
+	AccountFrozen = "frozen"
+)
+
+// CreateUserTxParams holds the arguments for creating a user who still has
+// to verify their email address.
+type CreateUserTxParams struct {
+	CreateUserParams
+	// SecretCodeHash is the util.HashSecret of the code mailed to the user,
+	// which must reach VerifyEmailTx before ExpiresAt.
+	SecretCodeHash []byte
+	ExpiresAt      time.Time
+}
+
+// CreateUserTxResult holds the created user and its pending verification.
+type CreateUserTxResult struct {
+	User        User
+	VerifyEmail VerifyEmail
+}
+
+// CreateUserTx creates a user together with a verification of their email.
+// Callers mail the verification only once it returns, so a rolled back
+// user is never sent a link.
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, "CreateUserTx", func(ctx context.Context, q *Queries) error {
+		var err error
+		result.User, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:       result.User.Username,
+			Email:          result.User.Email,
+			SecretCodeHash: arg.SecretCodeHash,
+			ExpiresAt:      arg.ExpiresAt,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// CreateAccountTx creates an account, with its owner as the primary holder,
+// and records an account.created event in the same transaction.
+// It fails with ErrCurrencyDisabled unless the currency is enabled.
+func (store *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, "CreateAccountTx", func(ctx context.Context, q *Queries) error {
+		currency, err := q.GetCurrency(ctx, arg.Currency)
+		if errors.Is(err, ErrRecordNotFound) || (err == nil && !currency.Enabled) {
+			return fmt.Errorf("%w: %s", ErrCurrencyDisabled, arg.Currency)
+		}
+		if err != nil {
+			return err
+		}
+
+		account, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateAccountHolder(ctx, CreateAccountHolderParams{
+			AccountID: account.ID,
+			Username:  account.Owner,
+			Role:      util.PrimaryHolder,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = recordEvent(ctx, q, EventAccountCreated, account, account.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAudit(ctx, q, AppendAuditLogParams{
+			Action: AuditAccountCreate,
+			Target: AccountTarget(account.ID),
+			After:  account,
+		})
+		return err
+	})
+
+	return account, err
+}
+
+// SetAccountStatusTx freezes or unfreezes an account and records the change
+// in the audit log.
+func (store *SQLStore) SetAccountStatusTx(ctx context.Context, arg SetAccountStatusParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, "SetAccountStatusTx", func(ctx context.Context, q *Queries) error {
+		before, err := q.GetAccountForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		account, err = q.SetAccountStatus(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		action, eventType := AuditAccountUnfreeze, EventAccountUnfrozen
+		if arg.Status == AccountFrozen {
+			action, eventType = AuditAccountFreeze, EventAccountFrozen
+		}
+		_, err = recordAudit(ctx, q, AppendAuditLogParams{
+			Action: action,
+			Target: AccountTarget(account.ID),
+			Before: before,
+			After:  account,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, q, eventType, account, account.ID)
+	})
+
+	return account, err
 }
 
 // TransferTxParams holds the arguments for a transfer between two accounts.
@@ -49,61 +254,208 @@ type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+	// Sender is the holder of the from account making the transfer. It
+	// defaults to the account's owner.
+	Sender string `json:"sender,omitempty"`
 }
 
 // TransferTxResult holds the result of a transfer transaction.
 type TransferTxResult struct {
-	Transfer    Transfer
-	FromAccount Account
-	ToAccount   Account
-	FromEntry   Entry
-	ToEntry     Entry
+	Transfer    Transfer `json:"transfer"`
+	FromAccount Account  `json:"from_account"`
+	ToAccount   Account  `json:"to_account"`
+	FromEntry   Entry    `json:"from_entry"`
+	ToEntry     Entry    `json:"to_entry"`
 }
 
+// TransferTx moves money between two accounts. It fails with
+// ErrAccountFrozen if either account is frozen, and with ErrEmailNotVerified
+// if the sender has not verified their email address. Whether the sender
+// may use the from account is up to the caller.
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	err := store.execTx(ctx, func(q *Queries) error {
+	err := store.execTx(ctx, "TransferTx", func(ctx context.Context, q *Queries) error {
 		var err error
+		result, err = transfer(ctx, q, arg, false)
+		if err != nil {
+			return err
+		}
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
+		senderName := arg.Sender
+		if senderName == "" {
+			senderName = result.FromAccount.Owner
+		}
+		sender, err := q.GetUser(ctx, senderName)
 		if err != nil {
 			return err
 		}
+		if !sender.IsEmailVerified {
+			return fmt.Errorf("%w: %s", ErrEmailNotVerified, sender.Username)
+		}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
+		_, err = recordAudit(ctx, q, AppendAuditLogParams{
+			Action: AuditTransferCreate,
+			Target: TransferTarget(result.Transfer.ID),
+			Before: balancesBefore(result),
+			After:  result,
 		})
+		return err
+	})
+	if err != nil {
+		return result, err
+	}
+
+	currency := result.FromAccount.Currency
+	metrics.TransfersCreated.WithLabelValues(currency).Inc()
+	metrics.AmountTransferred.WithLabelValues(currency).Add(float64(arg.Amount))
+
+	return result, nil
+}
+
+// transfer records a transfer, its entries and the balance updates using q.
+// Unless allowFrozen is set it fails with ErrAccountFrozen if either account
+// is frozen; the check runs once both rows are locked so a concurrent freeze
+// cannot slip in between.
+func transfer(ctx context.Context, q *Queries, arg TransferTxParams, allowFrozen bool) (TransferTxResult, error) {
+	var result TransferTxResult
+	var err error
+
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.FromAccountID,
+		Amount:    -arg.Amount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.ToAccountID,
+		Amount:    arg.Amount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if arg.FromAccountID < arg.ToAccountID {
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
+	} else {
+		// Lock/update in same order: lower ID first (ToAccountID, then FromAccountID).
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if !allowFrozen {
+		for _, account := range []Account{result.FromAccount, result.ToAccount} {
+			if account.Status == AccountFrozen {
+				return result, fmt.Errorf("%w: account [%d]", ErrAccountFrozen, account.ID)
+			}
+		}
+	}
+
+	err = publishAccountUpdate(ctx, q, result.FromAccount, result.FromEntry, result.Transfer.ID)
+	if err != nil {
+		return result, err
+	}
+
+	err = publishAccountUpdate(ctx, q, result.ToAccount, result.ToEntry, result.Transfer.ID)
+	if err != nil {
+		return result, err
+	}
+
+	// Each side gets its own event so the holders of one account never see
+	// the other's balance.
+	sides := []TransferPostedEvent{
+		{Transfer: result.Transfer, Account: result.FromAccount, Entry: result.FromEntry},
+		{Transfer: result.Transfer, Account: result.ToAccount, Entry: result.ToEntry},
+	}
+	for _, side := range sides {
+		err = recordEvent(ctx, q, EventTransferPosted, side, side.Account.ID)
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// ReverseTransferTxParams holds the arguments for reversing a transfer.
+type ReverseTransferTxParams struct {
+	TransferID int64  `json:"transfer_id"`
+	ReversedBy string `json:"reversed_by"`
+}
+
+// ReverseTransferTxResult holds the transfer that moved the money back and
+// the record linking it to the original transfer.
+type ReverseTransferTxResult struct {
+	TransferTxResult
+	Reversal TransferReversal `json:"reversal"`
+}
+
+// ReverseTransferTx moves the amount of a transfer back to its sender, even
+// if either account has been frozen since. A transfer can be reversed once;
+// reversing it again fails with a unique violation.
+func (store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	var result ReverseTransferTxResult
+
+	err := store.execTx(ctx, "ReverseTransferTx", func(ctx context.Context, q *Queries) error {
+		original, err := q.GetTransfer(ctx, arg.TransferID)
 		if err != nil {
 			return err
 		}
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
+		result.TransferTxResult, err = transfer(ctx, q, TransferTxParams{
+			FromAccountID: original.ToAccountID,
+			ToAccountID:   original.FromAccountID,
+			Amount:        original.Amount,
+		}, true)
 		if err != nil {
 			return err
 		}
 
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			// Lock/update in same order: lower ID first (ToAccountID, then FromAccountID).
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+		result.Reversal, err = q.CreateTransferReversal(ctx, CreateTransferReversalParams{
+			TransferID: original.ID,
+			ReversalID: result.Transfer.ID,
+			ReversedBy: arg.ReversedBy,
+		})
+		if err != nil {
+			return err
 		}
-		return nil
 
+		_, err = recordAudit(ctx, q, AppendAuditLogParams{
+			Action: AuditTransferReverse,
+			Target: TransferTarget(original.ID),
+			Before: balancesBefore(result.TransferTxResult),
+			After:  result,
+		})
+		return err
 	})
 
 	return result, err
 }
 
+// balancesBefore returns the accounts of a transfer as they were before it
+// was applied, for the audit log.
+func balancesBefore(result TransferTxResult) any {
+	from, to := result.FromAccount, result.ToAccount
+	from.Balance += result.Transfer.Amount
+	to.Balance -= result.Transfer.Amount
+	return struct {
+		FromAccount Account `json:"from_account"`
+		ToAccount   Account `json:"to_account"`
+	}{from, to}
+}
+
 func addMoney(ctx context.Context, q *Queries, accountID1 int64, amount1 int64, accountID2 int64, amount2 int64) (account1 Account, account2 Account, err error) {
 
 	account1, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{ID: accountID1, Amount: amount1})
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"simple_bank/util"
 	"testing"
 
@@ -145,3 +146,201 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, account2.Balance, updatedTo.Balance)
 }
+
+// TestCreateAccountTx tests that creating an account also records an account.created event.
+func TestCreateAccountTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	account, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  util.RandomMoney(),
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, account.ID)
+
+	holder, err := store.GetAccountHolder(context.Background(), GetAccountHolderParams{
+		AccountID: account.ID,
+		Username:  user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.PrimaryHolder, holder.Role)
+
+	events, err := store.ListPendingOutboxEvents(context.Background(), 1000)
+	require.NoError(t, err)
+
+	found := false
+	for _, event := range events {
+		if event.EventType == EventAccountCreated && event.AccountIds[0] == account.ID {
+			found = true
+		}
+	}
+	require.True(t, found)
+}
+
+// TestCreateAccountTxCurrencyDisabled tests that accounts cannot be opened in a disabled currency.
+func TestCreateAccountTxCurrencyDisabled(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	for _, currency := range []string{util.CAD, "XYZ"} {
+		_, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+			Owner:    user.Username,
+			Currency: currency,
+		})
+		require.ErrorIs(t, err, ErrCurrencyDisabled)
+	}
+}
+
+// TestSetAccountStatusTxEvents tests that freezing and unfreezing an account write outbox events.
+func TestSetAccountStatusTxEvents(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccount(t, createRandomUser(t).Username, util.USD)
+
+	_, err := store.SetAccountStatusTx(context.Background(), SetAccountStatusParams{Status: AccountFrozen, ID: account.ID})
+	require.NoError(t, err)
+	_, err = store.SetAccountStatusTx(context.Background(), SetAccountStatusParams{Status: AccountActive, ID: account.ID})
+	require.NoError(t, err)
+
+	events, err := store.ListPendingOutboxEvents(context.Background(), 1000)
+	require.NoError(t, err)
+
+	var statuses []string
+	for _, event := range events {
+		if event.EventType != EventAccountFrozen && event.EventType != EventAccountUnfrozen {
+			continue
+		}
+		if event.AccountIds[0] != account.ID {
+			continue
+		}
+		require.Equal(t, []int64{account.ID}, event.AccountIds)
+		var payload Account
+		require.NoError(t, json.Unmarshal(event.Payload, &payload))
+		require.Equal(t, account.ID, payload.ID)
+		statuses = append(statuses, payload.Status)
+	}
+	require.Equal(t, []string{AccountFrozen, AccountActive}, statuses)
+}
+
+// TestTransferTxFrozenAccount tests that transfers from and to a frozen account fail without moving money.
+func TestTransferTxFrozenAccount(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createRandomAccount(t, createRandomUser(t).Username, util.USD)
+	account2 := createRandomAccount(t, createRandomUser(t).Username, util.USD)
+
+	frozen, err := store.SetAccountStatus(context.Background(), SetAccountStatusParams{
+		Status: AccountFrozen,
+		ID:     account2.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountFrozen, frozen.Status)
+
+	for _, arg := range []TransferTxParams{
+		{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10},
+		{FromAccountID: account2.ID, ToAccountID: account1.ID, Amount: 10},
+	} {
+		_, err := store.TransferTx(context.Background(), arg)
+		require.ErrorIs(t, err, ErrAccountFrozen)
+	}
+
+	updated, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updated.Balance)
+}
+
+// TestTransferTxEvents tests that each side of a transfer gets its own
+// transfer.posted event, so webhooks of one account's holders never see the
+// other account.
+func TestTransferTxEvents(t *testing.T) {
+	store := NewStore(testDB)
+	sender := createRandomUser(t).Username
+	recipient := createRandomUser(t).Username
+	from := createRandomAccount(t, sender, util.USD)
+	to := createRandomAccount(t, recipient, util.USD)
+	webhook := createWebhookInTx(t, testQueries, recipient)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	events, err := store.ListPendingOutboxEvents(context.Background(), 1000)
+	require.NoError(t, err)
+
+	posted := map[int64]Outbox{}
+	for _, event := range events {
+		if event.EventType != EventTransferPosted {
+			continue
+		}
+		var payload TransferPostedEvent
+		require.NoError(t, json.Unmarshal(event.Payload, &payload))
+		if payload.Transfer.ID == result.Transfer.ID {
+			require.Equal(t, []int64{payload.Account.ID}, event.AccountIds)
+			posted[payload.Account.ID] = event
+		}
+	}
+	require.Len(t, posted, 2)
+
+	// The recipient's webhook only gets the recipient's side.
+	for accountID, event := range posted {
+		webhooks, err := store.ListWebhooksForEvent(context.Background(), ListWebhooksForEventParams{
+			EventType:  event.EventType,
+			AccountIds: event.AccountIds,
+		})
+		require.NoError(t, err)
+
+		subscribed := false
+		for _, w := range webhooks {
+			subscribed = subscribed || w.ID == webhook.ID
+		}
+		require.Equal(t, accountID == to.ID, subscribed)
+	}
+
+	var delivered map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(posted[to.ID].Payload, &delivered))
+	require.NotContains(t, delivered, "from_account")
+	var account Account
+	require.NoError(t, json.Unmarshal(delivered["account"], &account))
+	require.Equal(t, to.ID, account.ID)
+	require.Equal(t, result.ToAccount.Balance, account.Balance)
+	require.NotContains(t, string(posted[to.ID].Payload), sender)
+}
+
+// TestReverseTransferTx tests that a reversal moves the money back, even to a frozen account, and only once.
+func TestReverseTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+	admin := createRandomUser(t)
+	account1 := createRandomAccount(t, createRandomUser(t).Username, util.USD)
+	account2 := createRandomAccount(t, createRandomUser(t).Username, util.USD)
+
+	original, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	_, err = store.SetAccountStatus(context.Background(), SetAccountStatusParams{
+		Status: AccountFrozen,
+		ID:     account1.ID,
+	})
+	require.NoError(t, err)
+
+	arg := ReverseTransferTxParams{TransferID: original.Transfer.ID, ReversedBy: admin.Username}
+	result, err := store.ReverseTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, account2.ID, result.Transfer.FromAccountID)
+	require.Equal(t, account1.ID, result.Transfer.ToAccountID)
+	require.Equal(t, int64(10), result.Transfer.Amount)
+	require.Equal(t, account1.Balance, result.ToAccount.Balance)
+	require.Equal(t, account2.Balance, result.FromAccount.Balance)
+	require.Equal(t, original.Transfer.ID, result.Reversal.TransferID)
+	require.Equal(t, result.Transfer.ID, result.Reversal.ReversalID)
+	require.Equal(t, admin.Username, result.Reversal.ReversedBy)
+
+	_, err = store.ReverseTransferTx(context.Background(), arg)
+	require.Equal(t, UniqueViolation, ErrorCode(err))
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// totpSnapshot is what the audit log records when two-factor authentication
+// is enabled; the secret and recovery codes stay out of the log.
+type totpSnapshot struct {
+	RecoveryCodes int `json:"recovery_codes"`
+}
+
+// EnableTOTPTxParams holds the arguments for confirming a TOTP enrollment.
+// Step is the time step of the code the user proved their authenticator
+// with, and RecoveryCodeHashes the util.HashSecret of each recovery code
+// handed to them.
+type EnableTOTPTxParams struct {
+	Username           string
+	Step               int64
+	RecoveryCodeHashes [][]byte
+}
+
+// EnableTOTPTx confirms the pending TOTP secret of a user, so that login
+// asks for a code from then on, and stores their recovery codes. It fails
+// with ErrTOTPAlreadyEnabled if the secret is already confirmed and with
+// ErrRecordNotFound if enrollment was never started.
+func (store *SQLStore) EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (TotpSecret, error) {
+	var secret TotpSecret
+
+	err := store.execTx(ctx, "EnableTOTPTx", func(ctx context.Context, q *Queries) error {
+		var err error
+		secret, err = q.ConfirmTotpSecret(ctx, ConfirmTotpSecretParams{
+			Username:     arg.Username,
+			LastUsedStep: arg.Step,
+		})
+		if errors.Is(err, ErrRecordNotFound) {
+			if _, getErr := q.GetTotpSecret(ctx, arg.Username); getErr == nil {
+				return ErrTOTPAlreadyEnabled
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, codeHash := range arg.RecoveryCodeHashes {
+			_, err = q.CreateTotpRecoveryCode(ctx, CreateTotpRecoveryCodeParams{
+				Username: arg.Username,
+				CodeHash: codeHash,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = recordAudit(ctx, q, AppendAuditLogParams{
+			Action: AuditTOTPEnable,
+			Target: UserTarget(arg.Username),
+			After:  totpSnapshot{RecoveryCodes: len(arg.RecoveryCodeHashes)},
+		})
+		return err
+	})
+
+	return secret, err
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"simple_bank/util"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func createPendingTotpSecret(t *testing.T, username string) TotpSecret {
+	secret, err := testQueries.CreateTotpSecret(context.Background(), CreateTotpSecretParams{
+		Username:        username,
+		SecretEncrypted: []byte(util.RandomString(32)),
+	})
+	require.NoError(t, err)
+	require.False(t, secret.ConfirmedAt.Valid)
+	return secret
+}
+
+func TestEnableTOTPTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	createPendingTotpSecret(t, user.Username)
+
+	// Enrolling again before confirming replaces the secret.
+	pending := createPendingTotpSecret(t, user.Username)
+
+	recoveryCode := util.RandomString(16)
+	enabled, err := store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{
+		Username:           user.Username,
+		Step:               100,
+		RecoveryCodeHashes: [][]byte{util.HashSecret(recoveryCode)},
+	})
+	require.NoError(t, err)
+	require.True(t, enabled.ConfirmedAt.Valid)
+	require.Equal(t, pending.SecretEncrypted, enabled.SecretEncrypted)
+	require.Equal(t, int64(100), enabled.LastUsedStep)
+
+	_, err = store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{Username: user.Username, Step: 101})
+	require.ErrorIs(t, err, ErrTOTPAlreadyEnabled)
+
+	// A confirmed secret cannot be replaced by enrolling again.
+	_, err = testQueries.CreateTotpSecret(context.Background(), CreateTotpSecretParams{
+		Username:        user.Username,
+		SecretEncrypted: []byte(util.RandomString(32)),
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	rows, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Target:   pgtype.Text{String: UserTarget(user.Username), Valid: true},
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, AuditTOTPEnable, rows[0].Action)
+	require.NotContains(t, string(rows[0].After), recoveryCode)
+}
+
+func TestEnableTOTPTxNotEnrolled(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	_, err := store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{Username: user.Username, Step: 1})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUseTotpStep(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	createPendingTotpSecret(t, user.Username)
+
+	// Codes are only checked for confirmed secrets.
+	_, err := testQueries.UseTotpStep(context.Background(), UseTotpStepParams{Username: user.Username, LastUsedStep: 1})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{Username: user.Username, Step: 100})
+	require.NoError(t, err)
+
+	for _, step := range []int64{99, 100} {
+		_, err = testQueries.UseTotpStep(context.Background(), UseTotpStepParams{Username: user.Username, LastUsedStep: step})
+		require.ErrorIs(t, err, ErrRecordNotFound)
+	}
+
+	used, err := testQueries.UseTotpStep(context.Background(), UseTotpStepParams{Username: user.Username, LastUsedStep: 101})
+	require.NoError(t, err)
+	require.Equal(t, int64(101), used.LastUsedStep)
+}
+
+func TestUseTotpRecoveryCode(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	createPendingTotpSecret(t, user.Username)
+
+	codeHash := util.HashSecret(util.RandomString(16))
+	_, err := store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{
+		Username:           user.Username,
+		Step:               1,
+		RecoveryCodeHashes: [][]byte{codeHash},
+	})
+	require.NoError(t, err)
+
+	arg := UseTotpRecoveryCodeParams{Username: user.Username, CodeHash: codeHash}
+	used, err := testQueries.UseTotpRecoveryCode(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, used.UsedAt.Valid)
+
+	_, err = testQueries.UseTotpRecoveryCode(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
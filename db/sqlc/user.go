@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+)
+
+// roleSnapshot is what the audit log records about a role change.
+type roleSnapshot struct {
+	Role string `json:"role"`
+}
+
+// UpdateUserRoleTx changes a user's role and records the old and new role
+// in the audit log.
+func (store *SQLStore) UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, "UpdateUserRoleTx", func(ctx context.Context, q *Queries) error {
+		before, err := q.GetUser(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.UpdateUserRole(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = recordAudit(ctx, q, AppendAuditLogParams{
+			Action: AuditUserRoleChange,
+			Target: UserTarget(user.Username),
+			Before: roleSnapshot{Role: before.Role},
+			After:  roleSnapshot{Role: user.Role},
+		})
+		return err
+	})
+
+	return user, err
+}
@@ -8,6 +8,7 @@ import (
 	"simple_bank/util"
 
 	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,6 +20,8 @@ func createRandomUser(t *testing.T) User {
 		Password: util.RandomString(6),
 		FullName: util.RandomOwner(),
 		Email:    util.RandomEmail(),
+		// Verified, so that transfers from the user's accounts go through.
+		IsEmailVerified: true,
 	}
 
 	user, err := testQueries.CreateUser(context.Background(), arg)
@@ -30,6 +33,8 @@ func createRandomUser(t *testing.T) User {
 	require.Equal(t, arg.Email, user.Email)
 	require.True(t, user.PasswordChangedAt.IsZero())
 	require.NotZero(t, user.CreatedAt)
+	require.Equal(t, util.CustomerRole, user.Role)
+	require.True(t, user.IsEmailVerified)
 
 	return user
 }
@@ -58,3 +63,51 @@ func TestGetUserNotFound(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, pgx.ErrNoRows.Error(), err.Error())
 }
+
+// TestUpdateUserRole tests the UpdateUserRole function
+func TestUpdateUserRole(t *testing.T) {
+	user := createRandomUser(t)
+	updated, err := testQueries.UpdateUserRole(context.Background(), UpdateUserRoleParams{
+		Role:     util.SupportRole,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.SupportRole, updated.Role)
+
+	_, err = testQueries.UpdateUserRole(context.Background(), UpdateUserRoleParams{
+		Role:     "root",
+		Username: user.Username,
+	})
+	require.Error(t, err)
+}
+
+// TestUpdateUserRoleTx tests that a role change is recorded in the audit log
+func TestUpdateUserRoleTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	ctx := WithAuditActor(context.Background(), AuditActor{Username: "cli"})
+	updated, err := store.UpdateUserRoleTx(ctx, UpdateUserRoleParams{
+		Role:     util.AdminRole,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.AdminRole, updated.Role)
+
+	rows, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Target:   pgtype.Text{String: UserTarget(user.Username), Valid: true},
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, AuditUserRoleChange, rows[0].Action)
+	require.Equal(t, "cli", rows[0].Actor)
+	require.JSONEq(t, `{"role":"`+user.Role+`"}`, string(rows[0].Before))
+	require.JSONEq(t, `{"role":"admin"}`, string(rows[0].After))
+
+	_, err = store.UpdateUserRoleTx(context.Background(), UpdateUserRoleParams{
+		Role:     util.AdminRole,
+		Username: "nonexistent_username",
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
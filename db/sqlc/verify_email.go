@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// verifiedEmailSnapshot is what the audit log records about a verification.
+type verifiedEmailSnapshot struct {
+	VerifyEmailID int64  `json:"verify_email_id"`
+	Email         string `json:"email"`
+}
+
+// VerifyEmailTxParams holds the arguments for verifying an email address.
+// SecretCodeHash is the util.HashSecret of the code the user sent.
+type VerifyEmailTxParams struct {
+	EmailID        int64
+	SecretCodeHash []byte
+}
+
+// VerifyEmailTx marks the email of the user a verification was sent to as
+// verified and uses up the verification. It fails with ErrVerifyEmailInvalid
+// unless the verification exists, matches the code, is unused and has not
+// expired. The change is audited as that user.
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, "VerifyEmailTx", func(ctx context.Context, q *Queries) error {
+		verifyEmail, err := q.UseVerifyEmail(ctx, UseVerifyEmailParams{
+			ID:             arg.EmailID,
+			SecretCodeHash: arg.SecretCodeHash,
+		})
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return ErrVerifyEmailInvalid
+			}
+			return err
+		}
+
+		user, err = q.UpdateUserEmailVerified(ctx, verifyEmail.Username)
+		if err != nil {
+			return err
+		}
+
+		actor := AuditActorFrom(ctx)
+		actor.Username = user.Username
+		_, err = recordAudit(WithAuditActor(ctx, actor), q, AppendAuditLogParams{
+			Action: AuditEmailVerify,
+			Target: UserTarget(user.Username),
+			After: verifiedEmailSnapshot{
+				VerifyEmailID: verifyEmail.ID,
+				Email:         verifyEmail.Email,
+			},
+		})
+		return err
+	})
+
+	return user, err
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple_bank/util"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+// createUnverifiedUser creates a user through CreateUserTx and returns it
+// with the code mailed to verify its email.
+func createUnverifiedUser(t *testing.T, expiresIn time.Duration) (CreateUserTxResult, string) {
+	store := NewStore(testDB)
+	secretCode, err := util.NewSecret(32)
+	require.NoError(t, err)
+
+	result, err := store.CreateUserTx(context.Background(), CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username: util.RandomOwner(),
+			Password: util.RandomString(6),
+			FullName: util.RandomOwner(),
+			Email:    util.RandomEmail(),
+		},
+		SecretCodeHash: util.HashSecret(secretCode),
+		ExpiresAt:      time.Now().Add(expiresIn),
+	})
+	require.NoError(t, err)
+	require.False(t, result.User.IsEmailVerified)
+	require.Equal(t, result.User.Username, result.VerifyEmail.Username)
+	require.Equal(t, result.User.Email, result.VerifyEmail.Email)
+	require.False(t, result.VerifyEmail.IsUsed)
+
+	return result, secretCode
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB)
+	created, secretCode := createUnverifiedUser(t, time.Hour)
+	arg := VerifyEmailTxParams{EmailID: created.VerifyEmail.ID, SecretCodeHash: util.HashSecret(secretCode)}
+
+	wrongCode := VerifyEmailTxParams{EmailID: arg.EmailID, SecretCodeHash: util.HashSecret(util.RandomString(32))}
+	_, err := store.VerifyEmailTx(context.Background(), wrongCode)
+	require.ErrorIs(t, err, ErrVerifyEmailInvalid)
+
+	user, err := store.VerifyEmailTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, created.User.Username, user.Username)
+	require.True(t, user.IsEmailVerified)
+
+	// A verification works once.
+	_, err = store.VerifyEmailTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrVerifyEmailInvalid)
+
+	rows, err := store.ListAuditLog(context.Background(), ListAuditLogParams{
+		Target:   pgtype.Text{String: UserTarget(user.Username), Valid: true},
+		PageSize: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, AuditEmailVerify, rows[0].Action)
+	require.Equal(t, user.Username, rows[0].Actor)
+}
+
+func TestVerifyEmailTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+	created, secretCode := createUnverifiedUser(t, -time.Minute)
+
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:        created.VerifyEmail.ID,
+		SecretCodeHash: util.HashSecret(secretCode),
+	})
+	require.ErrorIs(t, err, ErrVerifyEmailInvalid)
+
+	user, err := store.GetUser(context.Background(), created.User.Username)
+	require.NoError(t, err)
+	require.False(t, user.IsEmailVerified)
+}
+
+// TestTransferTxEmailNotVerified tests that an unverified user cannot send
+// money, but can still receive it.
+func TestTransferTxEmailNotVerified(t *testing.T) {
+	store := NewStore(testDB)
+	created, _ := createUnverifiedUser(t, time.Hour)
+	unverified := createRandomAccount(t, created.User.Username, util.USD)
+	verified := createRandomAccount(t, createRandomUser(t).Username, util.USD)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: unverified.ID,
+		ToAccountID:   verified.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrEmailNotVerified)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: verified.ID,
+		ToAccountID:   unverified.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple_bank/util"
+
+	"github.com/stretchr/testify/require"
+)
+
+// createWebhookInTx creates a transfer.posted webhook for owner using the given Queries.
+func createWebhookInTx(t *testing.T, q *Queries, owner string) Webhook {
+	arg := CreateWebhookParams{
+		Owner:      owner,
+		Url:        "https://example.com/" + util.RandomString(6),
+		EventTypes: []string{EventTransferPosted},
+		Secret:     util.RandomString(32),
+	}
+	webhook, err := q.CreateWebhook(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, webhook)
+
+	require.Equal(t, arg.Owner, webhook.Owner)
+	require.Equal(t, arg.Url, webhook.Url)
+	require.Equal(t, arg.EventTypes, webhook.EventTypes)
+	require.Equal(t, arg.Secret, webhook.Secret)
+	require.NotZero(t, webhook.ID)
+	require.NotZero(t, webhook.CreatedAt)
+
+	return webhook
+}
+
+// TestCreateWebhook tests the creation of a webhook.
+func TestCreateWebhook(t *testing.T) {
+	runTestWithTransaction(t, func(t *testing.T, q *Queries) {
+		createWebhookInTx(t, q, createRandomUser(t).Username)
+	})
+}
+
+// TestListWebhooksForEvent tests that only subscribed owners of the event's accounts match.
+func TestListWebhooksForEvent(t *testing.T) {
+	runTestWithTransaction(t, func(t *testing.T, q *Queries) {
+		owner := createRandomUser(t).Username
+		other := createRandomUser(t).Username
+		account := createAccountInTx(t, q, owner, util.RandomCurrency())
+		subscribed := createWebhookInTx(t, q, owner)
+		createWebhookInTx(t, q, other)
+
+		webhooks, err := q.ListWebhooksForEvent(context.Background(), ListWebhooksForEventParams{
+			EventType:  EventTransferPosted,
+			AccountIds: []int64{account.ID},
+		})
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		require.Equal(t, subscribed.ID, webhooks[0].ID)
+
+		// Other holders of the account hear about it too.
+		_, err = q.CreateAccountHolder(context.Background(), CreateAccountHolderParams{
+			AccountID: account.ID,
+			Username:  other,
+			Role:      util.JointHolder,
+		})
+		require.NoError(t, err)
+
+		webhooks, err = q.ListWebhooksForEvent(context.Background(), ListWebhooksForEventParams{
+			EventType:  EventTransferPosted,
+			AccountIds: []int64{account.ID},
+		})
+		require.NoError(t, err)
+		require.Len(t, webhooks, 2)
+
+		webhooks, err = q.ListWebhooksForEvent(context.Background(), ListWebhooksForEventParams{
+			EventType:  EventAccountCreated,
+			AccountIds: []int64{account.ID},
+		})
+		require.NoError(t, err)
+		require.Empty(t, webhooks)
+	})
+}
+
+// TestWebhookDeliveryLifecycle tests queueing, attempting and replaying a delivery.
+func TestWebhookDeliveryLifecycle(t *testing.T) {
+	runTestWithTransaction(t, func(t *testing.T, q *Queries) {
+		event := createOutboxEventInTx(t, q)
+		webhook := createWebhookInTx(t, q, createRandomUser(t).Username)
+
+		arg := CreateWebhookDeliveryParams{
+			WebhookID: webhook.ID,
+			EventID:   event.ID,
+			EventType: event.EventType,
+			Payload:   event.Payload,
+		}
+		require.NoError(t, q.CreateWebhookDelivery(context.Background(), arg))
+		// Queueing the same event twice is a no-op.
+		require.NoError(t, q.CreateWebhookDelivery(context.Background(), arg))
+
+		deliveries, err := q.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+			WebhookID: webhook.ID,
+			Limit:     10,
+			Offset:    0,
+		})
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		delivery := deliveries[0]
+		require.Equal(t, "pending", delivery.Status)
+
+		failed, err := q.UpdateWebhookDeliveryAttempt(context.Background(), UpdateWebhookDeliveryAttemptParams{
+			ID:             delivery.ID,
+			Status:         "failed",
+			ResponseStatus: 500,
+			LastError:      "webhook responded with status 500",
+			NextAttemptAt:  time.Now(),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "failed", failed.Status)
+		require.Equal(t, int32(1), failed.Attempts)
+
+		replayed, err := q.ReplayWebhookDelivery(context.Background(), delivery.ID)
+		require.NoError(t, err)
+		require.Equal(t, "pending", replayed.Status)
+		require.Zero(t, replayed.Attempts)
+	})
+}
@@ -0,0 +1,225 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"simple_bank/apikey"
+	db "simple_bank/db/sqlc"
+	"simple_bank/logging"
+	"simple_bank/oauth"
+	"simple_bank/pb"
+	"simple_bank/token"
+	"simple_bank/util"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authorizationHeader = "authorization"
+	authorizationBearer = "bearer"
+	authorizationAPIKey = "apikey"
+)
+
+// Metadata carrying the second factor, which the request messages have no
+// fields for.
+const (
+	totpCodeHeader     = "x-totp-code"
+	recoveryCodeHeader = "x-recovery-code"
+)
+
+type payloadContextKey struct{}
+
+// publicMethods can be called without an access token.
+var publicMethods = map[string]bool{
+	pb.SimpleBank_CreateUser_FullMethodName: true,
+	pb.SimpleBank_LoginUser_FullMethodName:  true,
+}
+
+// delegatedScopes names the scope an API key or OAuth access token needs to
+// call each method that takes one. The other methods need a login token.
+var delegatedScopes = map[string]string{
+	pb.SimpleBank_GetAccount_FullMethodName:     util.ScopeAccountsRead,
+	pb.SimpleBank_ListAccounts_FullMethodName:   util.ScopeAccountsRead,
+	pb.SimpleBank_ListEntries_FullMethodName:    util.ScopeAccountsRead,
+	pb.SimpleBank_CreateTransfer_FullMethodName: util.ScopeTransfersCreate,
+}
+
+// AuthInterceptor verifies the bearer token, OAuth access token or API key
+// in the request metadata and stores its payload in the context for every method that is
+// not public.
+func (server *Server) AuthInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	payload, err := server.authorizeUser(ctx, info.FullMethod)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "unauthorized: %s", err)
+	}
+
+	ctx = withAuditActor(ctx, payload.Username)
+	return handler(context.WithValue(ctx, payloadContextKey{}, payload), req)
+}
+
+// withAuditActor attributes the actions the store audits under ctx to
+// username and the address of the calling peer.
+func withAuditActor(ctx context.Context, username string) context.Context {
+	actor := db.AuditActor{
+		Username:  username,
+		IP:        peerIP(ctx),
+		RequestID: logging.RequestID(ctx),
+	}
+	return db.WithAuditActor(ctx, actor)
+}
+
+// peerIP returns the IP address of the client, without its port.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	ip := p.Addr.String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return ip
+}
+
+// authorizeUser authenticates the caller of method. Errors that are not a
+// gRPC status mean the caller is unauthenticated.
+func (server *Server) authorizeUser(ctx context.Context, method string) (*token.Payload, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("missing metadata")
+	}
+
+	values := md.Get(authorizationHeader)
+	if len(values) == 0 {
+		return nil, fmt.Errorf("missing authorization header")
+	}
+
+	fields := strings.Fields(values[0])
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid authorization header format")
+	}
+
+	authType := strings.ToLower(fields[0])
+	switch authType {
+	case authorizationBearer:
+		if !oauth.IsToken(fields[1]) {
+			return server.verifyAccessToken(ctx, fields[1])
+		}
+		scope, ok := delegatedScopes[method]
+		if !ok {
+			return nil, fmt.Errorf("%s does not accept OAuth access tokens", method)
+		}
+		return server.authorizeOAuthToken(ctx, fields[1], scope)
+	case authorizationAPIKey:
+		scope, ok := delegatedScopes[method]
+		if !ok {
+			return nil, fmt.Errorf("%s does not accept API keys", method)
+		}
+		return server.authorizeAPIKey(ctx, fields[1], scope)
+	}
+	return nil, fmt.Errorf("unsupported authorization type: %s", authType)
+}
+
+// verifyAccessToken verifies an access token and, as in the HTTP API,
+// rejects it if it was issued before its user last changed their password.
+// The payload carries the user's current role rather than the token's.
+func (server *Server) verifyAccessToken(ctx context.Context, accessToken string) (*token.Payload, error) {
+	payload, err := server.tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := server.store.GetUserAuthState(ctx, payload.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, token.ErrRevokedToken
+		}
+		return nil, status.Errorf(codes.Internal, "failed to check access token: %s", err)
+	}
+	if payload.IssuedAt.Before(state.PasswordChangedAt) {
+		return nil, token.ErrRevokedToken
+	}
+	payload.Role = state.Role
+	return payload, nil
+}
+
+// authorizeAPIKey authenticates an API key that must have been granted
+// scope. As in the HTTP API, the key acts for its owner with the customer
+// role.
+func (server *Server) authorizeAPIKey(ctx context.Context, keyString, scope string) (*token.Payload, error) {
+	key, err := server.apiKeys.Authenticate(ctx, keyString)
+	if err != nil {
+		if errors.Is(err, apikey.ErrInvalidKey) || errors.Is(err, apikey.ErrRevokedKey) || errors.Is(err, apikey.ErrExpiredKey) {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "failed to check API key: %s", err)
+	}
+
+	if !apikey.HasScope(key, scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "API key lacks the %q scope", scope)
+	}
+
+	return &token.Payload{
+		Username:  key.Owner,
+		Role:      util.CustomerRole,
+		IssuedAt:  key.CreatedAt,
+		ExpiredAt: key.ExpiresAt.Time,
+	}, nil
+}
+
+// authorizeOAuthToken authenticates an OAuth access token that must have
+// been granted scope. It acts for the user who granted it with the customer
+// role.
+func (server *Server) authorizeOAuthToken(ctx context.Context, accessToken, scope string) (*token.Payload, error) {
+	grant, err := server.oauth.AuthenticateToken(ctx, accessToken)
+	if err != nil {
+		if errors.Is(err, oauth.ErrInvalidToken) || errors.Is(err, oauth.ErrExpiredToken) {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "failed to check OAuth access token: %s", err)
+	}
+
+	if !oauth.HasScope(grant, scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "OAuth access token lacks the %q scope", scope)
+	}
+
+	return &token.Payload{
+		Username:  grant.Username,
+		Role:      util.CustomerRole,
+		IssuedAt:  grant.CreatedAt,
+		ExpiredAt: grant.ExpiresAt,
+	}, nil
+}
+
+// metadataValue returns the first value of key in the request metadata, or "".
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// authPayload returns the payload stored by AuthInterceptor.
+func authPayload(ctx context.Context) *token.Payload {
+	payload, _ := ctx.Value(payloadContextKey{}).(*token.Payload)
+	return payload
+}
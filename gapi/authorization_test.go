@@ -0,0 +1,234 @@
+package gapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple_bank/apikey"
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/pb"
+	"simple_bank/util"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newContextWithAPIKey issues a key for owner with scopes and returns an
+// outgoing context carrying it, along with the stored key.
+func newContextWithAPIKey(t *testing.T, owner string, scopes ...string) (context.Context, db.ApiKey) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateAPIKeyTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateApiKeyParams) (db.ApiKey, error) {
+			return db.ApiKey{ID: 1, Owner: arg.Owner, Prefix: arg.Prefix, SecretHash: arg.SecretHash, Scopes: arg.Scopes}, nil
+		})
+	key, keyString, err := apikey.NewService(store).Create(context.Background(), apikey.CreateParams{
+		Owner:  owner,
+		Scopes: scopes,
+	})
+	require.NoError(t, err)
+
+	md := metadata.MD{
+		authorizationHeader: []string{"ApiKey " + keyString},
+	}
+	return metadata.NewOutgoingContext(context.Background(), md), key
+}
+
+func TestAuthInterceptorAPIKey(t *testing.T) {
+	account := db.Account{ID: util.RandomInt(1, 1000), Owner: util.RandomOwner(), Currency: util.USD}
+
+	t.Run("OK", func(t *testing.T) {
+		ctx, key := newContextWithAPIKey(t, account.Owner, util.ScopeAccountsRead)
+
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(key, nil)
+		store.EXPECT().TouchApiKey(gomock.Any(), gomock.Eq(key.ID)).Times(1)
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+		client := newTestClient(t, newTestServer(t, store))
+		res, err := client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID})
+		require.NoError(t, err)
+		require.Equal(t, account.ID, res.GetAccount().GetId())
+	})
+
+	t.Run("MissingScope", func(t *testing.T) {
+		ctx, key := newContextWithAPIKey(t, account.Owner, util.ScopeTransfersCreate)
+
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(key, nil)
+		store.EXPECT().TouchApiKey(gomock.Any(), gomock.Any()).Times(1)
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+
+		client := newTestClient(t, newTestServer(t, store))
+		_, err := client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("MethodWithoutScope", func(t *testing.T) {
+		ctx, _ := newContextWithAPIKey(t, account.Owner, util.ScopeAccountsRead, util.ScopeTransfersCreate)
+
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(0)
+		store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+
+		client := newTestClient(t, newTestServer(t, store))
+		_, err := client.CreateAccount(ctx, &pb.CreateAccountRequest{Currency: util.USD})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("InvalidKey", func(t *testing.T) {
+		ctx, _ := newContextWithAPIKey(t, account.Owner, util.ScopeAccountsRead)
+
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().GetApiKeyByPrefix(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, db.ErrRecordNotFound)
+
+		client := newTestClient(t, newTestServer(t, store))
+		_, err := client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+// newContextWithOAuthToken returns an outgoing context carrying an OAuth
+// access token for username with scopes, along with its stored grant.
+func newContextWithOAuthToken(username string, scopes ...string) (context.Context, db.OauthToken) {
+	accessToken := "sbo_" + util.RandomString(43)
+	grant := db.OauthToken{
+		TokenHash: util.HashSecret(accessToken),
+		ClientID:  util.RandomString(16),
+		Username:  username,
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+	}
+
+	md := metadata.MD{
+		authorizationHeader: []string{"Bearer " + accessToken},
+	}
+	return metadata.NewOutgoingContext(context.Background(), md), grant
+}
+
+func TestAuthInterceptorOAuthToken(t *testing.T) {
+	account := db.Account{ID: util.RandomInt(1, 1000), Owner: util.RandomOwner(), Currency: util.USD}
+
+	t.Run("OK", func(t *testing.T) {
+		ctx, grant := newContextWithOAuthToken(account.Owner, util.ScopeAccountsRead)
+
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().GetOauthToken(gomock.Any(), gomock.Eq(grant.TokenHash)).Times(1).Return(grant, nil)
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+		client := newTestClient(t, newTestServer(t, store))
+		res, err := client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID})
+		require.NoError(t, err)
+		require.Equal(t, account.ID, res.GetAccount().GetId())
+	})
+
+	t.Run("MissingScope", func(t *testing.T) {
+		ctx, grant := newContextWithOAuthToken(account.Owner, util.ScopeAccountsRead)
+
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().GetOauthToken(gomock.Any(), gomock.Any()).Times(1).Return(grant, nil)
+		store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+		client := newTestClient(t, newTestServer(t, store))
+		_, err := client.CreateTransfer(ctx, &pb.CreateTransferRequest{
+			FromAccountId: account.ID,
+			ToAccountId:   account.ID + 1,
+			Amount:        1,
+			Currency:      util.USD,
+		})
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("MethodWithoutScope", func(t *testing.T) {
+		ctx, _ := newContextWithOAuthToken(account.Owner, util.ScopeAccountsRead, util.ScopeTransfersCreate)
+
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().GetOauthToken(gomock.Any(), gomock.Any()).Times(0)
+		store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).Times(0)
+
+		client := newTestClient(t, newTestServer(t, store))
+		_, err := client.CreateAccount(ctx, &pb.CreateAccountRequest{Currency: util.USD})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		ctx, grant := newContextWithOAuthToken(account.Owner, util.ScopeAccountsRead)
+		grant.ExpiresAt = time.Now().Add(-time.Second)
+
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().GetOauthToken(gomock.Any(), gomock.Any()).Times(1).Return(grant, nil)
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+
+		client := newTestClient(t, newTestServer(t, store))
+		_, err := client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestAuthInterceptorPasswordChanged(t *testing.T) {
+	account := db.Account{ID: util.RandomInt(1, 1000), Owner: util.RandomOwner(), Currency: util.USD}
+
+	t.Run("IssuedAfterChange", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().
+			GetUserAuthState(gomock.Any(), gomock.Eq(account.Owner)).
+			Times(1).
+			Return(db.GetUserAuthStateRow{Role: util.CustomerRole, PasswordChangedAt: time.Now().Add(-time.Hour)}, nil)
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+		server := newTestServer(t, store)
+		client := newTestClient(t, server)
+		ctx := newContextWithBearerToken(t, server, account.Owner, time.Minute)
+		_, err := client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID})
+		require.NoError(t, err)
+	})
+
+	t.Run("IssuedBeforeChange", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().
+			GetUserAuthState(gomock.Any(), gomock.Eq(account.Owner)).
+			Times(1).
+			Return(db.GetUserAuthStateRow{Role: util.CustomerRole, PasswordChangedAt: time.Now().Add(time.Second)}, nil)
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+
+		server := newTestServer(t, store)
+		client := newTestClient(t, server)
+		ctx := newContextWithBearerToken(t, server, account.Owner, time.Minute)
+		_, err := client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("UserDeleted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().
+			GetUserAuthState(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.GetUserAuthStateRow{}, db.ErrRecordNotFound)
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+
+		server := newTestServer(t, store)
+		client := newTestClient(t, server)
+		ctx := newContextWithBearerToken(t, server, account.Owner, time.Minute)
+		_, err := client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
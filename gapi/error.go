@@ -0,0 +1,40 @@
+package gapi
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// validateRequest checks req against its binding tags.
+func validateRequest(req any) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return invalidArgumentError(err)
+	}
+	return nil
+}
+
+func invalidArgumentError(err error) error {
+	badRequest := &errdetails.BadRequest{}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		for _, fieldErr := range validationErrors {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fieldErr.Field(),
+				Description: fieldErr.Error(),
+			})
+		}
+	}
+
+	statusInvalid := status.New(codes.InvalidArgument, err.Error())
+	statusDetails, detailsErr := statusInvalid.WithDetails(badRequest)
+	if detailsErr != nil {
+		return statusInvalid.Err()
+	}
+	return statusDetails.Err()
+}
@@ -0,0 +1,83 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/mail"
+	"simple_bank/pb"
+	"simple_bank/util"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestServer(t *testing.T, store db.Store) *Server {
+	// Let every access token through the password change check as a
+	// customer's, unless a test expects its own lookups first.
+	if mock, ok := store.(*mockdb.MockStore); ok {
+		mock.EXPECT().
+			GetUserAuthState(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(db.GetUserAuthStateRow{Role: util.CustomerRole}, nil)
+	}
+
+	config := util.Config{
+		TokenSymmetricKey:   util.RandomString(32),
+		AccessTokenDuration: time.Minute,
+		TOTPEncryptionKey:   util.RandomString(32),
+	}
+
+	server, err := NewServer(config, store, mail.LogMailer{})
+	require.NoError(t, err)
+
+	return server
+}
+
+// newTestClient serves server over an in-memory listener and returns a client for it.
+func newTestClient(t *testing.T, server *Server) pb.SimpleBankClient {
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(server.AuthInterceptor))
+	pb.RegisterSimpleBankServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewSimpleBankClient(conn)
+}
+
+func newContextWithBearerToken(t *testing.T, server *Server, username string, duration time.Duration) context.Context {
+	accessToken, _, err := server.tokenMaker.CreateToken(username, util.CustomerRole, duration)
+	require.NoError(t, err)
+
+	bearerToken := fmt.Sprintf("%s %s", authorizationBearer, accessToken)
+	md := metadata.MD{
+		authorizationHeader: []string{bearerToken},
+	}
+	return metadata.NewOutgoingContext(context.Background(), md)
+}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
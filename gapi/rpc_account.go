@@ -0,0 +1,128 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/pb"
+	"simple_bank/util"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// createAccountRequest mirrors the binding tags of the HTTP createAccountRequest.
+// The owner is always the authenticated user, and the balance always 0.
+type createAccountRequest struct {
+	Currency string `binding:"required,currency"`
+}
+
+func (server *Server) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	err := validateRequest(createAccountRequest{
+		Currency: req.GetCurrency(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	arg := db.CreateAccountParams{
+		Owner:    authPayload(ctx).Username,
+		Balance:  0,
+		Currency: req.GetCurrency(),
+	}
+
+	account, err := server.store.CreateAccountTx(ctx, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrCurrencyDisabled) {
+			return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+		}
+		if db.ErrorCode(err) == db.UniqueViolation {
+			return nil, status.Errorf(codes.AlreadyExists, "account already exists: %s", err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create account: %s", err)
+	}
+
+	return &pb.CreateAccountResponse{Account: convertAccount(account)}, nil
+}
+
+// getAccountRequest mirrors the binding tags of the HTTP getAccountRequest.
+type getAccountRequest struct {
+	ID int64 `binding:"required,min=1"`
+}
+
+func (server *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	if err := validateRequest(getAccountRequest{ID: req.GetId()}); err != nil {
+		return nil, err
+	}
+
+	account, _, err := server.authorizedAccount(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetAccountResponse{Account: convertAccount(account)}, nil
+}
+
+// listAccountsRequest mirrors the binding tags of the HTTP listAccountsRequest.
+type listAccountsRequest struct {
+	PageID   int32 `binding:"required,min=1"`
+	PageSize int32 `binding:"required,min=5,max=10"`
+}
+
+func (server *Server) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	err := validateRequest(listAccountsRequest{
+		PageID:   req.GetPageId(),
+		PageSize: req.GetPageSize(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	arg := db.ListAccountsParams{
+		Username: authPayload(ctx).Username,
+		Limit:    req.GetPageSize(),
+		Offset:   (req.GetPageId() - 1) * req.GetPageSize(),
+	}
+
+	accounts, err := server.store.ListAccounts(ctx, arg)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list accounts: %s", err)
+	}
+
+	rsp := &pb.ListAccountsResponse{}
+	for _, account := range accounts {
+		rsp.Accounts = append(rsp.Accounts, convertAccount(account))
+	}
+	return rsp, nil
+}
+
+// authorizedAccount loads an account and the role the authenticated user
+// holds it in.
+func (server *Server) authorizedAccount(ctx context.Context, id int64) (db.Account, string, error) {
+	account, err := server.store.GetAccount(ctx, id)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return account, "", status.Errorf(codes.NotFound, "account [%d] not found", id)
+		}
+		return account, "", status.Errorf(codes.Internal, "failed to get account: %s", err)
+	}
+
+	username := authPayload(ctx).Username
+	if account.Owner == username {
+		return account, util.PrimaryHolder, nil
+	}
+
+	holder, err := server.store.GetAccountHolder(ctx, db.GetAccountHolderParams{
+		AccountID: id,
+		Username:  username,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return account, "", status.Errorf(codes.PermissionDenied, "account [%d] doesn't belong to the authenticated user", id)
+		}
+		return account, "", status.Errorf(codes.Internal, "failed to get account holder: %s", err)
+	}
+
+	return account, holder.Role, nil
+}
@@ -0,0 +1,300 @@
+package gapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/pb"
+	"simple_bank/twofactor"
+	"simple_bank/util"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetAccountRPC(t *testing.T) {
+	account := db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    util.RandomOwner(),
+		Balance:  util.RandomMoney(),
+		Currency: util.RandomCurrency(),
+	}
+
+	testCases := []struct {
+		name          string
+		req           *pb.GetAccountRequest
+		buildStubs    func(store *mockdb.MockStore)
+		buildContext  func(t *testing.T, server *Server) context.Context
+		checkResponse func(t *testing.T, res *pb.GetAccountResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.GetAccountRequest{Id: account.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithBearerToken(t, server, account.Owner, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.GetAccountResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account.ID, res.GetAccount().GetId())
+				require.Equal(t, account.Owner, res.GetAccount().GetOwner())
+				require.Equal(t, account.Balance, res.GetAccount().GetBalance())
+			},
+		},
+		{
+			name: "NoAuthorization",
+			req:  &pb.GetAccountRequest{Id: account.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, res *pb.GetAccountResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name: "ExpiredToken",
+			req:  &pb.GetAccountRequest{Id: account.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithBearerToken(t, server, account.Owner, -time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.GetAccountResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name: "JointHolder",
+			req:  &pb.GetAccountRequest{Id: account.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					GetAccountHolder(gomock.Any(), gomock.Eq(db.GetAccountHolderParams{
+						AccountID: account.ID,
+						Username:  "joint",
+					})).
+					Times(1).
+					Return(db.AccountHolder{AccountID: account.ID, Username: "joint", Role: util.JointHolder}, nil)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithBearerToken(t, server, "joint", time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.GetAccountResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account.ID, res.GetAccount().GetId())
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			req:  &pb.GetAccountRequest{Id: account.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					GetAccountHolder(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.AccountHolder{}, db.ErrRecordNotFound)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithBearerToken(t, server, "someoneelse", time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.GetAccountResponse, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+		{
+			name: "NotFound",
+			req:  &pb.GetAccountRequest{Id: account.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, db.ErrRecordNotFound)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithBearerToken(t, server, account.Owner, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.GetAccountResponse, err error) {
+				require.Equal(t, codes.NotFound, status.Code(err))
+			},
+		},
+		{
+			name: "InvalidID",
+			req:  &pb.GetAccountRequest{Id: 0},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithBearerToken(t, server, account.Owner, time.Minute)
+			},
+			checkResponse: func(t *testing.T, res *pb.GetAccountResponse, err error) {
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				require.Equal(t, codes.InvalidArgument, st.Code())
+				require.Len(t, st.Details(), 1)
+				badRequest := st.Details()[0].(*errdetails.BadRequest)
+				require.Equal(t, "ID", badRequest.GetFieldViolations()[0].GetField())
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := newTestClient(t, server)
+			res, err := client.GetAccount(tc.buildContext(t, server), tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
+
+func TestCreateTransferRPCCurrencyMismatch(t *testing.T) {
+	owner := util.RandomOwner()
+	from := db.Account{ID: 1, Owner: owner, Currency: util.USD}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	client := newTestClient(t, server)
+	_, err := client.CreateTransfer(newContextWithBearerToken(t, server, owner, time.Minute), &pb.CreateTransferRequest{
+		FromAccountId: from.ID,
+		ToAccountId:   2,
+		Amount:        10,
+		Currency:      util.EUR,
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestCreateTransferRPCViewerHolder(t *testing.T) {
+	from := db.Account{ID: 1, Owner: util.RandomOwner(), Currency: util.USD}
+	viewer := util.RandomOwner()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+	store.EXPECT().
+		GetAccountHolder(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.AccountHolder{AccountID: from.ID, Username: viewer, Role: util.ViewerHolder}, nil)
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	client := newTestClient(t, server)
+	_, err := client.CreateTransfer(newContextWithBearerToken(t, server, viewer, time.Minute), &pb.CreateTransferRequest{
+		FromAccountId: from.ID,
+		ToAccountId:   2,
+		Amount:        10,
+		Currency:      util.USD,
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestCreateTransferRPCAccountFrozen(t *testing.T) {
+	owner := util.RandomOwner()
+	from := db.Account{ID: 1, Owner: owner, Currency: util.USD}
+	to := db.Account{ID: 2, Owner: util.RandomOwner(), Currency: util.USD, Status: db.AccountFrozen}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(from, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+	store.EXPECT().
+		TransferTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.TransferTxResult{}, db.ErrAccountFrozen)
+
+	server := newTestServer(t, store)
+	client := newTestClient(t, server)
+	_, err := client.CreateTransfer(newContextWithBearerToken(t, server, owner, time.Minute), &pb.CreateTransferRequest{
+		FromAccountId: from.ID,
+		ToAccountId:   to.ID,
+		Amount:        10,
+		Currency:      util.USD,
+	})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestCreateTransferRPCStepUp(t *testing.T) {
+	owner := util.RandomOwner()
+	from := db.Account{ID: 1, Owner: owner, Currency: util.USD}
+	to := db.Account{ID: 2, Owner: util.RandomOwner(), Currency: util.USD}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	server.config.TransferStepUpThreshold = 100
+	client := newTestClient(t, server)
+
+	// Enroll through the server's own service so the secret is sealed with its key.
+	var totp db.TotpSecret
+	store.EXPECT().
+		CreateTotpSecret(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, arg db.CreateTotpSecretParams) (db.TotpSecret, error) {
+			totp = db.TotpSecret{Username: arg.Username, SecretEncrypted: arg.SecretEncrypted}
+			return totp, nil
+		})
+	enrollment, err := server.twoFactor.Enroll(context.Background(), owner)
+	require.NoError(t, err)
+	totp.ConfirmedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(2).Return(from, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(2).Return(to, nil)
+	store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(owner)).Times(2).Return(totp, nil)
+	store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(1).Return(totp, nil)
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1)
+
+	req := &pb.CreateTransferRequest{
+		FromAccountId: from.ID,
+		ToAccountId:   to.ID,
+		Amount:        101,
+		Currency:      util.USD,
+	}
+	ctx := newContextWithBearerToken(t, server, owner, time.Minute)
+	_, err = client.CreateTransfer(ctx, req)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	code, err := twofactor.Code(enrollment.Secret, twofactor.Step(time.Now()))
+	require.NoError(t, err)
+	_, err = client.CreateTransfer(metadata.AppendToOutgoingContext(ctx, totpCodeHeader, code), req)
+	require.NoError(t, err)
+}
@@ -0,0 +1,47 @@
+package gapi
+
+import (
+	"context"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type listEntriesRequest struct {
+	AccountID int64 `binding:"required,min=1"`
+	PageID    int32 `binding:"required,min=1"`
+	PageSize  int32 `binding:"required,min=5,max=10"`
+}
+
+func (server *Server) ListEntries(ctx context.Context, req *pb.ListEntriesRequest) (*pb.ListEntriesResponse, error) {
+	err := validateRequest(listEntriesRequest{
+		AccountID: req.GetAccountId(),
+		PageID:    req.GetPageId(),
+		PageSize:  req.GetPageSize(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := server.authorizedAccount(ctx, req.GetAccountId()); err != nil {
+		return nil, err
+	}
+
+	entries, err := server.store.ListEntries(ctx, db.ListEntriesParams{
+		AccountID: req.GetAccountId(),
+		Limit:     req.GetPageSize(),
+		Offset:    (req.GetPageId() - 1) * req.GetPageSize(),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list entries: %s", err)
+	}
+
+	rsp := &pb.ListEntriesResponse{}
+	for _, entry := range entries {
+		rsp.Entries = append(rsp.Entries, convertEntry(entry))
+	}
+	return rsp, nil
+}
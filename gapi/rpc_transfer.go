@@ -0,0 +1,109 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/pb"
+	"simple_bank/twofactor"
+	"simple_bank/util"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// createTransferRequest mirrors the binding tags of the HTTP createTransferRequest.
+type createTransferRequest struct {
+	FromAccountID int64  `binding:"required,min=1"`
+	ToAccountID   int64  `binding:"required,gt=0"`
+	Amount        int64  `binding:"required,min=1"`
+	Currency      string `binding:"required,currency"`
+}
+
+func (server *Server) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+	err := validateRequest(createTransferRequest{
+		FromAccountID: req.GetFromAccountId(),
+		ToAccountID:   req.GetToAccountId(),
+		Amount:        req.GetAmount(),
+		Currency:      req.GetCurrency(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fromAccount, role, err := server.authorizedAccount(ctx, req.GetFromAccountId())
+	if err != nil {
+		return nil, err
+	}
+	if !util.CanTransfer(role) {
+		return nil, status.Errorf(codes.PermissionDenied, "%s holders cannot transfer from account [%d]", role, fromAccount.ID)
+	}
+	if err := checkCurrency(fromAccount, req.GetCurrency()); err != nil {
+		return nil, err
+	}
+
+	toAccount, err := server.store.GetAccount(ctx, req.GetToAccountId())
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "account [%d] not found", req.GetToAccountId())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get account: %s", err)
+	}
+	if err := checkCurrency(toAccount, req.GetCurrency()); err != nil {
+		return nil, err
+	}
+	if err := server.checkStepUp(ctx, req.GetAmount()); err != nil {
+		return nil, err
+	}
+
+	result, err := server.store.TransferTx(ctx, db.TransferTxParams{
+		FromAccountID: req.GetFromAccountId(),
+		ToAccountID:   req.GetToAccountId(),
+		Amount:        req.GetAmount(),
+		Sender:        authPayload(ctx).Username,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrAccountFrozen) || errors.Is(err, db.ErrEmailNotVerified) {
+			return nil, status.Errorf(codes.FailedPrecondition, "%s", err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to transfer: %s", err)
+	}
+
+	rsp := &pb.CreateTransferResponse{
+		Transfer:    convertTransfer(result.Transfer),
+		FromAccount: convertAccount(result.FromAccount),
+		ToAccount:   convertAccount(result.ToAccount),
+		FromEntry:   convertEntry(result.FromEntry),
+		ToEntry:     convertEntry(result.ToEntry),
+	}
+	return rsp, nil
+}
+
+func checkCurrency(account db.Account, currency string) error {
+	if account.Currency != currency {
+		return status.Errorf(codes.InvalidArgument, "account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
+	}
+	return nil
+}
+
+// checkStepUp requires a TOTP code in the x-totp-code metadata for
+// transfers above TRANSFER_STEP_UP_THRESHOLD.
+func (server *Server) checkStepUp(ctx context.Context, amount int64) error {
+	threshold := int64(server.config.TransferStepUpThreshold)
+	if threshold == 0 || amount <= threshold {
+		return nil
+	}
+
+	err := server.twoFactor.VerifyStepUp(ctx, authPayload(ctx).Username, metadataValue(ctx, totpCodeHeader))
+	if err != nil {
+		switch {
+		case errors.Is(err, twofactor.ErrNotEnabled):
+			return status.Errorf(codes.FailedPrecondition, "two-factor authentication must be enabled for transfers of this amount")
+		case errors.Is(err, twofactor.ErrCodeRequired), errors.Is(err, twofactor.ErrCodeInvalid):
+			return status.Errorf(codes.Unauthenticated, "%s", err)
+		}
+		return status.Errorf(codes.Internal, "failed to check second factor: %s", err)
+	}
+	return nil
+}
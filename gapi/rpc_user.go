@@ -0,0 +1,182 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/lockout"
+	"simple_bank/mail"
+	"simple_bank/pb"
+	"simple_bank/twofactor"
+	"simple_bank/util"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// secretCodeBytes is the entropy of the code mailed to verify an email address.
+const secretCodeBytes = 32
+
+// createUserRequest mirrors the binding tags of the HTTP createUserRequest.
+type createUserRequest struct {
+	Username string `binding:"required,alphanum"`
+	Password string `binding:"required,min=6"`
+	FullName string `binding:"required"`
+	Email    string `binding:"required,email"`
+}
+
+func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	err := validateRequest(createUserRequest{
+		Username: req.GetUsername(),
+		Password: req.GetPassword(),
+		FullName: req.GetFullName(),
+		Email:    req.GetEmail(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := util.HashPassword(req.GetPassword())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to hash password: %s", err)
+	}
+
+	secretCode, err := util.NewSecret(secretCodeBytes)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create verification code: %s", err)
+	}
+
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username: req.GetUsername(),
+			Password: hashedPassword,
+			FullName: req.GetFullName(),
+			Email:    req.GetEmail(),
+		},
+		SecretCodeHash: util.HashSecret(secretCode),
+		ExpiresAt:      time.Now().Add(server.config.EmailVerifyDuration),
+	}
+
+	result, err := server.store.CreateUserTx(ctx, arg)
+	if err != nil {
+		if db.ErrorCode(err) == db.UniqueViolation {
+			return nil, status.Errorf(codes.AlreadyExists, "user already exists: %s", err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create user: %s", err)
+	}
+
+	// As in the HTTP API, the link is only mailed once the user is committed.
+	user, verifyEmail := result.User, result.VerifyEmail
+	msg := mail.NewVerifyEmailMessage(server.config.PublicURL, user.Email, user.FullName,
+		verifyEmail.ID, secretCode, verifyEmail.ExpiresAt)
+	if err := server.mailer.Send(ctx, msg); err != nil {
+		slog.ErrorContext(ctx, "cannot send verification email", "username", user.Username, "err", err)
+	}
+
+	return &pb.CreateUserResponse{User: convertUser(user)}, nil
+}
+
+// loginSnapshot mirrors the HTTP loginSnapshot.
+type loginSnapshot struct {
+	SecondFactor string `json:"second_factor"`
+}
+
+// loginUserRequest mirrors the binding tags of the HTTP loginUserRequest.
+type loginUserRequest struct {
+	Username string `binding:"required,alphanum"`
+	Password string `binding:"required,min=6"`
+}
+
+// incorrectLogin is the only error a login with an unknown username or a
+// wrong password gets.
+const incorrectLogin = "incorrect username or password"
+
+func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	err := validateRequest(loginUserRequest{
+		Username: req.GetUsername(),
+		Password: req.GetPassword(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Locked out logins are refused before the password is looked at, as
+	// in the HTTP API.
+	_, err = server.loginGuard.Check(ctx, req.GetUsername(), peerIP(ctx))
+	if err != nil {
+		if errors.Is(err, lockout.ErrLockedOut) {
+			return nil, status.Errorf(codes.ResourceExhausted, "%s", err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to check login lockout: %s", err)
+	}
+
+	// As in the HTTP API, an unknown username fails like a wrong password
+	// and takes as long.
+	user, err := server.store.GetUser(ctx, req.GetUsername())
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			util.CheckUnknownUserPassword(req.GetPassword())
+			return nil, server.loginFailed(ctx, req.GetUsername(), status.Error(codes.Unauthenticated, incorrectLogin))
+		}
+		return nil, status.Errorf(codes.Internal, "failed to find user: %s", err)
+	}
+
+	err = util.CheckPassword(req.GetPassword(), user.Password)
+	if err != nil {
+		return nil, server.loginFailed(ctx, req.GetUsername(), status.Error(codes.Unauthenticated, incorrectLogin))
+	}
+
+	secondFactor, err := server.twoFactor.VerifyLogin(ctx, user.Username,
+		metadataValue(ctx, totpCodeHeader), metadataValue(ctx, recoveryCodeHeader))
+	if err != nil {
+		switch {
+		case errors.Is(err, twofactor.ErrCodeRequired):
+			return nil, status.Errorf(codes.Unauthenticated, "%s", err)
+		case errors.Is(err, twofactor.ErrCodeInvalid):
+			return nil, server.loginFailed(ctx, req.GetUsername(), status.Errorf(codes.Unauthenticated, "%s", err))
+		}
+		return nil, status.Errorf(codes.Internal, "failed to check second factor: %s", err)
+	}
+
+	if err := server.loginGuard.Succeed(ctx, user.Username); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to reset failed logins: %s", err)
+	}
+
+	audit := db.AppendAuditLogParams{
+		Action: db.AuditUserLogin,
+		Target: db.UserTarget(user.Username),
+	}
+	if secondFactor != "" {
+		audit.After = loginSnapshot{SecondFactor: secondFactor}
+	}
+	_, err = server.store.AppendAuditLog(withAuditActor(ctx, user.Username), audit)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to record login: %s", err)
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.AccessTokenDuration)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create access token: %s", err)
+	}
+
+	rsp := &pb.LoginUserResponse{
+		User:                 convertUser(user),
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: timestamppb.New(accessPayload.ExpiredAt),
+	}
+	return rsp, nil
+}
+
+// loginFailed counts a failed login for username from the client's IP and
+// returns err, the status to fail the login with.
+func (server *Server) loginFailed(ctx context.Context, username string, err error) error {
+	// Lockouts are audited as the system, from the client's IP.
+	if failErr := server.loginGuard.Fail(withAuditActor(ctx, ""), username, peerIP(ctx)); failErr != nil {
+		return status.Errorf(codes.Internal, "failed to record failed login: %s", failErr)
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package gapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/lockout"
+	"simple_bank/pb"
+	"simple_bank/util"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testLockoutPolicy = lockout.Policy{MaxUserFailures: 3, MaxIPFailures: 10, Lockout: time.Minute, MaxLockout: time.Hour}
+
+func TestLoginUserRPCLockedOut(t *testing.T) {
+	username := util.RandomOwner()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	lockedUntil := pgtype.Timestamptz{Time: time.Now().Add(time.Minute), Valid: true}
+	store.EXPECT().
+		ListLoginLockouts(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.LoginAttempt{{Key: db.UserTarget(username), Failures: 3, LockedUntil: lockedUntil}}, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	server.loginGuard = lockout.NewGuard(store, testLockoutPolicy)
+	client := newTestClient(t, server)
+	_, err := client.LoginUser(context.Background(), &pb.LoginUserRequest{
+		Username: username,
+		Password: util.RandomString(6),
+	})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestLoginUserRPCIncorrectPassword(t *testing.T) {
+	password := util.RandomString(6)
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+	user := db.User{Username: util.RandomOwner(), Password: hashedPassword}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListLoginLockouts(gomock.Any(), gomock.Any()).Times(1)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	// One failure for the username and one for the client IP.
+	store.EXPECT().RecordLoginFailureTx(gomock.Any(), gomock.Any()).Times(2)
+	store.EXPECT().DeleteLoginAttempt(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	server.loginGuard = lockout.NewGuard(store, testLockoutPolicy)
+	client := newTestClient(t, server)
+	_, err = client.LoginUser(context.Background(), &pb.LoginUserRequest{
+		Username: user.Username,
+		Password: "incorrect",
+	})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.Equal(t, incorrectLogin, status.Convert(err).Message())
+}
+
+func TestLoginUserRPCUnknownUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListLoginLockouts(gomock.Any(), gomock.Any()).Times(1)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, db.ErrRecordNotFound)
+	store.EXPECT().RecordLoginFailureTx(gomock.Any(), gomock.Any()).Times(2)
+
+	server := newTestServer(t, store)
+	server.loginGuard = lockout.NewGuard(store, testLockoutPolicy)
+	client := newTestClient(t, server)
+	_, err := client.LoginUser(context.Background(), &pb.LoginUserRequest{
+		Username: util.RandomOwner(),
+		Password: util.RandomString(6),
+	})
+
+	// Indistinguishable from a wrong password.
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.Equal(t, incorrectLogin, status.Convert(err).Message())
+}
@@ -0,0 +1,66 @@
+package gapi
+
+import (
+	"fmt"
+
+	"simple_bank/apikey"
+	db "simple_bank/db/sqlc"
+	"simple_bank/lockout"
+	"simple_bank/mail"
+	"simple_bank/oauth"
+	"simple_bank/pb"
+	"simple_bank/token"
+	"simple_bank/twofactor"
+	"simple_bank/util"
+	"simple_bank/val"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Server serves gRPC requests for our banking service.
+type Server struct {
+	pb.UnimplementedSimpleBankServer
+	config     util.Config
+	store      db.Store
+	tokenMaker token.Maker
+	mailer     mail.Mailer
+	twoFactor  *twofactor.Service
+	loginGuard *lockout.Guard
+	apiKeys    *apikey.Service
+	oauth      *oauth.Server
+}
+
+// NewServer creates a new gRPC server.
+func NewServer(config util.Config, store db.Store, mailer mail.Mailer) (*Server, error) {
+	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token maker: %w", err)
+	}
+
+	twoFactor, err := twofactor.NewService(store, config.TOTPEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create two-factor service: %w", err)
+	}
+
+	// Requests are checked by the same validator engine and custom
+	// validations as the Gin binding tags of the HTTP API.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		val.RegisterValidations(v)
+	}
+
+	server := &Server{
+		config:     config,
+		store:      store,
+		tokenMaker: tokenMaker,
+		mailer:     mailer,
+		twoFactor:  twoFactor,
+		loginGuard: lockout.NewGuard(store, lockout.NewPolicy(config)),
+		apiKeys:    apikey.NewService(store),
+		oauth: oauth.NewServer(store, oauth.Options{
+			CodeDuration:  config.OAuthCodeDuration,
+			TokenDuration: config.OAuthTokenDuration,
+		}),
+	}
+	return server, nil
+}
@@ -0,0 +1,72 @@
+// Package ledger checks that account balances, entries and transfers agree.
+package ledger
+
+import (
+	"context"
+	"fmt"
+
+	db "simple_bank/db/sqlc"
+)
+
+// Report is the outcome of Verify.
+type Report struct {
+	Accounts   int64                        `json:"accounts"`
+	Entries    int64                        `json:"entries"`
+	Transfers  int64                        `json:"transfers"`
+	EntrySum   int64                        `json:"entry_sum"`
+	Mismatches []db.ListLedgerMismatchesRow `json:"mismatches"`
+	Problems   []string                     `json:"problems"`
+}
+
+// OK reports whether the ledger is consistent.
+func (report Report) OK() bool {
+	return len(report.Problems) == 0
+}
+
+// Verify checks the double-entry invariants the store maintains:
+//
+//   - every account balance equals the sum of its entries and the net
+//     amount of the transfers into and out of it;
+//   - every transfer has exactly two entries, so there are twice as many
+//     entries as transfers;
+//   - entries sum to zero, since money only moves between accounts.
+//
+// Accounts always open empty and are never credited outside TransferTx, so
+// any balance set directly in the database shows up as a mismatch.
+func Verify(ctx context.Context, store db.Store) (Report, error) {
+	totals, err := store.GetLedgerTotals(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("cannot get ledger totals: %w", err)
+	}
+
+	mismatches, err := store.ListLedgerMismatches(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("cannot list ledger mismatches: %w", err)
+	}
+
+	report := Report{
+		Accounts:   totals.AccountCount,
+		Entries:    totals.EntryCount,
+		Transfers:  totals.TransferCount,
+		EntrySum:   totals.EntrySum,
+		Mismatches: mismatches,
+		Problems:   []string{},
+	}
+
+	for _, m := range mismatches {
+		report.Problems = append(report.Problems, fmt.Sprintf(
+			"account %d (%s): balance %d, entries total %d, transfers net %d",
+			m.ID, m.Currency, m.Balance, m.EntriesTotal, m.TransfersNet))
+	}
+	if totals.EntryCount != 2*totals.TransferCount {
+		report.Problems = append(report.Problems, fmt.Sprintf(
+			"%d entries for %d transfers, want %d",
+			totals.EntryCount, totals.TransferCount, 2*totals.TransferCount))
+	}
+	if totals.EntrySum != 0 {
+		report.Problems = append(report.Problems, fmt.Sprintf(
+			"entries sum to %d, want 0", totals.EntrySum))
+	}
+
+	return report, nil
+}
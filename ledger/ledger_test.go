@@ -0,0 +1,68 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	mismatch := db.ListLedgerMismatchesRow{ID: 7, Currency: "USD", Balance: 100, EntriesTotal: 0, TransfersNet: 0}
+
+	testCases := []struct {
+		name         string
+		totals       db.GetLedgerTotalsRow
+		mismatches   []db.ListLedgerMismatchesRow
+		wantProblems int
+	}{
+		{
+			name:       "Consistent",
+			totals:     db.GetLedgerTotalsRow{AccountCount: 3, EntryCount: 4, EntrySum: 0, TransferCount: 2},
+			mismatches: []db.ListLedgerMismatchesRow{},
+		},
+		{
+			name:         "BalanceMismatch",
+			totals:       db.GetLedgerTotalsRow{AccountCount: 3, EntryCount: 4, EntrySum: 0, TransferCount: 2},
+			mismatches:   []db.ListLedgerMismatchesRow{mismatch},
+			wantProblems: 1,
+		},
+		{
+			name:         "OrphanEntry",
+			totals:       db.GetLedgerTotalsRow{AccountCount: 3, EntryCount: 5, EntrySum: 100, TransferCount: 2},
+			mismatches:   []db.ListLedgerMismatchesRow{mismatch},
+			wantProblems: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetLedgerTotals(gomock.Any()).Times(1).Return(tc.totals, nil)
+			store.EXPECT().ListLedgerMismatches(gomock.Any()).Times(1).Return(tc.mismatches, nil)
+
+			report, err := Verify(context.Background(), store)
+			require.NoError(t, err)
+			require.Len(t, report.Problems, tc.wantProblems)
+			require.Equal(t, tc.wantProblems == 0, report.OK())
+			require.Equal(t, tc.totals.EntryCount, report.Entries)
+			require.Equal(t, tc.totals.TransferCount, report.Transfers)
+		})
+	}
+}
+
+func TestVerifyError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetLedgerTotals(gomock.Any()).Times(1).Return(db.GetLedgerTotalsRow{}, errors.New("connection refused"))
+	store.EXPECT().ListLedgerMismatches(gomock.Any()).Times(0)
+
+	_, err := Verify(context.Background(), store)
+	require.ErrorContains(t, err, "connection refused")
+}
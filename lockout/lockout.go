@@ -0,0 +1,139 @@
+// Package lockout protects login against password guessing. It counts
+// failed logins per username and per client IP, and locks either out for
+// a time that doubles with every further failure.
+package lockout
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+)
+
+// ErrLockedOut is returned by Check while a username or IP is locked out.
+var ErrLockedOut = errors.New("too many failed logins, try again later")
+
+// maxShift bounds the exponent of the backoff so it cannot overflow.
+const maxShift = 30
+
+// Policy configures when and for how long logins are locked out.
+type Policy struct {
+	// MaxUserFailures and MaxIPFailures are the consecutive failures after
+	// which a username or IP is locked out. 0 disables the limit.
+	MaxUserFailures int32
+	MaxIPFailures   int32
+	// Lockout is the first lockout; each further failure doubles it, up to
+	// MaxLockout. Failures are forgotten after MaxLockout without any.
+	Lockout    time.Duration
+	MaxLockout time.Duration
+}
+
+// NewPolicy returns the Policy set by the LOGIN_* config keys.
+func NewPolicy(config util.Config) Policy {
+	return Policy{
+		MaxUserFailures: int32(config.LoginMaxFailures),
+		MaxIPFailures:   int32(config.LoginMaxFailuresPerIP),
+		Lockout:         config.LoginLockout,
+		MaxLockout:      config.LoginMaxLockout,
+	}
+}
+
+// LockFor returns how long to lock out a key after failures consecutive
+// failed logins, when maxFailures are allowed.
+func (p Policy) LockFor(maxFailures, failures int32) time.Duration {
+	if maxFailures == 0 || failures < maxFailures {
+		return 0
+	}
+	shift := failures - maxFailures
+	if shift > maxShift {
+		return p.MaxLockout
+	}
+	d := p.Lockout << shift
+	if d > p.MaxLockout {
+		return p.MaxLockout
+	}
+	return d
+}
+
+// Guard applies a Policy to logins.
+type Guard struct {
+	store  db.Store
+	policy Policy
+}
+
+// NewGuard creates a Guard that keeps its counts in store.
+func NewGuard(store db.Store, policy Policy) *Guard {
+	return &Guard{store: store, policy: policy}
+}
+
+// Check fails with ErrLockedOut if username or ip is locked out, returning
+// how long until the lockout ends. Call it before checking the password, so
+// that the answer does not depend on whether the password is right.
+func (g *Guard) Check(ctx context.Context, username, ip string) (retryAfter time.Duration, err error) {
+	keys := g.keys(username, ip)
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	lockouts, err := g.store.ListLoginLockouts(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+	if len(lockouts) == 0 {
+		return 0, nil
+	}
+	// Lockouts are ordered by when they end, latest first.
+	return time.Until(lockouts[0].LockedUntil.Time), ErrLockedOut
+}
+
+// Fail counts a failed login for username from ip.
+func (g *Guard) Fail(ctx context.Context, username, ip string) error {
+	limits := []struct {
+		key         string
+		maxFailures int32
+	}{
+		{db.UserTarget(username), g.policy.MaxUserFailures},
+		{db.IPTarget(ip), g.policy.MaxIPFailures},
+	}
+
+	for _, limit := range limits {
+		if limit.maxFailures == 0 {
+			continue
+		}
+		maxFailures := limit.maxFailures
+		_, err := g.store.RecordLoginFailureTx(ctx, db.RecordLoginFailureTxParams{
+			Key:         limit.key,
+			ResetBefore: time.Now().Add(-g.policy.MaxLockout),
+			LockFor: func(failures int32) time.Duration {
+				return g.policy.LockFor(maxFailures, failures)
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Succeed forgets the failed logins of username. Those of the IP are kept,
+// so that logging into one account does not allow more guesses at others.
+func (g *Guard) Succeed(ctx context.Context, username string) error {
+	if g.policy.MaxUserFailures == 0 {
+		return nil
+	}
+	_, err := g.store.DeleteLoginAttempt(ctx, db.UserTarget(username))
+	return err
+}
+
+func (g *Guard) keys(username, ip string) []string {
+	var keys []string
+	if g.policy.MaxUserFailures > 0 {
+		keys = append(keys, db.UserTarget(username))
+	}
+	if g.policy.MaxIPFailures > 0 {
+		keys = append(keys, db.IPTarget(ip))
+	}
+	return keys
+}
@@ -0,0 +1,99 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+var testPolicy = Policy{
+	MaxUserFailures: 3,
+	MaxIPFailures:   10,
+	Lockout:         time.Minute,
+	MaxLockout:      10 * time.Minute,
+}
+
+func TestPolicyLockFor(t *testing.T) {
+	testCases := []struct {
+		failures int32
+		want     time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{5, 4 * time.Minute},
+		{6, 8 * time.Minute},
+		{7, 10 * time.Minute},
+		{100, 10 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.want, testPolicy.LockFor(testPolicy.MaxUserFailures, tc.failures), "failures=%d", tc.failures)
+	}
+	require.Zero(t, testPolicy.LockFor(0, 100))
+}
+
+func TestCheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	guard := NewGuard(store, testPolicy)
+	keys := []string{db.UserTarget("alice"), db.IPTarget("10.0.0.1")}
+
+	store.EXPECT().ListLoginLockouts(gomock.Any(), gomock.Eq(keys)).Times(1).Return([]db.LoginAttempt{}, nil)
+	retryAfter, err := guard.Check(context.Background(), "alice", "10.0.0.1")
+	require.NoError(t, err)
+	require.Zero(t, retryAfter)
+
+	lockedUntil := time.Now().Add(5 * time.Minute)
+	store.EXPECT().
+		ListLoginLockouts(gomock.Any(), gomock.Eq(keys)).
+		Times(1).
+		Return([]db.LoginAttempt{{Key: keys[0], LockedUntil: pgtype.Timestamptz{Time: lockedUntil, Valid: true}}}, nil)
+	retryAfter, err = guard.Check(context.Background(), "alice", "10.0.0.1")
+	require.ErrorIs(t, err, ErrLockedOut)
+	require.InDelta(t, 5*time.Minute, retryAfter, float64(time.Second))
+}
+
+func TestFail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	guard := NewGuard(store, testPolicy)
+
+	wantMax := map[string]int32{
+		db.UserTarget("alice"):  testPolicy.MaxUserFailures,
+		db.IPTarget("10.0.0.1"): testPolicy.MaxIPFailures,
+	}
+	store.EXPECT().
+		RecordLoginFailureTx(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, arg db.RecordLoginFailureTxParams) (db.LoginAttempt, error) {
+			maxFailures, ok := wantMax[arg.Key]
+			require.True(t, ok, arg.Key)
+			require.Zero(t, arg.LockFor(maxFailures-1))
+			require.Equal(t, testPolicy.Lockout, arg.LockFor(maxFailures))
+			require.WithinDuration(t, time.Now().Add(-testPolicy.MaxLockout), arg.ResetBefore, time.Second)
+			return db.LoginAttempt{}, nil
+		})
+
+	require.NoError(t, guard.Fail(context.Background(), "alice", "10.0.0.1"))
+}
+
+func TestDisabledPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	guard := NewGuard(store, Policy{})
+
+	// With no limits the guard never touches the store.
+	_, err := guard.Check(context.Background(), "alice", "10.0.0.1")
+	require.NoError(t, err)
+	require.NoError(t, guard.Fail(context.Background(), "alice", "10.0.0.1"))
+	require.NoError(t, guard.Succeed(context.Background(), "alice"))
+}
@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevel(t *testing.T) {
+	testCases := []struct {
+		in      string
+		want    slog.Level
+		wantErr bool
+	}{
+		{in: "", want: slog.LevelInfo},
+		{in: "debug", want: slog.LevelDebug},
+		{in: "INFO", want: slog.LevelInfo},
+		{in: "Warn", want: slog.LevelWarn},
+		{in: "error", want: slog.LevelError},
+		{in: "verbose", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		level, err := ParseLevel(tc.in)
+		if tc.wantErr {
+			require.Error(t, err)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, tc.want, level)
+	}
+}
+
+func TestLoggerAddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info")
+	require.NoError(t, err)
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	logger.With("component", "test").InfoContext(ctx, "hello", "n", 1)
+	logger.DebugContext(ctx, "hidden")
+	logger.Info("no context")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var first map[string]any
+	require.NoError(t, json.Unmarshal(lines[0], &first))
+	require.Equal(t, "hello", first["msg"])
+	require.Equal(t, "req-123", first[RequestIDKey])
+	require.Equal(t, "test", first["component"])
+
+	var second map[string]any
+	require.NoError(t, json.Unmarshal(lines[1], &second))
+	require.NotContains(t, second, RequestIDKey)
+}
@@ -0,0 +1,126 @@
+// Package mail sends email to users through pluggable mailers.
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Mailer delivers messages. Send must return an error unless the message
+// was accepted for delivery.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Supported mailer kinds.
+const (
+	MailerLog  = "log"
+	MailerFile = "file"
+	MailerSMTP = "smtp"
+)
+
+// Options configure NewMailer.
+type Options struct {
+	// Kind is one of MailerLog, MailerFile or MailerSMTP.
+	Kind string
+	// Target is the file path for file mailers.
+	Target string
+	// From is the sender address of SMTP mail.
+	From string
+	// SMTPAddress is the host:port of the SMTP server. Username and
+	// password are optional; when set they are sent with PLAIN auth.
+	SMTPAddress  string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// NewMailer creates the mailer of the kind opts ask for.
+func NewMailer(opts Options) (Mailer, error) {
+	switch opts.Kind {
+	case MailerLog:
+		return LogMailer{}, nil
+	case MailerFile:
+		return NewFileMailer(opts.Target)
+	case MailerSMTP:
+		return NewSMTPMailer(opts.SMTPAddress, opts.From, opts.SMTPUsername, opts.SMTPPassword)
+	}
+	return nil, fmt.Errorf("unsupported mailer: %q", opts.Kind)
+}
+
+// LogMailer logs messages instead of sending them, for local development.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "mail", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// WriterMailer writes every message as a JSON line to an io.Writer.
+type WriterMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterMailer creates a mailer writing JSON lines to w.
+func NewWriterMailer(w io.Writer) *WriterMailer {
+	return &WriterMailer{w: w}
+}
+
+func (mailer *WriterMailer) Send(ctx context.Context, msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	_, err = mailer.w.Write(append(data, '\n'))
+	return err
+}
+
+// NewFileMailer creates a mailer appending JSON lines to the file at path.
+func NewFileMailer(path string) (*WriterMailer, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterMailer(file), nil
+}
+
+// MemoryMailer keeps every message in memory, for tests.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryMailer creates an empty MemoryMailer.
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (mailer *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	mailer.messages = append(mailer.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (mailer *MemoryMailer) Messages() []Message {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+	return slices.Clone(mailer.messages)
+}
@@ -0,0 +1,56 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterMailer(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := NewWriterMailer(&buf)
+	msg := Message{To: "alice@example.com", Subject: "Hello", Body: "Hi Alice"}
+
+	require.NoError(t, mailer.Send(context.Background(), msg))
+
+	var got Message
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	require.Equal(t, msg, got)
+}
+
+func TestNewMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.jsonl")
+
+	mailer, err := NewMailer(Options{Kind: MailerFile, Target: path})
+	require.NoError(t, err)
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "bob@example.com"}))
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "carol@example.com"}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, bytes.Count(data, []byte("\n")))
+
+	mailer, err = NewMailer(Options{Kind: MailerLog})
+	require.NoError(t, err)
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "dave@example.com"}))
+
+	_, err = NewMailer(Options{Kind: MailerSMTP, SMTPAddress: "localhost", From: "bank@example.com"})
+	require.ErrorContains(t, err, "invalid SMTP address")
+
+	_, err = NewMailer(Options{Kind: "carrier-pigeon"})
+	require.ErrorContains(t, err, "unsupported mailer")
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer()
+	require.Empty(t, mailer.Messages())
+
+	msg := Message{To: "alice@example.com", Subject: "Hello", Body: "Hi Alice"}
+	require.NoError(t, mailer.Send(context.Background(), msg))
+	require.Equal(t, []Message{msg}, mailer.Messages())
+}
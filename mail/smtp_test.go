@@ -0,0 +1,94 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// serveSMTP accepts one connection on listener, speaks just enough SMTP for
+// net/smtp to deliver a message without auth or TLS, and sends the
+// envelope sender, recipient and data it received on the returned channel.
+func serveSMTP(listener net.Listener) <-chan []string {
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		text := textproto.NewConn(conn)
+		var got []string
+		text.PrintfLine("220 localhost ready")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			verb := strings.ToUpper(strings.Fields(line)[0])
+			switch verb {
+			case "EHLO", "HELO":
+				text.PrintfLine("250 localhost")
+			case "MAIL", "RCPT":
+				got = append(got, line)
+				text.PrintfLine("250 OK")
+			case "DATA":
+				text.PrintfLine("354 go ahead")
+				data, err := text.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				got = append(got, string(data))
+				text.PrintfLine("250 OK")
+			case "QUIT":
+				text.PrintfLine("221 bye")
+				received <- got
+				return
+			default:
+				text.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	return received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	received := serveSMTP(listener)
+
+	mailer, err := NewSMTPMailer(listener.Addr().String(), "Simple Bank <bank@example.com>", "", "")
+	require.NoError(t, err)
+
+	err = mailer.Send(context.Background(), Message{To: "alice@example.com", Subject: "Hello", Body: "line one\nline two"})
+	require.NoError(t, err)
+
+	got := <-received
+	require.Len(t, got, 3)
+	require.True(t, strings.HasPrefix(got[0], "MAIL FROM:<bank@example.com>"), got[0])
+	require.Equal(t, "RCPT TO:<alice@example.com>", got[1])
+
+	data := bufio.NewScanner(strings.NewReader(got[2]))
+	headers := map[string]bool{}
+	for data.Scan() && data.Text() != "" {
+		headers[strings.SplitN(data.Text(), ":", 2)[0]] = true
+	}
+	require.True(t, headers["From"])
+	require.True(t, headers["Subject"])
+	require.Contains(t, got[2], "line one\nline two")
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	mailer, err := NewSMTPMailer("127.0.0.1:25", "bank@example.com", "", "")
+	require.NoError(t, err)
+
+	err = mailer.Send(context.Background(), Message{To: "alice@example.com\r\nBcc: eve@example.com"})
+	require.ErrorContains(t, err, "line break")
+}
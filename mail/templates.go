@@ -0,0 +1,38 @@
+package mail
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewVerifyEmailMessage asks a new user to open the link that verifies
+// their email address. publicURL is where the API is reachable.
+func NewVerifyEmailMessage(publicURL, to, fullName string, verifyEmailID int64, secretCode string, expiresAt time.Time) Message {
+	query := url.Values{
+		"id":   {strconv.FormatInt(verifyEmailID, 10)},
+		"code": {secretCode},
+	}
+	link := strings.TrimSuffix(publicURL, "/") + "/verify_email?" + query.Encode()
+
+	return Message{
+		To:      to,
+		Subject: "Verify your Simple Bank email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen this link before %s to verify your email address:\n\n%s\n\n"+
+			"You can make transfers once it is verified.\n",
+			fullName, expiresAt.UTC().Format(time.RFC1123), link),
+	}
+}
+
+// NewPasswordResetMessage sends a password reset token to a user.
+func NewPasswordResetMessage(to, fullName, resetToken string, expiresAt time.Time) Message {
+	return Message{
+		To:      to,
+		Subject: "Reset your Simple Bank password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse this token to reset your password before %s:\n\n%s\n\n"+
+			"If you did not ask for a reset, ignore this email.\n",
+			fullName, expiresAt.UTC().Format(time.RFC1123), resetToken),
+	}
+}
@@ -0,0 +1,57 @@
+// Package metrics defines the Prometheus collectors exported at GET /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "simple_bank"
+
+// Transaction outcomes counted by TxTotal.
+const (
+	TxCommit   = "commit"
+	TxRollback = "rollback"
+	TxRetry    = "retry"
+)
+
+var (
+	// HTTPRequests counts HTTP requests by method, route template and status.
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes HTTP request latency by method, route template and status.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// TxTotal counts database transactions by outcome: commit, rollback or retry.
+	TxTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "transactions_total",
+		Help:      "Database transactions by outcome (commit, rollback, retry).",
+	}, []string{"result"})
+
+	// TransfersCreated counts committed transfers by currency.
+	TransfersCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_created_total",
+		Help:      "Committed transfers by currency.",
+	}, []string{"currency"})
+
+	// AmountTransferred sums the amount moved by committed transfers, by currency.
+	AmountTransferred = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transferred_amount_total",
+		Help:      "Amount moved by committed transfers, in minor units, by currency.",
+	}, []string{"currency"})
+)
@@ -0,0 +1,365 @@
+// Package oauth is an OAuth 2.0 authorization server (RFC 6749) for
+// third-party apps. Users consent to an app acting for them through the
+// authorization code grant, which always requires PKCE (RFC 7636, S256).
+// Confidential clients can also get tokens for the user who registered
+// them through the client credentials grant. Access tokens are opaque,
+// limited to scopes from util, and can be introspected (RFC 7662).
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+)
+
+// Errors from RFC 6749 sections 4.1.2.1 and 5.2. Their text is the error
+// code sent to clients; wrapping errors add the description.
+var (
+	ErrInvalidRequest          = errors.New("invalid_request")
+	ErrInvalidClient           = errors.New("invalid_client")
+	ErrInvalidGrant            = errors.New("invalid_grant")
+	ErrUnauthorizedClient      = errors.New("unauthorized_client")
+	ErrUnsupportedGrantType    = errors.New("unsupported_grant_type")
+	ErrUnsupportedResponseType = errors.New("unsupported_response_type")
+	ErrInvalidScope            = errors.New("invalid_scope")
+	ErrAccessDenied            = errors.New("access_denied")
+)
+
+// ErrorCode returns the RFC 6749 error code for err, or "server_error".
+func ErrorCode(err error) string {
+	for _, code := range []error{
+		ErrInvalidRequest, ErrInvalidClient, ErrInvalidGrant, ErrUnauthorizedClient,
+		ErrUnsupportedGrantType, ErrUnsupportedResponseType, ErrInvalidScope, ErrAccessDenied,
+	} {
+		if errors.Is(err, code) {
+			return code.Error()
+		}
+	}
+	return "server_error"
+}
+
+// Token errors for resource requests.
+var (
+	ErrInvalidToken = errors.New("OAuth access token is invalid")
+	ErrExpiredToken = errors.New("OAuth access token has expired")
+)
+
+// Grant types, and the one response type and PKCE method supported.
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantClientCredentials = "client_credentials"
+	ResponseTypeCode       = "code"
+	ChallengeMethodS256    = "S256"
+)
+
+// Access tokens look like sbo_<secret>, so they can be told apart from the
+// PASETO tokens of the API's own login.
+const (
+	tokenTag       = "sbo_"
+	clientIDBytes  = 12
+	secretBytes    = 32
+	minVerifierLen = 43
+	maxVerifierLen = 128
+)
+
+// Options configures how long codes and tokens last.
+type Options struct {
+	CodeDuration  time.Duration
+	TokenDuration time.Duration
+}
+
+// Server issues codes and tokens and checks them.
+type Server struct {
+	store   db.Store
+	options Options
+	now     func() time.Time
+}
+
+// NewServer creates a Server that keeps clients, codes and tokens in store.
+func NewServer(store db.Store, options Options) *Server {
+	return &Server{store: store, options: options, now: time.Now}
+}
+
+// RegisterClientParams holds the arguments for registering a client. Only
+// confidential clients get a secret; public ones, such as mobile apps,
+// cannot keep one and rely on PKCE alone.
+type RegisterClientParams struct {
+	Owner        string
+	Name         string
+	RedirectURIs []string
+	Scopes       []string
+	Confidential bool
+}
+
+// RegisterClient registers a client and returns it with its secret, which
+// is "" for public clients. Only a hash of the secret is stored, so it
+// cannot be shown again.
+func (s *Server) RegisterClient(ctx context.Context, arg RegisterClientParams) (db.OauthClient, string, error) {
+	clientID, err := util.NewSecret(clientIDBytes)
+	if err != nil {
+		return db.OauthClient{}, "", err
+	}
+
+	var secret string
+	var secretHash []byte
+	if arg.Confidential {
+		secret, err = util.NewSecret(secretBytes)
+		if err != nil {
+			return db.OauthClient{}, "", err
+		}
+		secretHash = util.HashSecret(secret)
+	}
+
+	client, err := s.store.CreateOAuthClientTx(ctx, db.CreateOauthClientParams{
+		ID:           clientID,
+		Owner:        arg.Owner,
+		Name:         arg.Name,
+		SecretHash:   secretHash,
+		RedirectUris: arg.RedirectURIs,
+		Scopes:       arg.Scopes,
+	})
+	if err != nil {
+		return db.OauthClient{}, "", err
+	}
+	return client, secret, nil
+}
+
+// AuthenticateClient returns the client with clientID if secret is its
+// secret. Public clients authenticate with an empty secret.
+func (s *Server) AuthenticateClient(ctx context.Context, clientID, secret string) (db.OauthClient, error) {
+	client, err := s.store.GetOauthClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.OauthClient{}, fmt.Errorf("%w: unknown client", ErrInvalidClient)
+		}
+		return db.OauthClient{}, err
+	}
+
+	if client.SecretHash == nil {
+		if secret != "" {
+			return db.OauthClient{}, fmt.Errorf("%w: public clients have no secret", ErrInvalidClient)
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare(util.HashSecret(secret), client.SecretHash) != 1 {
+		return db.OauthClient{}, fmt.Errorf("%w: wrong client secret", ErrInvalidClient)
+	}
+	return client, nil
+}
+
+// AuthorizationRequest is what a client asks a user to consent to.
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// Authorization is a validated AuthorizationRequest.
+type Authorization struct {
+	Client      db.OauthClient
+	RedirectURI string
+	Scopes      []string
+	Challenge   string
+}
+
+// ValidateAuthorization checks an authorization request. Until the redirect
+// URI is known to be the client's, errors must be shown to the user rather
+// than sent there; errors after that are *RedirectableError.
+func (s *Server) ValidateAuthorization(ctx context.Context, req AuthorizationRequest) (Authorization, error) {
+	client, err := s.store.GetOauthClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return Authorization{}, fmt.Errorf("%w: unknown client", ErrInvalidClient)
+		}
+		return Authorization{}, err
+	}
+	if !slices.Contains(client.RedirectUris, req.RedirectURI) {
+		return Authorization{}, fmt.Errorf("%w: redirect_uri is not registered for the client", ErrInvalidRequest)
+	}
+
+	auth := Authorization{Client: client, RedirectURI: req.RedirectURI}
+	if req.ResponseType != ResponseTypeCode {
+		return auth, &RedirectableError{fmt.Errorf("%w: response_type must be %q", ErrUnsupportedResponseType, ResponseTypeCode)}
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != ChallengeMethodS256 {
+		return auth, &RedirectableError{fmt.Errorf("%w: PKCE with code_challenge_method S256 is required", ErrInvalidRequest)}
+	}
+	auth.Challenge = req.CodeChallenge
+
+	auth.Scopes, err = grantedScopes(client, req.Scope)
+	if err != nil {
+		return auth, &RedirectableError{err}
+	}
+	return auth, nil
+}
+
+// RedirectableError is an authorization request error that may be sent to
+// the client's redirect URI.
+type RedirectableError struct {
+	Err error
+}
+
+func (e *RedirectableError) Error() string { return e.Err.Error() }
+func (e *RedirectableError) Unwrap() error { return e.Err }
+
+// Authorize records that username consented to auth and returns the code
+// the client exchanges for a token.
+func (s *Server) Authorize(ctx context.Context, username string, auth Authorization) (string, error) {
+	code, err := util.NewSecret(secretBytes)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.store.GrantOAuthCodeTx(ctx, db.CreateOauthAuthorizationCodeParams{
+		CodeHash:      util.HashSecret(code),
+		ClientID:      auth.Client.ID,
+		Username:      username,
+		RedirectUri:   auth.RedirectURI,
+		Scopes:        auth.Scopes,
+		CodeChallenge: auth.Challenge,
+		ExpiresAt:     s.now().Add(s.options.CodeDuration),
+	})
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// Token is an access token handed to a client.
+type Token struct {
+	AccessToken string
+	Scopes      []string
+	ExpiresIn   time.Duration
+}
+
+// ExchangeCode exchanges a code for an access token. The code is used up
+// even if the request fails, so that a stolen code cannot be retried.
+func (s *Server) ExchangeCode(ctx context.Context, client db.OauthClient, code, redirectURI, verifier string) (Token, error) {
+	grant, err := s.store.UseOauthAuthorizationCode(ctx, util.HashSecret(code))
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return Token{}, fmt.Errorf("%w: code is invalid, expired or already used", ErrInvalidGrant)
+		}
+		return Token{}, err
+	}
+
+	if grant.ClientID != client.ID {
+		return Token{}, fmt.Errorf("%w: code was issued to another client", ErrInvalidGrant)
+	}
+	if grant.RedirectUri != redirectURI {
+		return Token{}, fmt.Errorf("%w: redirect_uri does not match the authorization request", ErrInvalidGrant)
+	}
+	if len(verifier) < minVerifierLen || len(verifier) > maxVerifierLen {
+		return Token{}, fmt.Errorf("%w: code_verifier must be 43 to 128 characters", ErrInvalidRequest)
+	}
+	if subtle.ConstantTimeCompare([]byte(S256Challenge(verifier)), []byte(grant.CodeChallenge)) != 1 {
+		return Token{}, fmt.Errorf("%w: code_verifier does not match the code_challenge", ErrInvalidGrant)
+	}
+
+	return s.issueToken(ctx, client.ID, grant.Username, grant.Scopes)
+}
+
+// ClientCredentials issues a confidential client an access token that acts
+// for the user who registered it.
+func (s *Server) ClientCredentials(ctx context.Context, client db.OauthClient, scope string) (Token, error) {
+	if client.SecretHash == nil {
+		return Token{}, fmt.Errorf("%w: public clients cannot use client credentials", ErrUnauthorizedClient)
+	}
+	scopes, err := grantedScopes(client, scope)
+	if err != nil {
+		return Token{}, err
+	}
+	return s.issueToken(ctx, client.ID, client.Owner, scopes)
+}
+
+func (s *Server) issueToken(ctx context.Context, clientID, username string, scopes []string) (Token, error) {
+	secret, err := util.NewSecret(secretBytes)
+	if err != nil {
+		return Token{}, err
+	}
+	accessToken := tokenTag + secret
+
+	_, err = s.store.CreateOauthToken(ctx, db.CreateOauthTokenParams{
+		TokenHash: util.HashSecret(accessToken),
+		ClientID:  clientID,
+		Username:  username,
+		Scopes:    scopes,
+		ExpiresAt: s.now().Add(s.options.TokenDuration),
+	})
+	if err != nil {
+		return Token{}, err
+	}
+	return Token{AccessToken: accessToken, Scopes: scopes, ExpiresIn: s.options.TokenDuration}, nil
+}
+
+// AuthenticateToken returns the grant behind an access token. It fails with
+// ErrInvalidToken or ErrExpiredToken if the token may not be used.
+func (s *Server) AuthenticateToken(ctx context.Context, accessToken string) (db.OauthToken, error) {
+	if !IsToken(accessToken) {
+		return db.OauthToken{}, ErrInvalidToken
+	}
+	token, err := s.store.GetOauthToken(ctx, util.HashSecret(accessToken))
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.OauthToken{}, ErrInvalidToken
+		}
+		return db.OauthToken{}, err
+	}
+	if !s.now().Before(token.ExpiresAt) {
+		return db.OauthToken{}, ErrExpiredToken
+	}
+	return token, nil
+}
+
+// IsToken reports whether s looks like an access token issued here.
+func IsToken(s string) bool {
+	return strings.HasPrefix(s, tokenTag)
+}
+
+// HasScope reports whether token was granted scope.
+func HasScope(token db.OauthToken, scope string) bool {
+	return slices.Contains(token.Scopes, scope)
+}
+
+// S256Challenge returns the PKCE S256 code challenge for verifier.
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// FormatScope joins scopes into the space-separated form of RFC 6749.
+func FormatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// grantedScopes returns the scopes a client asked for in scope, or all the
+// client may ask for if scope is empty.
+func grantedScopes(client db.OauthClient, scope string) ([]string, error) {
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		return client.Scopes, nil
+	}
+
+	var scopes []string
+	for _, s := range requested {
+		if !slices.Contains(client.Scopes, s) {
+			return nil, fmt.Errorf("%w: the client may not ask for %q", ErrInvalidScope, s)
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, nil
+}
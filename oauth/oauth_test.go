@@ -0,0 +1,426 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+var testOptions = Options{CodeDuration: 5 * time.Minute, TokenDuration: time.Hour}
+
+const testRedirectURI = "https://app.example.com/callback"
+
+// registerClient registers a client through a mock store and returns the
+// stored row and its secret.
+func registerClient(t *testing.T, confidential bool) (db.OauthClient, string) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateOAuthClientTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateOauthClientParams) (db.OauthClient, error) {
+			return db.OauthClient{
+				ID:           arg.ID,
+				Owner:        arg.Owner,
+				Name:         arg.Name,
+				SecretHash:   arg.SecretHash,
+				RedirectUris: arg.RedirectUris,
+				Scopes:       arg.Scopes,
+				CreatedAt:    time.Now(),
+			}, nil
+		})
+
+	client, secret, err := NewServer(store, testOptions).RegisterClient(context.Background(), RegisterClientParams{
+		Owner:        util.RandomOwner(),
+		Name:         "budget app",
+		RedirectURIs: []string{testRedirectURI},
+		Scopes:       []string{util.ScopeAccountsRead, util.ScopeTransfersCreate},
+		Confidential: confidential,
+	})
+	require.NoError(t, err)
+	return client, secret
+}
+
+func TestRegisterClient(t *testing.T) {
+	client, secret := registerClient(t, true)
+	require.NotEmpty(t, client.ID)
+	require.NotEmpty(t, secret)
+	require.Equal(t, util.HashSecret(secret), client.SecretHash)
+
+	public, secret := registerClient(t, false)
+	require.NotEqual(t, client.ID, public.ID)
+	require.Empty(t, secret)
+	require.Nil(t, public.SecretHash)
+}
+
+func TestAuthenticateClient(t *testing.T) {
+	confidential, secret := registerClient(t, true)
+	public, _ := registerClient(t, false)
+
+	testCases := []struct {
+		name    string
+		client  db.OauthClient
+		secret  string
+		wantErr error
+	}{
+		{name: "Confidential", client: confidential, secret: secret},
+		{name: "WrongSecret", client: confidential, secret: "wrong", wantErr: ErrInvalidClient},
+		{name: "MissingSecret", client: confidential, wantErr: ErrInvalidClient},
+		{name: "Public", client: public},
+		{name: "PublicWithSecret", client: public, secret: secret, wantErr: ErrInvalidClient},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetOauthClient(gomock.Any(), gomock.Eq(tc.client.ID)).Times(1).Return(tc.client, nil)
+
+			client, err := NewServer(store, testOptions).AuthenticateClient(context.Background(), tc.client.ID, tc.secret)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.client.ID, client.ID)
+		})
+	}
+
+	t.Run("UnknownClient", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().GetOauthClient(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthClient{}, db.ErrRecordNotFound)
+
+		_, err := NewServer(store, testOptions).AuthenticateClient(context.Background(), "unknown", "")
+		require.ErrorIs(t, err, ErrInvalidClient)
+	})
+}
+
+func TestValidateAuthorization(t *testing.T) {
+	client, _ := registerClient(t, false)
+	verifier := util.RandomString(43)
+
+	validRequest := func() AuthorizationRequest {
+		return AuthorizationRequest{
+			ResponseType:        ResponseTypeCode,
+			ClientID:            client.ID,
+			RedirectURI:         testRedirectURI,
+			CodeChallenge:       S256Challenge(verifier),
+			CodeChallengeMethod: ChallengeMethodS256,
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		modify         func(req *AuthorizationRequest)
+		wantErr        error
+		wantRedirect   bool
+		wantScopes     []string
+		clientNotFound bool
+	}{
+		{
+			name:       "AllScopes",
+			modify:     func(req *AuthorizationRequest) {},
+			wantScopes: client.Scopes,
+		},
+		{
+			name:       "SomeScopes",
+			modify:     func(req *AuthorizationRequest) { req.Scope = util.ScopeAccountsRead + " " + util.ScopeAccountsRead },
+			wantScopes: []string{util.ScopeAccountsRead},
+		},
+		{
+			name:           "UnknownClient",
+			modify:         func(req *AuthorizationRequest) {},
+			clientNotFound: true,
+			wantErr:        ErrInvalidClient,
+		},
+		{
+			name:    "UnregisteredRedirectURI",
+			modify:  func(req *AuthorizationRequest) { req.RedirectURI = "https://evil.example.com/callback" },
+			wantErr: ErrInvalidRequest,
+		},
+		{
+			name:         "WrongResponseType",
+			modify:       func(req *AuthorizationRequest) { req.ResponseType = "token" },
+			wantErr:      ErrUnsupportedResponseType,
+			wantRedirect: true,
+		},
+		{
+			name:         "NoPKCE",
+			modify:       func(req *AuthorizationRequest) { req.CodeChallenge = "" },
+			wantErr:      ErrInvalidRequest,
+			wantRedirect: true,
+		},
+		{
+			name:         "PlainPKCE",
+			modify:       func(req *AuthorizationRequest) { req.CodeChallengeMethod = "plain" },
+			wantErr:      ErrInvalidRequest,
+			wantRedirect: true,
+		},
+		{
+			name:         "UnknownScope",
+			modify:       func(req *AuthorizationRequest) { req.Scope = "accounts:delete" },
+			wantErr:      ErrInvalidScope,
+			wantRedirect: true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			if tc.clientNotFound {
+				store.EXPECT().GetOauthClient(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthClient{}, db.ErrRecordNotFound)
+			} else {
+				store.EXPECT().GetOauthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+			}
+
+			req := validRequest()
+			tc.modify(&req)
+			auth, err := NewServer(store, testOptions).ValidateAuthorization(context.Background(), req)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				var redirectable *RedirectableError
+				require.Equal(t, tc.wantRedirect, errors.As(err, &redirectable))
+				if tc.wantRedirect {
+					require.Equal(t, testRedirectURI, auth.RedirectURI)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, client.ID, auth.Client.ID)
+			require.Equal(t, tc.wantScopes, auth.Scopes)
+			require.Equal(t, req.CodeChallenge, auth.Challenge)
+		})
+	}
+}
+
+func TestExchangeCode(t *testing.T) {
+	client, _ := registerClient(t, false)
+	verifier := util.RandomString(43)
+
+	grant := db.OauthAuthorizationCode{
+		ClientID:      client.ID,
+		Username:      util.RandomOwner(),
+		RedirectUri:   testRedirectURI,
+		Scopes:        []string{util.ScopeAccountsRead},
+		CodeChallenge: S256Challenge(verifier),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+
+	testCases := []struct {
+		name        string
+		redirectURI string
+		verifier    string
+		buildStubs  func(store *mockdb.MockStore)
+		wantErr     error
+	}{
+		{
+			name:        "OK",
+			redirectURI: testRedirectURI,
+			verifier:    verifier,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UseOauthAuthorizationCode(gomock.Any(), gomock.Eq(util.HashSecret("code"))).Times(1).Return(grant, nil)
+				store.EXPECT().
+					CreateOauthToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateOauthTokenParams) (db.OauthToken, error) {
+						require.Equal(t, client.ID, arg.ClientID)
+						require.Equal(t, grant.Username, arg.Username)
+						require.Equal(t, grant.Scopes, arg.Scopes)
+						require.WithinDuration(t, time.Now().Add(testOptions.TokenDuration), arg.ExpiresAt, time.Second)
+						return db.OauthToken{TokenHash: arg.TokenHash}, nil
+					})
+			},
+		},
+		{
+			name:        "UsedOrExpired",
+			redirectURI: testRedirectURI,
+			verifier:    verifier,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UseOauthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthAuthorizationCode{}, db.ErrRecordNotFound)
+				store.EXPECT().CreateOauthToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: ErrInvalidGrant,
+		},
+		{
+			name:        "OtherClient",
+			redirectURI: testRedirectURI,
+			verifier:    verifier,
+			buildStubs: func(store *mockdb.MockStore) {
+				other := grant
+				other.ClientID = "other"
+				store.EXPECT().UseOauthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(other, nil)
+				store.EXPECT().CreateOauthToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: ErrInvalidGrant,
+		},
+		{
+			name:        "OtherRedirectURI",
+			redirectURI: "https://app.example.com/other",
+			verifier:    verifier,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UseOauthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(grant, nil)
+				store.EXPECT().CreateOauthToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: ErrInvalidGrant,
+		},
+		{
+			name:        "ShortVerifier",
+			redirectURI: testRedirectURI,
+			verifier:    "short",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UseOauthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(grant, nil)
+				store.EXPECT().CreateOauthToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: ErrInvalidRequest,
+		},
+		{
+			name:        "WrongVerifier",
+			redirectURI: testRedirectURI,
+			verifier:    util.RandomString(43),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UseOauthAuthorizationCode(gomock.Any(), gomock.Any()).Times(1).Return(grant, nil)
+				store.EXPECT().CreateOauthToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: ErrInvalidGrant,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			tok, err := NewServer(store, testOptions).ExchangeCode(context.Background(), client, "code", tc.redirectURI, tc.verifier)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, IsToken(tok.AccessToken))
+			require.Equal(t, grant.Scopes, tok.Scopes)
+			require.Equal(t, testOptions.TokenDuration, tok.ExpiresIn)
+		})
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	confidential, _ := registerClient(t, true)
+	public, _ := registerClient(t, false)
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateOauthToken(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateOauthTokenParams) (db.OauthToken, error) {
+			require.Equal(t, confidential.Owner, arg.Username)
+			return db.OauthToken{TokenHash: arg.TokenHash}, nil
+		})
+	server := NewServer(store, testOptions)
+
+	tok, err := server.ClientCredentials(context.Background(), confidential, util.ScopeAccountsRead)
+	require.NoError(t, err)
+	require.Equal(t, []string{util.ScopeAccountsRead}, tok.Scopes)
+
+	_, err = server.ClientCredentials(context.Background(), confidential, "accounts:delete")
+	require.ErrorIs(t, err, ErrInvalidScope)
+
+	_, err = server.ClientCredentials(context.Background(), public, "")
+	require.ErrorIs(t, err, ErrUnauthorizedClient)
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	accessToken := tokenTag + util.RandomString(43)
+	grant := db.OauthToken{
+		TokenHash: util.HashSecret(accessToken),
+		ClientID:  "client",
+		Username:  util.RandomOwner(),
+		Scopes:    []string{util.ScopeAccountsRead},
+		ExpiresAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+	}
+
+	testCases := []struct {
+		name        string
+		accessToken string
+		buildStubs  func(store *mockdb.MockStore)
+		wantErr     error
+	}{
+		{
+			name:        "OK",
+			accessToken: accessToken,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOauthToken(gomock.Any(), gomock.Eq(grant.TokenHash)).Times(1).Return(grant, nil)
+			},
+		},
+		{
+			name:        "NotAnOAuthToken",
+			accessToken: "v4.local.something",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOauthToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:        "Unknown",
+			accessToken: accessToken,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetOauthToken(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthToken{}, db.ErrRecordNotFound)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:        "Expired",
+			accessToken: accessToken,
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := grant
+				expired.ExpiresAt = time.Now().Add(-time.Second)
+				store.EXPECT().GetOauthToken(gomock.Any(), gomock.Any()).Times(1).Return(expired, nil)
+			},
+			wantErr: ErrExpiredToken,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			got, err := NewServer(store, testOptions).AuthenticateToken(context.Background(), tc.accessToken)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, grant.Username, got.Username)
+			require.True(t, HasScope(got, util.ScopeAccountsRead))
+			require.False(t, HasScope(got, util.ScopeTransfersCreate))
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	require.Equal(t, "invalid_grant", ErrorCode(ErrInvalidGrant))
+	require.Equal(t, "invalid_scope", ErrorCode(&RedirectableError{Err: ErrInvalidScope}))
+	require.Equal(t, "server_error", ErrorCode(errors.New("connection refused")))
+}
@@ -0,0 +1,146 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	db "simple_bank/db/sqlc"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	defaultBatchSize = 100
+	defaultInterval  = time.Second
+	baseBackoff      = time.Second
+	maxBackoff       = 5 * time.Minute
+)
+
+// Relay polls the outbox table and delivers pending events to a Sink.
+//
+// Delivery is at-least-once: an event is only marked as published after the
+// sink accepted it. Events are delivered in insertion order, and once an event
+// fails every later event touching one of its accounts is held back until it
+// succeeds, which keeps the order per account. A failed event is retried
+// with exponential backoff rather than on every poll. Run a single relay per
+// database.
+type Relay struct {
+	store     db.Store
+	sink      Sink
+	interval  time.Duration
+	batchSize int32
+	now       func() time.Time
+}
+
+// NewRelay creates a relay delivering events from store to sink every interval.
+func NewRelay(store db.Store, sink Sink, interval time.Duration) *Relay {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Relay{
+		store:     store,
+		sink:      sink,
+		interval:  interval,
+		batchSize: defaultBatchSize,
+		now:       time.Now,
+	}
+}
+
+// Run delivers pending events until ctx is cancelled.
+func (relay *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(relay.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := relay.Flush(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("outbox relay failed", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush delivers one batch of pending events and returns how many were published.
+func (relay *Relay) Flush(ctx context.Context) (int, error) {
+	rows, err := relay.store.ListPendingOutboxEvents(ctx, relay.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	now := relay.now()
+	published := 0
+	blocked := make(map[int64]bool)
+	for _, row := range rows {
+		if isBlocked(blocked, row.AccountIds) {
+			// A held back event still has to go out before anything
+			// else touching its accounts.
+			block(blocked, row.AccountIds)
+			continue
+		}
+
+		if row.NextAttemptAt.Valid && now.Before(row.NextAttemptAt.Time) {
+			// Still backing off after a failure.
+			block(blocked, row.AccountIds)
+			continue
+		}
+
+		if err := relay.sink.Publish(ctx, newEvent(row)); err != nil {
+			block(blocked, row.AccountIds)
+			arg := db.MarkOutboxEventFailedParams{
+				ID:            row.ID,
+				LastError:     err.Error(),
+				NextAttemptAt: pgtype.Timestamptz{Time: now.Add(Backoff(row.Attempts + 1)), Valid: true},
+			}
+			if err := relay.store.MarkOutboxEventFailed(ctx, arg); err != nil {
+				return published, err
+			}
+			continue
+		}
+
+		if err := relay.store.MarkOutboxEventPublished(ctx, row.ID); err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+// Backoff returns the delay before the next attempt after the given number of attempts.
+func Backoff(attempts int32) time.Duration {
+	backoff := baseBackoff
+	for i := int32(1); i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+func isBlocked(blocked map[int64]bool, accountIDs []int64) bool {
+	for _, id := range accountIDs {
+		if blocked[id] {
+			return true
+		}
+	}
+	return false
+}
+
+func block(blocked map[int64]bool, accountIDs []int64) {
+	for _, id := range accountIDs {
+		blocked[id] = true
+	}
+}
+
+func newEvent(row db.Outbox) Event {
+	return Event{
+		ID:         row.ID,
+		Type:       row.EventType,
+		AccountIDs: row.AccountIds,
+		Payload:    row.Payload,
+		CreatedAt:  row.CreatedAt,
+	}
+}
@@ -0,0 +1,156 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	published []int64
+	failIDs   map[int64]bool
+}
+
+func (sink *recordingSink) Publish(ctx context.Context, event Event) error {
+	if sink.failIDs[event.ID] {
+		return errors.New("sink unavailable")
+	}
+	sink.published = append(sink.published, event.ID)
+	return nil
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, baseBackoff, Backoff(1))
+	require.Equal(t, 2*baseBackoff, Backoff(2))
+	require.Equal(t, 8*baseBackoff, Backoff(4))
+	require.Equal(t, maxBackoff, Backoff(100))
+}
+
+func TestRelayFlush(t *testing.T) {
+	now := time.Now()
+	rows := []db.Outbox{
+		{ID: 1, EventType: db.EventAccountCreated, AccountIds: []int64{10}},
+		{ID: 2, EventType: db.EventAccountCreated, AccountIds: []int64{20}},
+		{ID: 3, EventType: db.EventTransferPosted, AccountIds: []int64{10, 30}},
+		{ID: 4, EventType: db.EventTransferPosted, AccountIds: []int64{30, 40}},
+		{ID: 5, EventType: db.EventTransferPosted, AccountIds: []int64{20, 50}},
+	}
+
+	testCases := []struct {
+		name          string
+		rows          func() []db.Outbox
+		failIDs       map[int64]bool
+		buildStubs    func(store *mockdb.MockStore)
+		wantPublished []int64
+	}{
+		{
+			name: "AllPublished",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					MarkOutboxEventPublished(gomock.Any(), gomock.Any()).
+					Times(len(rows)).
+					Return(nil)
+			},
+			wantPublished: []int64{1, 2, 3, 4, 5},
+		},
+		{
+			name:    "FailureHoldsBackSameAccounts",
+			failIDs: map[int64]bool{1: true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					MarkOutboxEventFailed(gomock.Any(), gomock.Eq(db.MarkOutboxEventFailedParams{
+						ID:            1,
+						LastError:     "sink unavailable",
+						NextAttemptAt: pgtype.Timestamptz{Time: now.Add(baseBackoff), Valid: true},
+					})).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					MarkOutboxEventPublished(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(nil)
+			},
+			// 3 shares account 10 with the failed event and 4 shares account 30 with 3.
+			wantPublished: []int64{2, 5},
+		},
+		{
+			name: "RetryBacksOff",
+			rows: func() []db.Outbox {
+				backingOff := slices.Clone(rows)
+				backingOff[0].Attempts = 3
+				backingOff[0].NextAttemptAt = pgtype.Timestamptz{Time: now.Add(time.Second), Valid: true}
+				return backingOff
+			},
+			failIDs: map[int64]bool{1: true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().MarkOutboxEventFailed(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					MarkOutboxEventPublished(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(nil)
+			},
+			// 1 is not due yet and still holds back 3 and 4.
+			wantPublished: []int64{2, 5},
+		},
+		{
+			name: "RetryDue",
+			rows: func() []db.Outbox {
+				due := slices.Clone(rows)
+				due[0].Attempts = 3
+				due[0].NextAttemptAt = pgtype.Timestamptz{Time: now, Valid: true}
+				return due
+			},
+			failIDs: map[int64]bool{1: true},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					MarkOutboxEventFailed(gomock.Any(), gomock.Eq(db.MarkOutboxEventFailedParams{
+						ID:            1,
+						LastError:     "sink unavailable",
+						NextAttemptAt: pgtype.Timestamptz{Time: now.Add(Backoff(4)), Valid: true},
+					})).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					MarkOutboxEventPublished(gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(nil)
+			},
+			wantPublished: []int64{2, 5},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			pending := rows
+			if tc.rows != nil {
+				pending = tc.rows()
+			}
+			store.EXPECT().
+				ListPendingOutboxEvents(gomock.Any(), gomock.Eq(int32(defaultBatchSize))).
+				Times(1).
+				Return(pending, nil)
+			tc.buildStubs(store)
+
+			sink := &recordingSink{failIDs: tc.failIDs}
+			relay := NewRelay(store, sink, 0)
+			relay.now = func() time.Time { return now }
+
+			published, err := relay.Flush(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, len(tc.wantPublished), published)
+			require.Equal(t, tc.wantPublished, sink.published)
+		})
+	}
+}
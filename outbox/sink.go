@@ -0,0 +1,133 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event is the message delivered to sinks for every outbox row.
+type Event struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	AccountIDs []int64         `json:"account_ids"`
+	Payload    json.RawMessage `json:"payload"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Sink delivers events to a downstream system.
+// Publish must return an error unless the event was accepted, so the relay can retry it.
+type Sink interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Supported sink kinds.
+const (
+	SinkStdout  = "stdout"
+	SinkFile    = "file"
+	SinkWebhook = "webhook"
+)
+
+// NewSink creates the sink of the given kind. target is the file path for
+// file sinks and the URL for webhook sinks, and is ignored for stdout.
+func NewSink(kind string, target string) (Sink, error) {
+	switch kind {
+	case SinkStdout:
+		return NewWriterSink(os.Stdout), nil
+	case SinkFile:
+		return NewFileSink(target)
+	case SinkWebhook:
+		return NewWebhookSink(target, http.DefaultClient), nil
+	}
+	return nil, fmt.Errorf("unsupported outbox sink: %q", kind)
+}
+
+// WriterSink writes every event as a JSON line to an io.Writer.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink creates a sink writing JSON lines to w.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (sink *WriterSink) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	_, err = sink.w.Write(append(data, '\n'))
+	return err
+}
+
+// NewFileSink creates a sink appending JSON lines to the file at path.
+func NewFileSink(path string) (*WriterSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterSink(file), nil
+}
+
+// WebhookSink POSTs every event as JSON to a URL.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink creates a sink posting events to url with client.
+func NewWebhookSink(url string, client *http.Client) *WebhookSink {
+	return &WebhookSink{url: url, client: client}
+}
+
+func (sink *WebhookSink) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Receivers can use the event ID to drop duplicates caused by redelivery.
+	req.Header.Set("X-Event-ID", fmt.Sprint(event.ID))
+	req.Header.Set("X-Event-Type", event.Type)
+
+	resp, err := sink.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// MultiSink publishes every event to all of its sinks.
+// An event is retried on all sinks if any of them fails.
+type MultiSink []Sink
+
+func (sinks MultiSink) Publish(ctx context.Context, event Event) error {
+	for _, sink := range sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	db "simple_bank/db/sqlc"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+	event := Event{ID: 1, Type: db.EventAccountCreated, AccountIDs: []int64{7}, Payload: json.RawMessage(`{"id":7}`)}
+
+	require.NoError(t, sink.Publish(context.Background(), event))
+
+	var got Event
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	require.Equal(t, event.ID, got.ID)
+	require.Equal(t, event.AccountIDs, got.AccountIDs)
+	require.JSONEq(t, string(event.Payload), string(got.Payload))
+}
+
+func TestWebhookSink(t *testing.T) {
+	testCases := []struct {
+		name      string
+		status    int
+		expectErr bool
+	}{
+		{name: "OK", status: http.StatusNoContent},
+		{name: "ServerError", status: http.StatusInternalServerError, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var received Event
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "1", r.Header.Get("X-Event-ID"))
+				require.Equal(t, db.EventTransferPosted, r.Header.Get("X-Event-Type"))
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+				w.WriteHeader(tc.status)
+			}))
+			defer receiver.Close()
+
+			sink := NewWebhookSink(receiver.URL, receiver.Client())
+			event := Event{ID: 1, Type: db.EventTransferPosted, AccountIDs: []int64{1, 2}, Payload: json.RawMessage(`{}`)}
+
+			err := sink.Publish(context.Background(), event)
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, event.AccountIDs, received.AccountIDs)
+		})
+	}
+}
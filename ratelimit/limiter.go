@@ -0,0 +1,93 @@
+// Package ratelimit implements token-bucket rate limiting with in-memory and
+// Postgres-backed buckets.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Backends accepted by config.
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+// pruneInterval is how often limiters drop buckets that have refilled.
+const pruneInterval = time.Minute
+
+// Limit allows bursts of up to Burst requests, refilled at Burst per Period.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseLimit parses limits written as "<requests>/<period>", e.g. "10/1m".
+// An empty string yields the zero Limit, which disables limiting.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" {
+		return Limit{}, nil
+	}
+
+	burst, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: want <requests>/<period>", s)
+	}
+
+	n, err := strconv.Atoi(burst)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", s)
+	}
+
+	return Limit{Burst: n, Period: d}, nil
+}
+
+// IsZero reports whether l disables limiting.
+func (l Limit) IsZero() bool {
+	return l.Burst == 0
+}
+
+// rate is the refill rate in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until a token is available; zero when Allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+func newResult(limit Limit, tokens float64, allowed bool) Result {
+	rate := limit.rate()
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(limit.Burst) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return result
+}
+
+// Limiter takes tokens from per-key buckets.
+type Limiter interface {
+	// Allow takes one token from the bucket for key, creating a full bucket
+	// for unseen keys.
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "", want: Limit{}},
+		{in: "10/1m", want: Limit{Burst: 10, Period: time.Minute}},
+		{in: "300/30s", want: Limit{Burst: 300, Period: 30 * time.Second}},
+		{in: "10", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "ten/1m", wantErr: true},
+		{in: "10/soon", wantErr: true},
+		{in: "10/0s", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		limit, err := ParseLimit(tc.in)
+		if tc.wantErr {
+			require.Error(t, err, tc.in)
+			continue
+		}
+		require.NoError(t, err, tc.in)
+		require.Equal(t, tc.want, limit)
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	limit := Limit{Burst: 10, Period: 10 * time.Second}
+
+	result := newResult(limit, 4.5, true)
+	require.True(t, result.Allowed)
+	require.Equal(t, 10, result.Limit)
+	require.Equal(t, 4, result.Remaining)
+	require.Zero(t, result.RetryAfter)
+	require.Equal(t, 5500*time.Millisecond, result.Reset)
+
+	result = newResult(limit, 0.25, false)
+	require.False(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+	require.Equal(t, 750*time.Millisecond, result.RetryAfter)
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+
+	limit := Limit{Burst: 3, Period: 3 * time.Second}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		result, err := limiter.Allow(ctx, "ip:1.2.3.4", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, i, result.Remaining)
+	}
+
+	result, err := limiter.Allow(ctx, "ip:1.2.3.4", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, time.Second, result.RetryAfter)
+	require.Equal(t, 3*time.Second, result.Reset)
+
+	// Other keys have their own bucket.
+	result, err = limiter.Allow(ctx, "ip:5.6.7.8", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// One token refills per second.
+	now = now.Add(time.Second)
+	result, err = limiter.Allow(ctx, "ip:1.2.3.4", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+
+	// Refills are capped at the burst.
+	now = now.Add(time.Hour)
+	result, err = limiter.Allow(ctx, "ip:1.2.3.4", limit)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Remaining)
+}
+
+func TestMemoryLimiterPrune(t *testing.T) {
+	now := time.Now()
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+
+	limit := Limit{Burst: 1, Period: time.Second}
+	_, err := limiter.Allow(context.Background(), "user:alice", limit)
+	require.NoError(t, err)
+	require.Len(t, limiter.buckets, 1)
+
+	now = now.Add(pruneInterval)
+	_, err = limiter.Allow(context.Background(), "user:bob", limit)
+	require.NoError(t, err)
+	require.Len(t, limiter.buckets, 1)
+	require.Contains(t, limiter.buckets, "user:bob")
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	db "simple_bank/db/sqlc"
+)
+
+// PostgresLimiter keeps buckets in the rate_limit_buckets table so that all
+// instances share them. Each Allow is a single atomic upsert.
+type PostgresLimiter struct {
+	store db.Store
+
+	mu        sync.Mutex
+	lastPrune time.Time
+	maxPeriod time.Duration
+	now       func() time.Time
+}
+
+// NewPostgresLimiter creates a limiter backed by store.
+func NewPostgresLimiter(store db.Store) *PostgresLimiter {
+	return &PostgresLimiter{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+func (limiter *PostgresLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	limiter.prune(ctx, limit)
+
+	bucket, err := limiter.store.TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(limit.Burst),
+		Rate:  limit.rate(),
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(limit, bucket.Tokens, bucket.Allowed), nil
+}
+
+// prune deletes buckets idle for longer than the longest period seen, at
+// most once per pruneInterval.
+func (limiter *PostgresLimiter) prune(ctx context.Context, limit Limit) {
+	limiter.mu.Lock()
+	now := limiter.now()
+	limiter.maxPeriod = max(limiter.maxPeriod, limit.Period)
+	if now.Sub(limiter.lastPrune) < pruneInterval {
+		limiter.mu.Unlock()
+		return
+	}
+	limiter.lastPrune = now
+	before := now.Add(-limiter.maxPeriod)
+	limiter.mu.Unlock()
+
+	if _, err := limiter.store.DeleteRateLimitBucketsBefore(ctx, before); err != nil {
+		slog.WarnContext(ctx, "cannot prune rate limit buckets", "err", err)
+	}
+}
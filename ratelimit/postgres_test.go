@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresLimiter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	limit := Limit{Burst: 5, Period: time.Minute}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		DeleteRateLimitBucketsBefore(gomock.Any(), gomock.Eq(now.Add(-time.Minute))).
+		Times(1).
+		Return(int64(3), nil)
+	gomock.InOrder(
+		store.EXPECT().
+			TakeRateLimitToken(gomock.Any(), gomock.Eq(db.TakeRateLimitTokenParams{
+				Key:   "user:alice",
+				Burst: 5,
+				Rate:  5.0 / 60,
+			})).
+			Times(1).
+			Return(db.RateLimitBucket{Key: "user:alice", Tokens: 4, Allowed: true}, nil),
+		store.EXPECT().
+			TakeRateLimitToken(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.RateLimitBucket{Key: "user:alice", Tokens: 0.5, Allowed: false}, nil),
+		store.EXPECT().
+			TakeRateLimitToken(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.RateLimitBucket{}, errors.New("connection refused")),
+	)
+
+	limiter := NewPostgresLimiter(store)
+	limiter.now = func() time.Time { return now }
+
+	result, err := limiter.Allow(context.Background(), "user:alice", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 4, result.Remaining)
+	require.Equal(t, 12*time.Second, result.Reset)
+
+	// Pruning runs at most once per interval.
+	result, err = limiter.Allow(context.Background(), "user:alice", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 6*time.Second, result.RetryAfter)
+
+	_, err = limiter.Allow(context.Background(), "user:alice", limit)
+	require.Error(t, err)
+}
@@ -0,0 +1,295 @@
+// Package seed fills a database with a reproducible dataset of users,
+// accounts and transfers for demos and load tests.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strings"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+)
+
+// TreasuryUsername owns the accounts that fund every other account. Money
+// only moves through transfers, so the treasury balances go negative by the
+// amount handed out and the ledger still balances.
+const TreasuryUsername = "treasury"
+
+var firstNames = []string{
+	"Ada", "Alan", "Barbara", "Claude", "Dennis", "Edsger", "Frances", "Grace",
+	"Hedy", "Ivan", "John", "Ken", "Leslie", "Margaret", "Niklaus", "Radia",
+	"Rob", "Shafi", "Tim", "Whitfield",
+}
+
+var lastNames = []string{
+	"Allen", "Backus", "Cerf", "Dijkstra", "Engelbart", "Goldwasser", "Hamilton",
+	"Hopper", "Kahn", "Knuth", "Lamport", "Liskov", "Lovelace", "Perlman",
+	"Ritchie", "Shannon", "Sutherland", "Thompson", "Turing", "Wirth",
+}
+
+// Options controls the size and shape of the dataset. Two runs with the same
+// Options against empty databases create the same users, accounts and
+// transfers, apart from timestamps and password salts.
+type Options struct {
+	// Seed initialises the random source.
+	Seed int64
+	// Users is the number of customers to create.
+	Users int
+	// Currencies customers open accounts in; each customer gets between one
+	// and all of them.
+	Currencies []string
+	// OpeningBalance is the most the treasury pays into a new account.
+	OpeningBalance int64
+	// Transfers is the number of customer-to-customer transfers to attempt
+	// after the opening deposits. A transfer is skipped if it would have to
+	// come from an empty account, or if no currency has two accounts.
+	Transfers int
+	// MaxAmount caps a single customer transfer.
+	MaxAmount int64
+	// Password is set on every user, customers and treasury alike.
+	Password string
+}
+
+// DefaultOptions returns a small dataset suitable for local development.
+func DefaultOptions() Options {
+	return Options{
+		Seed:           1,
+		Users:          10,
+		Currencies:     []string{util.USD, util.EUR},
+		OpeningBalance: 100_000,
+		Transfers:      100,
+		MaxAmount:      10_000,
+		Password:       "secret",
+	}
+}
+
+func (opts Options) validate() error {
+	var errs []error
+	if opts.Users < 0 {
+		errs = append(errs, errors.New("users must not be negative"))
+	}
+	if len(opts.Currencies) == 0 {
+		errs = append(errs, errors.New("at least one currency is required"))
+	}
+	for _, currency := range opts.Currencies {
+		if !util.IsSupportedCurrency(currency) {
+			errs = append(errs, fmt.Errorf("unsupported currency %q", currency))
+		}
+	}
+	if opts.OpeningBalance < 1 {
+		errs = append(errs, errors.New("opening balance must be positive"))
+	}
+	if opts.Transfers < 0 {
+		errs = append(errs, errors.New("transfers must not be negative"))
+	}
+	if opts.MaxAmount < 1 {
+		errs = append(errs, errors.New("max amount must be positive"))
+	}
+	if len(opts.Password) < 6 {
+		errs = append(errs, errors.New("password must have at least 6 characters"))
+	}
+	return errors.Join(errs...)
+}
+
+// Result summarises what Run created. The treasury is not counted in Users,
+// but its accounts and the opening deposits are counted in Accounts and
+// Transfers.
+type Result struct {
+	Seed      int64    `json:"seed"`
+	Users     []string `json:"users"`
+	Accounts  int      `json:"accounts"`
+	Transfers int      `json:"transfers"`
+}
+
+// generator holds the state of one run. Everything it creates goes through
+// the store's public methods, so the dataset obeys the same invariants as
+// data created through the API.
+type generator struct {
+	store db.Store
+	opts  Options
+	rng   *rand.Rand
+
+	hashedPassword string
+	// treasury holds the treasury account for each currency.
+	treasury map[string]db.Account
+	// accounts holds customer accounts by currency, in creation order, with
+	// balances kept up to date after each transfer.
+	accounts map[string][]*db.Account
+	result   Result
+}
+
+// Run creates the dataset described by opts. It expects the users it
+// generates not to exist yet, so run it against an empty database.
+func Run(ctx context.Context, store db.Store, opts Options) (Result, error) {
+	if err := opts.validate(); err != nil {
+		return Result{}, err
+	}
+
+	hashedPassword, err := util.HashPassword(opts.Password)
+	if err != nil {
+		return Result{}, err
+	}
+
+	g := &generator{
+		store:          store,
+		opts:           opts,
+		rng:            rand.New(rand.NewSource(opts.Seed)),
+		hashedPassword: hashedPassword,
+		treasury:       map[string]db.Account{},
+		accounts:       map[string][]*db.Account{},
+		result:         Result{Seed: opts.Seed, Users: []string{}},
+	}
+
+	if err := g.createTreasury(ctx); err != nil {
+		return g.result, err
+	}
+	for i := range opts.Users {
+		if err := g.createCustomer(ctx, i); err != nil {
+			return g.result, err
+		}
+	}
+	for range opts.Transfers {
+		if err := g.createTransfer(ctx); err != nil {
+			return g.result, err
+		}
+	}
+
+	slog.InfoContext(ctx, "seeded database",
+		"seed", opts.Seed, "users", len(g.result.Users), "accounts", g.result.Accounts, "transfers", g.result.Transfers)
+	return g.result, nil
+}
+
+func (g *generator) createTreasury(ctx context.Context) error {
+	_, err := g.store.CreateUser(ctx, db.CreateUserParams{
+		Username: TreasuryUsername,
+		Password: g.hashedPassword,
+		FullName: "Treasury",
+		Email:    TreasuryUsername + "@example.com",
+		// Seeded users have no inbox to verify from, but must be able to send money.
+		IsEmailVerified: true,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot create treasury user: %w", err)
+	}
+
+	for _, currency := range g.opts.Currencies {
+		account, err := g.createAccount(ctx, TreasuryUsername, currency)
+		if err != nil {
+			return err
+		}
+		g.treasury[currency] = account
+	}
+	return nil
+}
+
+// createCustomer creates the i-th customer with accounts in a random subset
+// of the currencies, each funded from the treasury.
+func (g *generator) createCustomer(ctx context.Context, i int) error {
+	first := firstNames[g.rng.Intn(len(firstNames))]
+	last := lastNames[g.rng.Intn(len(lastNames))]
+	// The index keeps usernames unique however the names repeat.
+	username := fmt.Sprintf("%s%s%d", strings.ToLower(first), strings.ToLower(last), i+1)
+
+	_, err := g.store.CreateUser(ctx, db.CreateUserParams{
+		Username:        username,
+		Password:        g.hashedPassword,
+		FullName:        first + " " + last,
+		Email:           username + "@example.com",
+		IsEmailVerified: true,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot create user %s: %w", username, err)
+	}
+	g.result.Users = append(g.result.Users, username)
+
+	currencies := make([]string, len(g.opts.Currencies))
+	for j, k := range g.rng.Perm(len(currencies)) {
+		currencies[j] = g.opts.Currencies[k]
+	}
+	currencies = currencies[:1+g.rng.Intn(len(currencies))]
+
+	for _, currency := range currencies {
+		account, err := g.createAccount(ctx, username, currency)
+		if err != nil {
+			return err
+		}
+
+		deposit := g.opts.OpeningBalance/2 + g.rng.Int63n(g.opts.OpeningBalance/2+1)
+		if deposit < 1 {
+			deposit = 1
+		}
+		result, err := g.transfer(ctx, g.treasury[currency].ID, account.ID, deposit)
+		if err != nil {
+			return err
+		}
+		g.accounts[currency] = append(g.accounts[currency], &result.ToAccount)
+	}
+	return nil
+}
+
+func (g *generator) createAccount(ctx context.Context, owner string, currency string) (db.Account, error) {
+	account, err := g.store.CreateAccountTx(ctx, db.CreateAccountParams{
+		Owner:    owner,
+		Balance:  0,
+		Currency: currency,
+	})
+	if err != nil {
+		return db.Account{}, fmt.Errorf("cannot create %s account for %s: %w", currency, owner, err)
+	}
+	g.result.Accounts++
+	return account, nil
+}
+
+// createTransfer moves money between two customer accounts in the same
+// currency. Small amounts are far more common than large ones, and no
+// customer account is overdrawn.
+func (g *generator) createTransfer(ctx context.Context) error {
+	var currencies []string
+	for _, currency := range g.opts.Currencies {
+		if len(g.accounts[currency]) >= 2 {
+			currencies = append(currencies, currency)
+		}
+	}
+	if len(currencies) == 0 {
+		return nil
+	}
+	accounts := g.accounts[currencies[g.rng.Intn(len(currencies))]]
+
+	fromIndex := g.rng.Intn(len(accounts))
+	toIndex := g.rng.Intn(len(accounts) - 1)
+	if toIndex >= fromIndex {
+		toIndex++
+	}
+	from, to := accounts[fromIndex], accounts[toIndex]
+
+	limit := min(from.Balance, g.opts.MaxAmount)
+	if limit < 1 {
+		return nil
+	}
+	r := g.rng.Float64()
+	amount := 1 + int64(r*r*r*float64(limit-1))
+
+	result, err := g.transfer(ctx, from.ID, to.ID, amount)
+	if err != nil {
+		return err
+	}
+	*from, *to = result.FromAccount, result.ToAccount
+	return nil
+}
+
+func (g *generator) transfer(ctx context.Context, fromAccountID, toAccountID, amount int64) (db.TransferTxResult, error) {
+	result, err := g.store.TransferTx(ctx, db.TransferTxParams{
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        amount,
+	})
+	if err != nil {
+		return result, fmt.Errorf("cannot transfer %d from account %d to %d: %w", amount, fromAccountID, toAccountID, err)
+	}
+	g.result.Transfers++
+	return result, nil
+}
@@ -0,0 +1,127 @@
+package seed
+
+import (
+	"context"
+	"testing"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLedger backs a mock store with in-memory accounts and records every
+// write, so runs can be compared.
+type fakeLedger struct {
+	accounts  map[int64]*db.Account
+	users     []db.CreateUserParams
+	opened    []db.CreateAccountParams
+	transfers []db.TransferTxParams
+}
+
+func newFakeStore(t *testing.T) (*mockdb.MockStore, *fakeLedger) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	ledger := &fakeLedger{accounts: map[int64]*db.Account{}}
+
+	store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.CreateUserParams) (db.User, error) {
+			// Salted hashes differ between runs.
+			arg.Password = ""
+			ledger.users = append(ledger.users, arg)
+			return db.User{Username: arg.Username, FullName: arg.FullName, Email: arg.Email}, nil
+		})
+	store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.CreateAccountParams) (db.Account, error) {
+			ledger.opened = append(ledger.opened, arg)
+			account := db.Account{ID: int64(len(ledger.opened)), Owner: arg.Owner, Balance: arg.Balance, Currency: arg.Currency}
+			ledger.accounts[account.ID] = &account
+			return account, nil
+		})
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(_ context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+			ledger.transfers = append(ledger.transfers, arg)
+			from, to := ledger.accounts[arg.FromAccountID], ledger.accounts[arg.ToAccountID]
+			require.Equal(t, from.Currency, to.Currency)
+			from.Balance -= arg.Amount
+			to.Balance += arg.Amount
+			return db.TransferTxResult{FromAccount: *from, ToAccount: *to}, nil
+		})
+
+	return store, ledger
+}
+
+func TestRunIsDeterministic(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Users = 20
+	opts.Transfers = 200
+
+	store1, ledger1 := newFakeStore(t)
+	result1, err := Run(context.Background(), store1, opts)
+	require.NoError(t, err)
+
+	store2, ledger2 := newFakeStore(t)
+	result2, err := Run(context.Background(), store2, opts)
+	require.NoError(t, err)
+
+	require.Equal(t, result1, result2)
+	require.Equal(t, ledger1.users, ledger2.users)
+	require.Equal(t, ledger1.opened, ledger2.opened)
+	require.Equal(t, ledger1.transfers, ledger2.transfers)
+
+	opts.Seed++
+	store3, ledger3 := newFakeStore(t)
+	_, err = Run(context.Background(), store3, opts)
+	require.NoError(t, err)
+	require.NotEqual(t, ledger1.transfers, ledger3.transfers)
+}
+
+func TestRunDataset(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Users = 15
+	opts.Transfers = 300
+
+	store, ledger := newFakeStore(t)
+	result, err := Run(context.Background(), store, opts)
+	require.NoError(t, err)
+
+	require.Len(t, result.Users, opts.Users)
+	require.Len(t, ledger.users, opts.Users+1)
+	require.Equal(t, TreasuryUsername, ledger.users[0].Username)
+	require.Equal(t, len(ledger.opened), result.Accounts)
+	require.Equal(t, len(ledger.transfers), result.Transfers)
+	require.Greater(t, result.Transfers, result.Accounts)
+
+	var total int64
+	for _, account := range ledger.accounts {
+		total += account.Balance
+		if account.Owner == TreasuryUsername {
+			require.LessOrEqual(t, account.Balance, int64(0))
+		} else {
+			require.GreaterOrEqual(t, account.Balance, int64(0))
+		}
+	}
+	require.Zero(t, total)
+
+	for _, transfer := range ledger.transfers {
+		require.Positive(t, transfer.Amount)
+		require.NotEqual(t, transfer.FromAccountID, transfer.ToAccountID)
+	}
+}
+
+func TestRunInvalidOptions(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Users = -1
+	opts.Currencies = []string{util.USD, "XYZ"}
+	opts.Password = "short"
+
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+
+	_, err := Run(context.Background(), store, opts)
+	require.ErrorContains(t, err, "users must not be negative")
+	require.ErrorContains(t, err, `unsupported currency "XYZ"`)
+	require.ErrorContains(t, err, "password must have at least 6 characters")
+}
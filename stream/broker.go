@@ -0,0 +1,150 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	db "simple_bank/db/sqlc"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultBufferSize = 16
+	reconnectDelay    = time.Second
+)
+
+// Broker fans out account updates published by TransferTx to subscribers.
+//
+// Every subscription has a bounded buffer. A subscriber that falls so far
+// behind that its buffer is full is dropped and its channel closed, so one slow
+// client can never hold back the others or grow memory without limit.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[*Subscription]struct{}
+	bufferSize  int
+}
+
+// Subscription receives the updates of a single account.
+type Subscription struct {
+	broker    *Broker
+	accountID int64
+	updates   chan db.AccountUpdate
+	closeOnce sync.Once
+}
+
+// NewBroker creates a broker buffering up to bufferSize updates per subscriber.
+func NewBroker(bufferSize int) *Broker {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	return &Broker{
+		subscribers: make(map[int64]map[*Subscription]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+// Subscribe starts receiving updates for accountID. Callers must Close the subscription.
+func (broker *Broker) Subscribe(accountID int64) *Subscription {
+	sub := &Subscription{
+		broker:    broker,
+		accountID: accountID,
+		updates:   make(chan db.AccountUpdate, broker.bufferSize),
+	}
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if broker.subscribers[accountID] == nil {
+		broker.subscribers[accountID] = make(map[*Subscription]struct{})
+	}
+	broker.subscribers[accountID][sub] = struct{}{}
+
+	return sub
+}
+
+// Publish delivers update to every subscriber of its account without blocking.
+func (broker *Broker) Publish(update db.AccountUpdate) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	for sub := range broker.subscribers[update.AccountID] {
+		select {
+		case sub.updates <- update:
+		default:
+			broker.remove(sub)
+		}
+	}
+}
+
+// Updates returns the channel updates are delivered on. It is closed when the
+// subscription is closed or dropped for being too slow.
+func (sub *Subscription) Updates() <-chan db.AccountUpdate {
+	return sub.updates
+}
+
+// Close stops the subscription. It is safe to call more than once.
+func (sub *Subscription) Close() {
+	sub.broker.mu.Lock()
+	defer sub.broker.mu.Unlock()
+	sub.broker.remove(sub)
+}
+
+// remove must be called with broker.mu held.
+func (broker *Broker) remove(sub *Subscription) {
+	sub.closeOnce.Do(func() {
+		subs := broker.subscribers[sub.accountID]
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(broker.subscribers, sub.accountID)
+		}
+		close(sub.updates)
+	})
+}
+
+// Listen publishes every notification received on db.AccountUpdatesChannel
+// until ctx is cancelled, reconnecting when the connection is lost.
+func (broker *Broker) Listen(ctx context.Context, pool *pgxpool.Pool) {
+	for {
+		err := broker.listen(ctx, pool)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("stream listener failed", "err", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+func (broker *Broker) listen(ctx context.Context, pool *pgxpool.Pool) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, "LISTEN "+db.AccountUpdatesChannel)
+	if err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var update db.AccountUpdate
+		if err := json.Unmarshal([]byte(notification.Payload), &update); err != nil {
+			slog.Warn("stream listener got invalid payload", "err", err)
+			continue
+		}
+		broker.Publish(update)
+	}
+}
@@ -0,0 +1,47 @@
+package stream
+
+import (
+	"testing"
+
+	db "simple_bank/db/sqlc"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrokerPublish(t *testing.T) {
+	broker := NewBroker(1)
+	sub := broker.Subscribe(1)
+	defer sub.Close()
+	other := broker.Subscribe(2)
+	defer other.Close()
+
+	broker.Publish(db.AccountUpdate{AccountID: 1, Balance: 10})
+
+	update := <-sub.Updates()
+	require.Equal(t, int64(10), update.Balance)
+	require.Empty(t, other.Updates())
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	broker := NewBroker(1)
+	slow := broker.Subscribe(1)
+	fast := broker.Subscribe(1)
+	defer fast.Close()
+
+	broker.Publish(db.AccountUpdate{AccountID: 1, Balance: 10})
+	<-fast.Updates()
+	broker.Publish(db.AccountUpdate{AccountID: 1, Balance: 20})
+
+	// The slow subscriber still gets its buffered update, then its channel is closed.
+	update, ok := <-slow.Updates()
+	require.True(t, ok)
+	require.Equal(t, int64(10), update.Balance)
+	_, ok = <-slow.Updates()
+	require.False(t, ok)
+
+	update = <-fast.Updates()
+	require.Equal(t, int64(20), update.Balance)
+
+	// Closing a dropped subscription is a no-op.
+	slow.Close()
+}
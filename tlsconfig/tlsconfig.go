@@ -0,0 +1,164 @@
+// Package tlsconfig builds server TLS configs whose certificate is reloaded
+// when its files change, so certificates can be rotated without a restart.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// ParseVersion parses a minimum TLS version written as "1.2" or "1.3".
+func ParseVersion(s string) (uint16, error) {
+	switch s {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version %q", s)
+}
+
+// Options describes a server TLS config.
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile, if set, holds the CAs client certificates are verified
+	// against. Clients may still connect without a certificate; handlers
+	// that need one must check the request's verified chains.
+	ClientCAFile string
+	MinVersion   string
+}
+
+// New loads the certificate and client CAs and returns a server config
+// together with the reloader serving its certificate. Run the reloader to
+// pick up new certificates.
+func New(opts Options) (*tls.Config, *CertReloader, error) {
+	minVersion, err := ParseVersion(opts.MinVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reloader, err := NewCertReloader(opts.CertFile, opts.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(opts.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no certificates found in %s", opts.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return config, reloader, nil
+}
+
+// CertReloader serves a certificate loaded from a key pair on disk and
+// reloads it when either file's modification time changes.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// NewCertReloader loads the key pair from certFile and keyFile.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	reloader := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config.
+func (reloader *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	reloader.mu.RLock()
+	defer reloader.mu.RUnlock()
+	return reloader.cert, nil
+}
+
+// Reload loads the key pair again if either file changed since the last
+// successful load, and reports whether it did. If loading fails, for
+// instance because only one of the files has been replaced so far, the
+// current certificate stays in use and the next call tries again.
+func (reloader *CertReloader) Reload() (bool, error) {
+	modTime, err := latestModTime(reloader.certFile, reloader.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	reloader.mu.RLock()
+	unchanged := reloader.cert != nil && modTime.Equal(reloader.modTime)
+	reloader.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(reloader.certFile, reloader.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("cannot load TLS key pair: %w", err)
+	}
+
+	reloader.mu.Lock()
+	reloader.cert = &cert
+	reloader.modTime = modTime
+	reloader.mu.Unlock()
+	return true, nil
+}
+
+// Run checks the files every interval until ctx is cancelled.
+func (reloader *CertReloader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := reloader.Reload()
+		if err != nil {
+			slog.WarnContext(ctx, "cannot reload TLS certificate", "err", err)
+			continue
+		}
+		if reloaded {
+			slog.InfoContext(ctx, "reloaded TLS certificate", "cert_file", reloader.certFile)
+		}
+	}
+}
+
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,102 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"simple_bank/tlsconfig/tlstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	version, err := ParseVersion("1.2")
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS12), version)
+
+	version, err = ParseVersion("1.3")
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS13), version)
+
+	for _, s := range []string{"", "1.1", "TLS1.3"} {
+		_, err := ParseVersion(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestNew(t *testing.T) {
+	ca := tlstest.NewCA(t)
+	certFile, keyFile, _ := ca.Issue(t, "server", "localhost")
+
+	config, _, err := New(Options{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"})
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+	require.Equal(t, tls.NoClientCert, config.ClientAuth)
+	require.Nil(t, config.ClientCAs)
+
+	config, _, err = New(Options{CertFile: certFile, KeyFile: keyFile, ClientCAFile: ca.CertFile, MinVersion: "1.2"})
+	require.NoError(t, err)
+	require.Equal(t, tls.VerifyClientCertIfGiven, config.ClientAuth)
+	require.NotNil(t, config.ClientCAs)
+
+	_, _, err = New(Options{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile, MinVersion: "1.2"})
+	require.ErrorContains(t, err, "no certificates found")
+
+	_, _, err = New(Options{CertFile: certFile, KeyFile: filepath.Join(t.TempDir(), "missing"), MinVersion: "1.2"})
+	require.Error(t, err)
+}
+
+func TestCertReloader(t *testing.T) {
+	ca := tlstest.NewCA(t)
+	certFile, keyFile, first := ca.Issue(t, "server", "first")
+
+	reloader, err := NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	cert, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, first.Certificate, cert.Certificate)
+
+	reloaded, err := reloader.Reload()
+	require.NoError(t, err)
+	require.False(t, reloaded)
+
+	// Issue a new pair over the same files, with a later modification time
+	// in case the file system's clock resolution is coarse.
+	_, _, second := ca.Issue(t, "server", "second")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+
+	reloaded, err = reloader.Reload()
+	require.NoError(t, err)
+	require.True(t, reloaded)
+
+	cert, err = reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, second.Certificate, cert.Certificate)
+}
+
+func TestCertReloaderKeepsCertOnError(t *testing.T) {
+	ca := tlstest.NewCA(t)
+	certFile, keyFile, first := ca.Issue(t, "server", "first")
+
+	reloader, err := NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	// A half-written rotation: the certificate changed but the key is garbage.
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.WriteFile(keyFile, []byte("not a key"), 0o600))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+
+	reloaded, err := reloader.Reload()
+	require.Error(t, err)
+	require.False(t, reloaded)
+
+	cert, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, first.Certificate, cert.Certificate)
+}
@@ -0,0 +1,90 @@
+// Package tracing configures OpenTelemetry tracing for the HTTP server and
+// the database layer.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ServiceName identifies this service in traces.
+const ServiceName = "simple_bank"
+
+// Supported span exporters.
+const (
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Setup installs the global tracer provider and the W3C trace-context
+// propagator. exporter is "stdout", "otlp" or empty to disable exporting;
+// endpoint is the OTLP/HTTP collector URL and defaults to the standard
+// OTEL_EXPORTER_OTLP_* environment variables when empty. The returned
+// function flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, exporter, endpoint string) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if exporter == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	spanExporter, err := newExporter(ctx, exporter, endpoint, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, exporter, endpoint string, w io.Writer) (sdktrace.SpanExporter, error) {
+	switch exporter {
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(w))
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter %q", exporter)
+	}
+}
+
+// queryName matches the "-- name: GetAccount :one" header sqlc puts at the
+// start of every generated statement.
+var queryName = regexp.MustCompile(`^-- name: (\w+) :\w+`)
+
+// QuerySpanName names SQL spans after the sqlc query instead of the full
+// statement, falling back to the statement for hand-written SQL.
+func QuerySpanName(stmt string) string {
+	if m := queryName.FindStringSubmatch(stmt); m != nil {
+		return m[1]
+	}
+	return stmt
+}
@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestQuerySpanName(t *testing.T) {
+	testCases := []struct {
+		stmt string
+		want string
+	}{
+		{"-- name: GetAccount :one\nSELECT id FROM accounts WHERE id = $1", "GetAccount"},
+		{"-- name: ListAccounts :many\nSELECT id FROM accounts", "ListAccounts"},
+		{"SELECT version, dirty FROM schema_migrations", "SELECT version, dirty FROM schema_migrations"},
+		{"begin", "begin"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.want, QuerySpanName(tc.stmt))
+	}
+}
+
+func TestNewExporter(t *testing.T) {
+	var buf bytes.Buffer
+	exporter, err := newExporter(context.Background(), ExporterStdout, "", &buf)
+	require.NoError(t, err)
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	_, span := provider.Tracer("test").Start(context.Background(), "transfer")
+	span.End()
+	require.NoError(t, provider.Shutdown(context.Background()))
+	require.Contains(t, buf.String(), `"Name":"transfer"`)
+
+	exporter, err = newExporter(context.Background(), ExporterOTLP, "http://localhost:4318", &buf)
+	require.NoError(t, err)
+	require.NoError(t, exporter.Shutdown(context.Background()))
+
+	_, err = newExporter(context.Background(), "zipkin", "", &buf)
+	require.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package twofactor
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestHOTPRFC6238 checks the SHA-1 test vectors of RFC 6238, appendix B.
+func TestHOTPRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	testCases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tc := range testCases {
+		step := Step(time.Unix(tc.unix, 0))
+		require.Equal(t, tc.want, hotp(key, uint64(step), 8))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Now()
+
+	code, err := Code(secret, Step(now))
+	require.NoError(t, err)
+	require.Len(t, code, Digits)
+
+	step, ok := Validate(secret, code, now)
+	require.True(t, ok)
+	require.Equal(t, Step(now), step)
+
+	// A code from the previous step is still accepted, one from further back is not.
+	step, ok = Validate(secret, code, now.Add(Period))
+	require.True(t, ok)
+	require.Equal(t, Step(now), step)
+	_, ok = Validate(secret, code, now.Add(2*Period))
+	require.False(t, ok)
+
+	_, ok = Validate(secret, "12345", now)
+	require.False(t, ok)
+	_, ok = Validate("not base32!", "123456", now)
+	require.False(t, ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	uri := ProvisioningURI(Issuer, "alice", secret)
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", u.Scheme)
+	require.Equal(t, "totp", u.Host)
+	require.Equal(t, "/Simple Bank:alice", u.Path)
+	require.Equal(t, secret, u.Query().Get("secret"))
+	require.Equal(t, Issuer, u.Query().Get("issuer"))
+	require.Equal(t, "6", u.Query().Get("digits"))
+	require.NotContains(t, uri, "+")
+}
@@ -0,0 +1,270 @@
+// Package twofactor implements TOTP two-factor authentication (RFC 6238):
+// enrolling an authenticator app, one-time recovery codes, and checking a
+// second factor at login and for large transfers.
+package twofactor
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+)
+
+// Issuer names the bank in authenticator apps.
+const Issuer = "Simple Bank"
+
+// Recovery codes handed out when two-factor authentication is enabled.
+const (
+	RecoveryCodeCount = 10
+	recoveryCodeBytes = 10
+)
+
+// Methods a user can pass the second factor of a login with.
+const (
+	MethodTOTP         = "totp"
+	MethodRecoveryCode = "recovery_code"
+)
+
+var (
+	ErrNotConfigured = errors.New("two-factor authentication is not configured on this server")
+	ErrNotEnrolled   = errors.New("two-factor authentication enrollment has not been started")
+	ErrNotEnabled    = errors.New("two-factor authentication is not enabled")
+	ErrCodeRequired  = errors.New("two-factor authentication code is required")
+	ErrCodeInvalid   = errors.New("two-factor authentication code is invalid")
+)
+
+// Enrollment is handed to a user to add their secret to an authenticator app.
+type Enrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+// Service enrolls users and checks their codes. TOTP secrets are stored
+// sealed with AES-256-GCM.
+type Service struct {
+	store db.Store
+	aead  cipher.AEAD
+	now   func() time.Time
+}
+
+// NewService creates a Service that seals secrets with encryptionKey, which
+// must be 32 characters. With an empty key, users cannot enroll, and those
+// who already have cannot log in.
+func NewService(store db.Store, encryptionKey string) (*Service, error) {
+	service := &Service{store: store, now: time.Now}
+	if encryptionKey == "" {
+		return service, nil
+	}
+
+	if len(encryptionKey) != 32 {
+		return nil, fmt.Errorf("invalid TOTP encryption key size: must be exactly 32 characters")
+	}
+	block, err := aes.NewCipher([]byte(encryptionKey))
+	if err != nil {
+		return nil, err
+	}
+	service.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// Enroll creates a new secret for username, replacing any unconfirmed one.
+// The second factor is only required once Enable confirms it. It fails with
+// db.ErrTOTPAlreadyEnabled if username already has a confirmed secret.
+func (s *Service) Enroll(ctx context.Context, username string) (Enrollment, error) {
+	if s.aead == nil {
+		return Enrollment{}, ErrNotConfigured
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		return Enrollment{}, err
+	}
+	sealed, err := s.seal(secret)
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	_, err = s.store.CreateTotpSecret(ctx, db.CreateTotpSecretParams{
+		Username:        username,
+		SecretEncrypted: sealed,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return Enrollment{}, db.ErrTOTPAlreadyEnabled
+		}
+		return Enrollment{}, err
+	}
+
+	return Enrollment{
+		Secret:          secret,
+		ProvisioningURI: ProvisioningURI(Issuer, username, secret),
+	}, nil
+}
+
+// Enable confirms the secret from Enroll with a code from the user's app
+// and returns their recovery codes, which are only stored hashed.
+func (s *Service) Enable(ctx context.Context, username, code string) ([]string, error) {
+	totp, err := s.store.GetTotpSecret(ctx, username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return nil, ErrNotEnrolled
+		}
+		return nil, err
+	}
+	if totp.ConfirmedAt.Valid {
+		return nil, db.ErrTOTPAlreadyEnabled
+	}
+
+	step, err := s.validate(totp, code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([][]byte, RecoveryCodeCount)
+	for i := range codes {
+		codes[i], err = util.NewSecret(recoveryCodeBytes)
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = util.HashSecret(codes[i])
+	}
+
+	_, err = s.store.EnableTOTPTx(ctx, db.EnableTOTPTxParams{
+		Username:           username,
+		Step:               step,
+		RecoveryCodeHashes: hashes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyLogin checks the second factor of a login for username. Users
+// without two-factor authentication pass with method "". Others must send
+// a TOTP code or, if they lost their app, one of their recovery codes,
+// which then stops working.
+func (s *Service) VerifyLogin(ctx context.Context, username, code, recoveryCode string) (method string, err error) {
+	totp, err := s.enabledSecret(ctx, username)
+	if errors.Is(err, ErrNotEnabled) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if recoveryCode != "" {
+		_, err := s.store.UseTotpRecoveryCode(ctx, db.UseTotpRecoveryCodeParams{
+			Username: username,
+			CodeHash: util.HashSecret(recoveryCode),
+		})
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				return "", ErrCodeInvalid
+			}
+			return "", err
+		}
+		return MethodRecoveryCode, nil
+	}
+
+	if err := s.useCode(ctx, totp, code); err != nil {
+		return "", err
+	}
+	return MethodTOTP, nil
+}
+
+// VerifyStepUp checks a TOTP code that confirms a sensitive action, such
+// as a large transfer. Recovery codes are not accepted. It fails with
+// ErrNotEnabled if username has not enabled two-factor authentication.
+func (s *Service) VerifyStepUp(ctx context.Context, username, code string) error {
+	totp, err := s.enabledSecret(ctx, username)
+	if err != nil {
+		return err
+	}
+	return s.useCode(ctx, totp, code)
+}
+
+func (s *Service) enabledSecret(ctx context.Context, username string) (db.TotpSecret, error) {
+	totp, err := s.store.GetTotpSecret(ctx, username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return db.TotpSecret{}, ErrNotEnabled
+		}
+		return db.TotpSecret{}, err
+	}
+	if !totp.ConfirmedAt.Valid {
+		return db.TotpSecret{}, ErrNotEnabled
+	}
+	return totp, nil
+}
+
+// useCode validates code and records its step, so that it is rejected if
+// sent again.
+func (s *Service) useCode(ctx context.Context, totp db.TotpSecret, code string) error {
+	step, err := s.validate(totp, code)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.store.UseTotpStep(ctx, db.UseTotpStepParams{
+		Username:     totp.Username,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return ErrCodeInvalid
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Service) validate(totp db.TotpSecret, code string) (int64, error) {
+	if code == "" {
+		return 0, ErrCodeRequired
+	}
+	secret, err := s.open(totp.SecretEncrypted)
+	if err != nil {
+		return 0, err
+	}
+
+	step, ok := Validate(secret, code, s.now())
+	if !ok {
+		return 0, ErrCodeInvalid
+	}
+	return step, nil
+}
+
+// seal encrypts secret with a random nonce, which it prepends.
+func (s *Service) seal(secret string) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, []byte(secret), nil), nil
+}
+
+func (s *Service) open(sealed []byte) (string, error) {
+	if s.aead == nil {
+		return "", ErrNotConfigured
+	}
+	if len(sealed) < s.aead.NonceSize() {
+		return "", fmt.Errorf("cannot decrypt TOTP secret: too short")
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	secret, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt TOTP secret: %w", err)
+	}
+	return string(secret), nil
+}
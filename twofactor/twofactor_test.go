@@ -0,0 +1,208 @@
+package twofactor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T, store db.Store) *Service {
+	service, err := NewService(store, util.RandomString(32))
+	require.NoError(t, err)
+	return service
+}
+
+// enabledSecret returns a confirmed TOTP row for username and its secret.
+func enabledSecret(t *testing.T, service *Service, username string) (db.TotpSecret, string) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	sealed, err := service.seal(secret)
+	require.NoError(t, err)
+
+	return db.TotpSecret{
+		Username:        username,
+		SecretEncrypted: sealed,
+		ConfirmedAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}, secret
+}
+
+func currentCode(t *testing.T, secret string) string {
+	code, err := Code(secret, Step(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+func TestNewServiceInvalidKey(t *testing.T) {
+	_, err := NewService(nil, "too-short")
+	require.Error(t, err)
+
+	service, err := NewService(nil, "")
+	require.NoError(t, err)
+	_, err = service.Enroll(context.Background(), "alice")
+	require.ErrorIs(t, err, ErrNotConfigured)
+}
+
+func TestEnrollAndEnable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	service := newTestService(t, store)
+
+	var pending db.TotpSecret
+	store.EXPECT().
+		CreateTotpSecret(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateTotpSecretParams) (db.TotpSecret, error) {
+			pending = db.TotpSecret{Username: arg.Username, SecretEncrypted: arg.SecretEncrypted}
+			return pending, nil
+		})
+
+	enrollment, err := service.Enroll(context.Background(), "alice")
+	require.NoError(t, err)
+	require.Contains(t, enrollment.ProvisioningURI, "secret="+enrollment.Secret)
+	require.NotContains(t, string(pending.SecretEncrypted), enrollment.Secret)
+
+	store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq("alice")).AnyTimes().Return(pending, nil)
+
+	_, err = service.Enable(context.Background(), "alice", "abcdef")
+	require.ErrorIs(t, err, ErrCodeInvalid)
+
+	store.EXPECT().
+		EnableTOTPTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.EnableTOTPTxParams) (db.TotpSecret, error) {
+			require.Equal(t, "alice", arg.Username)
+			require.Equal(t, Step(time.Now()), arg.Step)
+			require.Len(t, arg.RecoveryCodeHashes, RecoveryCodeCount)
+			return db.TotpSecret{}, nil
+		})
+
+	codes, err := service.Enable(context.Background(), "alice", currentCode(t, enrollment.Secret))
+	require.NoError(t, err)
+	require.Len(t, codes, RecoveryCodeCount)
+}
+
+func TestEnrollAlreadyEnabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	service := newTestService(t, store)
+
+	store.EXPECT().
+		CreateTotpSecret(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.TotpSecret{}, db.ErrRecordNotFound)
+
+	_, err := service.Enroll(context.Background(), "alice")
+	require.ErrorIs(t, err, db.ErrTOTPAlreadyEnabled)
+}
+
+func TestVerifyLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	service := newTestService(t, store)
+	totp, secret := enabledSecret(t, service, "alice")
+
+	testCases := []struct {
+		name         string
+		code         string
+		recoveryCode string
+		buildStubs   func(store *mockdb.MockStore)
+		wantMethod   string
+		wantErr      error
+	}{
+		{
+			name: "NotEnabled",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).Times(1).Return(db.TotpSecret{}, db.ErrRecordNotFound)
+			},
+		},
+		{
+			name: "NotConfirmed",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).Times(1).Return(db.TotpSecret{Username: "alice"}, nil)
+			},
+		},
+		{
+			name: "CodeRequired",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).Times(1).Return(totp, nil)
+			},
+			wantErr: ErrCodeRequired,
+		},
+		{
+			name: "Code",
+			code: currentCode(t, secret),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).Times(1).Return(totp, nil)
+				arg := db.UseTotpStepParams{Username: "alice", LastUsedStep: Step(time.Now())}
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Eq(arg)).Times(1).Return(totp, nil)
+			},
+			wantMethod: MethodTOTP,
+		},
+		{
+			name: "ReplayedCode",
+			code: currentCode(t, secret),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).Times(1).Return(totp, nil)
+				store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(1).Return(db.TotpSecret{}, db.ErrRecordNotFound)
+			},
+			wantErr: ErrCodeInvalid,
+		},
+		{
+			name:         "RecoveryCode",
+			recoveryCode: "recovery",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).Times(1).Return(totp, nil)
+				arg := db.UseTotpRecoveryCodeParams{Username: "alice", CodeHash: util.HashSecret("recovery")}
+				store.EXPECT().UseTotpRecoveryCode(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			wantMethod: MethodRecoveryCode,
+		},
+		{
+			name:         "UsedRecoveryCode",
+			recoveryCode: "recovery",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).Times(1).Return(totp, nil)
+				store.EXPECT().UseTotpRecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(db.TotpRecoveryCode{}, db.ErrRecordNotFound)
+			},
+			wantErr: ErrCodeInvalid,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.buildStubs(store)
+
+			method, err := service.VerifyLogin(context.Background(), "alice", tc.code, tc.recoveryCode)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantMethod, method)
+		})
+	}
+}
+
+func TestVerifyStepUp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	service := newTestService(t, store)
+
+	store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).Times(1).Return(db.TotpSecret{}, db.ErrRecordNotFound)
+	err := service.VerifyStepUp(context.Background(), "alice", "123456")
+	require.ErrorIs(t, err, ErrNotEnabled)
+
+	totp, secret := enabledSecret(t, service, "alice")
+	store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).Times(1).Return(totp, nil)
+	store.EXPECT().UseTotpStep(gomock.Any(), gomock.Any()).Times(1).Return(totp, nil)
+	err = service.VerifyStepUp(context.Background(), "alice", currentCode(t, secret))
+	require.NoError(t, err)
+}
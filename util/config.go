@@ -1,29 +1,351 @@
 package util
 
-import "github.com/spf13/viper"
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+)
+
+// Profiles select a set of defaults and validation rules through APP_ENV.
+const (
+	ProfileDev  = "dev"
+	ProfileTest = "test"
+	ProfileProd = "prod"
+)
+
+// Config holds the application settings. Each field is read from the
+// variable named in its env tag, falling back to the profile default and
+// then to its default tag. Keys marked "file" can instead be read from the
+// file named by <KEY>_FILE, for secrets mounted by an orchestrator.
 type Config struct {
-	DBDriver      string `mapstructure:"DB_DRIVER"`
-	DBSource      string `mapstructure:"DB_SOURCE"`
-	ServerAddress string `mapstructure:"SERVER_ADDRESS"`
+	Environment             string        `env:"APP_ENV" default:"dev" validate:"oneof=dev test prod"`
+	DBDriver                string        `env:"DB_DRIVER" default:"postgres" validate:"oneof=postgres"`
+	DBSource                string        `env:"DB_SOURCE,file" validate:"required,db_url"`
+	MigrateOnStart          bool          `env:"MIGRATE_ON_START" default:"false"`
+	GRPCServerAddress       string        `env:"GRPC_SERVER_ADDRESS" validate:"omitempty,hostname_port"`
+	ServerAddress           string        `env:"SERVER_ADDRESS" default:"0.0.0.0:8080" validate:"required,hostname_port"`
+	TLSCertFile             string        `env:"TLS_CERT_FILE" validate:"required_with=TLSKeyFile TLSClientCAFile,omitempty,file"`
+	TLSKeyFile              string        `env:"TLS_KEY_FILE" validate:"required_with=TLSCertFile,omitempty,file"`
+	TLSClientCAFile         string        `env:"TLS_CLIENT_CA_FILE" validate:"omitempty,file"`
+	TLSMinVersion           string        `env:"TLS_MIN_VERSION" default:"1.2" validate:"oneof=1.2 1.3"`
+	TLSReloadInterval       time.Duration `env:"TLS_RELOAD_INTERVAL" default:"30s" validate:"gt=0"`
+	HTTPReadTimeout         time.Duration `env:"HTTP_READ_TIMEOUT" default:"10s" validate:"gte=0"`
+	HTTPReadHeaderTimeout   time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" default:"5s" validate:"gte=0"`
+	HTTPWriteTimeout        time.Duration `env:"HTTP_WRITE_TIMEOUT" default:"15s" validate:"gte=0"`
+	HTTPIdleTimeout         time.Duration `env:"HTTP_IDLE_TIMEOUT" default:"60s" validate:"gte=0"`
+	HTTPMaxHeaderBytes      int           `env:"HTTP_MAX_HEADER_BYTES" default:"1048576" validate:"gte=0"`
+	TrustedProxies          []string      `env:"TRUSTED_PROXIES"`
+	ShutdownTimeout         time.Duration `env:"SHUTDOWN_TIMEOUT" default:"30s" validate:"gt=0"`
+	LogLevel                string        `env:"LOG_LEVEL" default:"info" validate:"oneof=debug info warn error"`
+	RateLimitBackend        string        `env:"RATE_LIMIT_BACKEND" default:"memory" validate:"omitempty,oneof=memory postgres"`
+	RateLimitDefault        string        `env:"RATE_LIMIT_DEFAULT" default:"300/1m"`
+	RateLimitTransfers      string        `env:"RATE_LIMIT_TRANSFERS" default:"10/1m"`
+	RateLimitLogin          string        `env:"RATE_LIMIT_LOGIN" default:"5/1m"`
+	LoginMaxFailures        int           `env:"LOGIN_MAX_FAILURES" default:"5" validate:"gte=0"`
+	LoginMaxFailuresPerIP   int           `env:"LOGIN_MAX_FAILURES_PER_IP" default:"20" validate:"gte=0"`
+	LoginLockout            time.Duration `env:"LOGIN_LOCKOUT" default:"1m" validate:"gt=0"`
+	LoginMaxLockout         time.Duration `env:"LOGIN_MAX_LOCKOUT" default:"1h" validate:"gtefield=LoginLockout"`
+	TokenSymmetricKey       string        `env:"TOKEN_SYMMETRIC_KEY,file" validate:"required,len=32"`
+	AccessTokenDuration     time.Duration `env:"ACCESS_TOKEN_DURATION" default:"15m" validate:"gt=0"`
+	PasswordResetDuration   time.Duration `env:"PASSWORD_RESET_DURATION" default:"30m" validate:"gt=0"`
+	OAuthCodeDuration       time.Duration `env:"OAUTH_CODE_DURATION" default:"5m" validate:"gt=0,lte=10m"`
+	OAuthTokenDuration      time.Duration `env:"OAUTH_TOKEN_DURATION" default:"1h" validate:"gt=0"`
+	TOTPEncryptionKey       string        `env:"TOTP_ENCRYPTION_KEY,file" validate:"omitempty,len=32"`
+	TransferStepUpThreshold int           `env:"TRANSFER_STEP_UP_THRESHOLD" default:"0" validate:"gte=0"`
+	EmailVerifyDuration     time.Duration `env:"EMAIL_VERIFY_DURATION" default:"24h" validate:"gt=0"`
+	PublicURL               string        `env:"PUBLIC_URL" default:"http://localhost:8080" validate:"url"`
+	Mailer                  string        `env:"MAILER" default:"log" validate:"oneof=log file smtp"`
+	MailTarget              string        `env:"MAIL_TARGET" validate:"required_if=Mailer file"`
+	MailFrom                string        `env:"MAIL_FROM" default:"Simple Bank <no-reply@simplebank.local>"`
+	SMTPAddress             string        `env:"SMTP_ADDRESS" validate:"required_if=Mailer smtp,omitempty,hostname_port"`
+	SMTPUsername            string        `env:"SMTP_USERNAME"`
+	SMTPPassword            string        `env:"SMTP_PASSWORD,file"`
+	StreamBufferSize        int           `env:"STREAM_BUFFER_SIZE" default:"16" validate:"gt=0"`
+	OutboxSink              string        `env:"OUTBOX_SINK" validate:"omitempty,oneof=stdout file webhook"`
+	OutboxTarget            string        `env:"OUTBOX_TARGET"`
+	OutboxPollInterval      time.Duration `env:"OUTBOX_POLL_INTERVAL" default:"1s" validate:"gt=0"`
+	WebhookTimeout          time.Duration `env:"WEBHOOK_TIMEOUT" default:"10s" validate:"gt=0"`
+	TracingExporter         string        `env:"TRACING_EXPORTER" validate:"omitempty,oneof=stdout otlp"`
+	TracingEndpoint         string        `env:"TRACING_ENDPOINT" validate:"omitempty,url"`
+}
+
+// profileDefaults override the default tags for each profile.
+var profileDefaults = map[string]map[string]string{
+	ProfileDev: {
+		"LOG_LEVEL":        "debug",
+		"MIGRATE_ON_START": "true",
+	},
+	ProfileTest: {
+		"LOG_LEVEL":          "warn",
+		"RATE_LIMIT_BACKEND": "",
+	},
+	ProfileProd: {
+		"LOG_LEVEL": "info",
+	},
+}
+
+// FieldError describes one invalid config key.
+type FieldError struct {
+	Key     string
+	Message string
+}
+
+// ValidationError lists every invalid key found by LoadConfig.
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	problems := make([]string, len(e))
+	for i, fieldErr := range e {
+		problems[i] = fieldErr.Key + " " + fieldErr.Message
+	}
+	return "invalid config: " + strings.Join(problems, "; ")
 }
 
+// LoadConfig reads the config from path/app.env, then from
+// path/app.<APP_ENV>.env, then from the environment, each layer overriding
+// the last. Both files are optional. If any key is missing or malformed the
+// returned error is a ValidationError listing all of them.
 func LoadConfig(path string) (config Config, err error) {
-	viper.AddConfigPath(path)
-	viper.SetConfigName("app")
-	viper.SetConfigType("env")
+	v := viper.New()
+	v.SetConfigType("env")
+
+	fields := configFields()
+	for _, field := range fields {
+		v.MustBindEnv(field.key)
+		if field.file {
+			v.MustBindEnv(field.key + "_FILE")
+		}
+	}
+
+	if err := mergeConfigFile(v, filepath.Join(path, "app.env")); err != nil {
+		return config, err
+	}
+
+	profile := v.GetString("APP_ENV")
+	if profile == "" {
+		profile = ProfileDev
+	}
+	if _, ok := profileDefaults[profile]; ok {
+		if err := mergeConfigFile(v, filepath.Join(path, "app."+profile+".env")); err != nil {
+			return config, err
+		}
+	}
+
+	for _, field := range fields {
+		v.SetDefault(field.key, field.defaultValue)
+	}
+	for key, value := range profileDefaults[profile] {
+		v.SetDefault(key, value)
+	}
+
+	var errs ValidationError
+	invalid := map[string]bool{}
+	value := reflect.ValueOf(&config).Elem()
+	for _, field := range fields {
+		raw, fieldErr := lookup(v, field)
+		if fieldErr == nil {
+			fieldErr = decode(value.Field(field.index), raw)
+		}
+		if fieldErr != nil {
+			if fieldErr.Key == "" {
+				fieldErr.Key = field.key
+			}
+			errs = append(errs, *fieldErr)
+			invalid[field.key] = true
+		}
+	}
+
+	// Keys that could not be read are reported once, not again for failing
+	// validation with their zero value.
+	for _, fieldErr := range validateConfig(config, fields) {
+		if !invalid[fieldErr.Key] {
+			errs = append(errs, fieldErr)
+		}
+	}
+	if len(errs) > 0 {
+		return config, errs
+	}
+	return config, nil
+}
+
+type configField struct {
+	index        int
+	name         string
+	key          string
+	file         bool
+	defaultValue string
+}
+
+func configFields() []configField {
+	t := reflect.TypeOf(Config{})
+	fields := make([]configField, t.NumField())
+	for i := range fields {
+		f := t.Field(i)
+		key, option, _ := strings.Cut(f.Tag.Get("env"), ",")
+		fields[i] = configField{
+			index:        i,
+			name:         f.Name,
+			key:          key,
+			file:         option == "file",
+			defaultValue: f.Tag.Get("default"),
+		}
+	}
+	return fields
+}
+
+// mergeConfigFile merges the env file at path into v, if it exists.
+func mergeConfigFile(v *viper.Viper, path string) error {
+	v.SetConfigFile(path)
+	err := v.MergeInConfig()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	return nil
+}
 
-	viper.AutomaticEnv()
+// lookup returns the raw value of field, reading it from <KEY>_FILE if set.
+func lookup(v *viper.Viper, field configField) (string, *FieldError) {
+	if !field.file || v.GetString(field.key+"_FILE") == "" {
+		return v.GetString(field.key), nil
+	}
 
-	err = viper.ReadInConfig()
+	fileKey := field.key + "_FILE"
+	if v.IsSet(field.key) && v.GetString(field.key) != "" {
+		return "", &FieldError{fileKey, "cannot be set together with " + field.key}
+	}
+	data, err := os.ReadFile(v.GetString(fileKey))
 	if err != nil {
-		return
+		return "", &FieldError{fileKey, err.Error()}
 	}
+	return strings.TrimSpace(string(data)), nil
+}
 
-	err = viper.Unmarshal(&config)
+func decode(dst reflect.Value, raw string) *FieldError {
+	var err error
+	switch dst.Interface().(type) {
+	case string:
+		dst.SetString(raw)
+	case bool:
+		var b bool
+		if b, err = strconv.ParseBool(raw); err == nil {
+			dst.SetBool(b)
+		}
+	case int:
+		var n int
+		if n, err = strconv.Atoi(raw); err == nil {
+			dst.SetInt(int64(n))
+		}
+	case time.Duration:
+		var d time.Duration
+		if d, err = time.ParseDuration(raw); err == nil {
+			dst.SetInt(int64(d))
+		}
+	case []string:
+		// A comma-separated list; empty means nil.
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		dst.Set(reflect.ValueOf(items))
+	default:
+		panic(fmt.Sprintf("unsupported config type %s", dst.Type()))
+	}
 	if err != nil {
-		return
+		return &FieldError{Message: fmt.Sprintf("has invalid %s %q", dst.Type(), raw)}
 	}
+	return nil
+}
 
-	return
+var configValidator = newConfigValidator()
+
+func newConfigValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterValidation("db_url", func(fl validator.FieldLevel) bool {
+		u, err := url.Parse(fl.Field().String())
+		return err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") && u.Host != ""
+	})
+	return v
+}
+
+func validateConfig(config Config, fields []configField) ValidationError {
+	keys := map[string]string{}
+	for _, field := range fields {
+		keys[field.name] = field.key
+	}
+
+	var errs ValidationError
+	var validationErrs validator.ValidationErrors
+	if errors.As(configValidator.Struct(config), &validationErrs) {
+		for _, fe := range validationErrs {
+			errs = append(errs, FieldError{keys[fe.StructField()], validationMessage(fe, keys)})
+		}
+	}
+
+	for _, proxy := range config.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, FieldError{"TRUSTED_PROXIES", fmt.Sprintf("has invalid IP or CIDR %q", proxy)})
+			}
+		}
+	}
+
+	if config.Environment == ProfileProd {
+		if u, err := url.Parse(config.DBSource); err == nil && u.Query().Get("sslmode") == "disable" {
+			errs = append(errs, FieldError{"DB_SOURCE", "must not disable TLS in prod"})
+		}
+		// The log mailer writes reset tokens and verification codes to the log.
+		if config.Mailer == "log" {
+			errs = append(errs, FieldError{"MAILER", "must not be log in prod"})
+		}
+	}
+	return errs
+}
+
+func validationMessage(fe validator.FieldError, keys map[string]string) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_with":
+		var others []string
+		for _, name := range strings.Fields(fe.Param()) {
+			others = append(others, keys[name])
+		}
+		return "is required with " + strings.Join(others, " or ")
+	case "required_if":
+		name, value, _ := strings.Cut(fe.Param(), " ")
+		return "is required when " + keys[name] + " is " + value
+	case "file":
+		return "must be an existing file"
+	case "db_url":
+		return "must be a postgres:// or postgresql:// URL"
+	case "hostname_port":
+		return "must be host:port"
+	case "url":
+		return "must be a URL"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "len":
+		return "must be exactly " + fe.Param() + " characters"
+	case "gt":
+		return "must be positive"
+	case "gte":
+		return "must not be negative"
+	}
+	return "failed " + fe.Tag() + " validation"
 }
@@ -1,12 +1,16 @@
 package util
 
-// Supported currencies
+import "regexp"
+
+// Currencies enabled by the initial migration
 const (
 	USD = "USD"
 	EUR = "EUR"
 	CAD = "CAD"
 )
 
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
 // IsSupportedCurrency checks if the currency is supported
 func IsSupportedCurrency(currency string) bool {
 	switch currency {
@@ -15,3 +19,9 @@ func IsSupportedCurrency(currency string) bool {
 	}
 	return false
 }
+
+// IsCurrencyCode checks if code looks like an ISO 4217 currency code.
+// Whether the currency is enabled is up to the currencies table.
+func IsCurrencyCode(code string) bool {
+	return currencyCode.MatchString(code)
+}
@@ -0,0 +1,15 @@
+package util
+
+// Roles a user can hold an account in. The primary holder opened the account
+// and decides who else holds it; joint holders can use it like the primary
+// holder, and viewers can only see it.
+const (
+	PrimaryHolder = "primary"
+	JointHolder   = "joint"
+	ViewerHolder  = "viewer"
+)
+
+// CanTransfer reports whether holders in role may send money from the account.
+func CanTransfer(role string) bool {
+	return role == PrimaryHolder || role == JointHolder
+}
@@ -0,0 +1,32 @@
+package util
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HashPassword returns the bcrypt hash of the password
+func HashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hashedPassword), nil
+}
+
+// CheckPassword checks if the provided password is correct or not
+func CheckPassword(password string, hashedPassword string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+}
+
+// unknownUserHash is the bcrypt hash, at the cost HashPassword uses, of a
+// random password that was thrown away.
+const unknownUserHash = "$2a$10$VMgW2JU6uYmGgljTdn3f1u5417fAYJKpM77HZ687AsmSJj9NI0VJu"
+
+// CheckUnknownUserPassword does the work of CheckPassword for a username
+// that does not exist, so that the time a failed login takes does not tell
+// whether the username does.
+func CheckUnknownUserPassword(password string) {
+	CheckPassword(password, unknownUserHash)
+}
@@ -0,0 +1,17 @@
+package util
+
+// Roles a user can have. Every user is a customer unless promoted.
+const (
+	CustomerRole = "customer"
+	SupportRole  = "support"
+	AdminRole    = "admin"
+)
+
+// IsSupportedRole checks if the role is one of the roles above
+func IsSupportedRole(role string) bool {
+	switch role {
+	case CustomerRole, SupportRole, AdminRole:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+package util
+
+// Scopes an API key can be granted. Access tokens from login are not
+// scoped: they may do whatever the user's role allows.
+const (
+	ScopeAccountsRead    = "accounts:read"
+	ScopeTransfersCreate = "transfers:create"
+)
+
+// IsSupportedScope checks if the scope is one of the scopes above
+func IsSupportedScope(scope string) bool {
+	switch scope {
+	case ScopeAccountsRead, ScopeTransfersCreate:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+// Package val holds the custom validations used in request binding tags,
+// shared by the HTTP and gRPC APIs.
+package val
+
+import (
+	db "simple_bank/db/sqlc"
+	"simple_bank/util"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if currency, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsCurrencyCode(currency)
+	}
+	return false
+}
+
+var validEventType validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if eventType, ok := fieldLevel.Field().Interface().(string); ok {
+		return db.IsSupportedEventType(eventType)
+	}
+	return false
+}
+
+var validAPIScope validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if scope, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsSupportedScope(scope)
+	}
+	return false
+}
+
+// RegisterValidations adds the custom validations to v.
+func RegisterValidations(v *validator.Validate) {
+	v.RegisterValidation("currency", validCurrency)
+	v.RegisterValidation("event_type", validEventType)
+	v.RegisterValidation("api_scope", validAPIScope)
+}
@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"context"
+
+	db "simple_bank/db/sqlc"
+	"simple_bank/outbox"
+)
+
+// Dispatcher is an outbox.Sink that queues a delivery for every webhook
+// subscribed to the event type and owning one of the event's accounts.
+type Dispatcher struct {
+	store db.Store
+}
+
+// NewDispatcher creates a new Dispatcher.
+func NewDispatcher(store db.Store) *Dispatcher {
+	return &Dispatcher{store: store}
+}
+
+func (dispatcher *Dispatcher) Publish(ctx context.Context, event outbox.Event) error {
+	webhooks, err := dispatcher.store.ListWebhooksForEvent(ctx, db.ListWebhooksForEventParams{
+		EventType:  event.Type,
+		AccountIds: event.AccountIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		// Redelivered outbox events are ignored by the (webhook_id, event_id) key.
+		err := dispatcher.store.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+			WebhookID: webhook.ID,
+			EventID:   event.ID,
+			EventType: event.Type,
+			Payload:   event.Payload,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
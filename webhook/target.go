@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// Errors returned by CheckURL and by clients from NewClient.
+var (
+	ErrInsecureURL     = errors.New("webhook URL must use https")
+	ErrForbiddenTarget = errors.New("webhook URL must point at a public address")
+)
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which
+// net.IP.IsPrivate leaves out.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Resolver looks up the addresses of a host. *net.Resolver implements it.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// IsPublicIP reports whether webhooks may be delivered to ip: loopback,
+// private, link-local, shared, multicast and unspecified addresses would let
+// subscribers reach the bank's own network.
+func IsPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// CheckURL checks that rawURL is an http URL, or https if requireHTTPS,
+// whose host only resolves to public addresses.
+func CheckURL(ctx context.Context, resolver Resolver, rawURL string, requireHTTPS bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" && (requireHTTPS || u.Scheme != "http") {
+		return ErrInsecureURL
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return fmt.Errorf("%w: %s", ErrForbiddenTarget, host)
+		}
+		return nil
+	}
+
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("cannot resolve webhook host %q: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenTarget, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// NewClient creates the client deliveries are sent with. It gives up on a
+// subscriber after timeout, and refuses to connect to anything but public
+// addresses, so a host that resolved to one when its webhook was created
+// cannot be pointed at the bank's network later.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialControl}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be the only address dialed, so deliveries go direct.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// dialControl runs once the address to connect to is resolved.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenTarget, host)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// staticResolver resolves the hosts it holds and fails for any other.
+type staticResolver map[string]string
+
+func (resolver staticResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ip, ok := resolver[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return []net.IPAddr{{IP: net.ParseIP(ip)}}, nil
+}
+
+func TestCheckURL(t *testing.T) {
+	resolver := staticResolver{
+		"hooks.example.com":    "93.184.216.34",
+		"internal.example.com": "10.0.0.5",
+	}
+
+	testCases := []struct {
+		name         string
+		url          string
+		requireHTTPS bool
+		wantErr      error
+	}{
+		{name: "HTTPS", url: "https://hooks.example.com/events", requireHTTPS: true},
+		{name: "HTTPInDev", url: "http://hooks.example.com/events"},
+		{name: "HTTPOutsideDev", url: "http://hooks.example.com/events", requireHTTPS: true, wantErr: ErrInsecureURL},
+		{name: "OtherScheme", url: "ftp://hooks.example.com/events", wantErr: ErrInsecureURL},
+		{name: "Loopback", url: "https://127.0.0.1:8080/", wantErr: ErrForbiddenTarget},
+		{name: "LoopbackIPv6", url: "https://[::1]/", wantErr: ErrForbiddenTarget},
+		{name: "Metadata", url: "https://169.254.169.254/latest/meta-data", wantErr: ErrForbiddenTarget},
+		{name: "Private", url: "https://192.168.1.10/", wantErr: ErrForbiddenTarget},
+		{name: "Unspecified", url: "https://0.0.0.0/", wantErr: ErrForbiddenTarget},
+		{name: "PrivateHostname", url: "https://internal.example.com/", wantErr: ErrForbiddenTarget},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckURL(context.Background(), resolver, tc.url, tc.requireHTTPS)
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+
+	err := CheckURL(context.Background(), resolver, "https://unknown.example.com/", true)
+	require.ErrorContains(t, err, "cannot resolve")
+}
+
+func TestNewClientRefusesPrivateAddress(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the client reached a loopback receiver")
+	}))
+	defer receiver.Close()
+
+	client := NewClient(time.Second)
+	_, err := client.Post(receiver.URL, "application/json", nil)
+	require.ErrorIs(t, err, ErrForbiddenTarget)
+}
@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "simple_bank/db/sqlc"
+)
+
+// Delivery statuses stored in webhook_deliveries.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const (
+	defaultBatchSize   = 50
+	defaultInterval    = time.Second
+	defaultMaxAttempts = 8
+	baseBackoff        = 5 * time.Second
+	maxBackoff         = time.Hour
+)
+
+// Message is the JSON body POSTed to subscribers.
+type Message struct {
+	EventID   int64           `json:"event_id"`
+	EventType string          `json:"event_type"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Worker sends due webhook deliveries and reschedules failed ones with
+// exponential backoff until the maximum number of attempts is reached.
+type Worker struct {
+	store       db.Store
+	client      *http.Client
+	interval    time.Duration
+	maxAttempts int32
+}
+
+// NewWorker creates a worker sending deliveries with client every interval.
+// Deliveries are sent one at a time, so client needs a Timeout for a
+// subscriber that never answers not to hold up everyone else's.
+func NewWorker(store db.Store, client *http.Client, interval time.Duration) *Worker {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Worker{
+		store:       store,
+		client:      client,
+		interval:    interval,
+		maxAttempts: defaultMaxAttempts,
+	}
+}
+
+// Run sends due deliveries until ctx is cancelled.
+func (worker *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(worker.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := worker.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("webhook worker failed", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue sends one batch of due deliveries and returns how many succeeded.
+func (worker *Worker) DeliverDue(ctx context.Context) (int, error) {
+	deliveries, err := worker.store.ListDueWebhookDeliveries(ctx, defaultBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	succeeded := 0
+	for _, delivery := range deliveries {
+		webhook, err := worker.store.GetWebhook(ctx, delivery.WebhookID)
+		if err != nil {
+			return succeeded, err
+		}
+
+		statusCode, sendErr := worker.send(ctx, webhook, delivery)
+		arg := db.UpdateWebhookDeliveryAttemptParams{
+			ID:             delivery.ID,
+			Status:         StatusSucceeded,
+			ResponseStatus: int32(statusCode),
+			NextAttemptAt:  time.Now(),
+		}
+		if sendErr != nil {
+			arg.LastError = sendErr.Error()
+			arg.Status, arg.NextAttemptAt = worker.retry(delivery.Attempts + 1)
+		} else {
+			succeeded++
+		}
+
+		if _, err := worker.store.UpdateWebhookDeliveryAttempt(ctx, arg); err != nil {
+			return succeeded, err
+		}
+	}
+
+	return succeeded, nil
+}
+
+// retry returns the status and next attempt time after a failed attempt.
+func (worker *Worker) retry(attempts int32) (string, time.Time) {
+	if attempts >= worker.maxAttempts {
+		return StatusFailed, time.Now()
+	}
+	return StatusPending, time.Now().Add(Backoff(attempts))
+}
+
+// Backoff returns the delay before the next attempt after the given number of attempts.
+func Backoff(attempts int32) time.Duration {
+	backoff := baseBackoff
+	for i := int32(1); i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+func (worker *Worker) send(ctx context.Context, webhook db.Webhook, delivery db.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(Message{
+		EventID:   delivery.EventID,
+		EventType: delivery.EventType,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, strconv.FormatInt(webhook.ID, 10))
+	req.Header.Set(EventIDHeader, strconv.FormatInt(delivery.EventID, 10))
+	req.Header.Set(EventTypeHeader, delivery.EventType)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, time.Now(), body))
+
+	resp, err := worker.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
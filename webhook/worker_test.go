@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "simple_bank/db/mock"
+	db "simple_bank/db/sqlc"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignature(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+	body := []byte(`{"event_id":1}`)
+
+	header := Sign(secret, time.Now(), body)
+	require.NoError(t, Verify(secret, header, body, time.Minute))
+
+	require.ErrorIs(t, Verify("whsec_other", header, body, time.Minute), ErrInvalidSignature)
+	require.ErrorIs(t, Verify(secret, header, []byte(`{"event_id":2}`), time.Minute), ErrInvalidSignature)
+	require.ErrorIs(t, Verify(secret, "v1=abc", body, time.Minute), ErrInvalidSignature)
+
+	old := Sign(secret, time.Now().Add(-time.Hour), body)
+	require.ErrorIs(t, Verify(secret, old, body, time.Minute), ErrExpiredSignature)
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, baseBackoff, Backoff(1))
+	require.Equal(t, 2*baseBackoff, Backoff(2))
+	require.Equal(t, 8*baseBackoff, Backoff(4))
+	require.Equal(t, maxBackoff, Backoff(100))
+}
+
+func TestDeliverDue(t *testing.T) {
+	testCases := []struct {
+		name       string
+		status     int
+		attempts   int32
+		wantStatus string
+	}{
+		{name: "Succeeded", status: http.StatusOK, wantStatus: StatusSucceeded},
+		{name: "Retried", status: http.StatusServiceUnavailable, wantStatus: StatusPending},
+		{name: "GaveUp", status: http.StatusServiceUnavailable, attempts: defaultMaxAttempts - 1, wantStatus: StatusFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			webhook := db.Webhook{ID: 1, EventTypes: []string{db.EventTransferPosted}, Secret: "whsec_test"}
+			delivery := db.WebhookDelivery{
+				ID:        2,
+				WebhookID: webhook.ID,
+				EventID:   3,
+				EventType: db.EventTransferPosted,
+				Payload:   []byte(`{"transfer":{"id":4}}`),
+				Attempts:  tc.attempts,
+			}
+
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				require.NoError(t, Verify(webhook.Secret, r.Header.Get(SignatureHeader), body, time.Minute))
+				require.Equal(t, "3", r.Header.Get(EventIDHeader))
+
+				var msg Message
+				require.NoError(t, json.Unmarshal(body, &msg))
+				require.Equal(t, delivery.EventType, msg.EventType)
+				require.JSONEq(t, string(delivery.Payload), string(msg.Data))
+				w.WriteHeader(tc.status)
+			}))
+			defer receiver.Close()
+			webhook.Url = receiver.URL
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().ListDueWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return([]db.WebhookDelivery{delivery}, nil)
+			store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+			store.EXPECT().
+				UpdateWebhookDeliveryAttempt(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ any, arg db.UpdateWebhookDeliveryAttemptParams) (db.WebhookDelivery, error) {
+					require.Equal(t, delivery.ID, arg.ID)
+					require.Equal(t, tc.wantStatus, arg.Status)
+					require.Equal(t, int32(tc.status), arg.ResponseStatus)
+					if tc.wantStatus == StatusPending {
+						require.WithinDuration(t, time.Now().Add(Backoff(tc.attempts+1)), arg.NextAttemptAt, time.Second)
+					}
+					return delivery, nil
+				})
+
+			worker := NewWorker(store, receiver.Client(), 0)
+			_, err := worker.DeliverDue(context.Background())
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDeliverDueTimeout(t *testing.T) {
+	hung := db.Webhook{ID: 1, EventTypes: []string{db.EventTransferPosted}, Secret: "whsec_hung"}
+	ok := db.Webhook{ID: 2, EventTypes: []string{db.EventTransferPosted}, Secret: "whsec_ok"}
+	deliveries := []db.WebhookDelivery{
+		{ID: 3, WebhookID: hung.ID, EventID: 5, EventType: db.EventTransferPosted, Payload: []byte(`{}`)},
+		{ID: 4, WebhookID: ok.ID, EventID: 5, EventType: db.EventTransferPosted, Payload: []byte(`{}`)},
+	}
+
+	// The first receiver never responds.
+	release := make(chan struct{})
+	hungReceiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hungReceiver.Close()
+	defer close(release)
+	hung.Url = hungReceiver.URL
+
+	okReceiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer okReceiver.Close()
+	ok.Url = okReceiver.URL
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListDueWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(deliveries, nil)
+	store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(hung.ID)).Times(1).Return(hung, nil)
+	store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(ok.ID)).Times(1).Return(ok, nil)
+	store.EXPECT().
+		UpdateWebhookDeliveryAttempt(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ any, arg db.UpdateWebhookDeliveryAttemptParams) (db.WebhookDelivery, error) {
+			if arg.ID == deliveries[0].ID {
+				require.Equal(t, StatusPending, arg.Status)
+				require.NotEmpty(t, arg.LastError)
+			} else {
+				require.Equal(t, StatusSucceeded, arg.Status)
+			}
+			return db.WebhookDelivery{}, nil
+		})
+
+	worker := NewWorker(store, &http.Client{Timeout: 100 * time.Millisecond}, 0)
+	start := time.Now()
+	succeeded, err := worker.DeliverDue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, succeeded)
+	require.Less(t, time.Since(start), 5*time.Second)
+}